@@ -0,0 +1,286 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+	"github.com/StackExchange/dnscontrol/v4/pkg/credsfile"
+	"github.com/StackExchange/dnscontrol/v4/pkg/diff2"
+	"github.com/StackExchange/dnscontrol/v4/pkg/normalize"
+	"github.com/StackExchange/dnscontrol/v4/pkg/printer"
+	"github.com/StackExchange/dnscontrol/v4/pkg/zonerecs"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/exp/slices"
+)
+
+var _ = cmd(catMain, func() *cli.Command {
+	var args CheckDriftArgs
+	return &cli.Command{
+		Name:  "check-drift",
+		Usage: "Report (by category) how live zones differ from dnsconfig.js. Exit 1 if they differ, 2 if they can't be checked",
+		Action: func(ctx *cli.Context) error {
+			err := CheckDrift(args)
+			if errors.Is(err, ErrDriftUnchecked) {
+				return cli.Exit(err, 2)
+			}
+			return exit(err)
+		},
+		Flags: args.flags(),
+	}
+}())
+
+// CheckDriftArgs contains all data/flags needed to run check-drift, independently of CLI.
+type CheckDriftArgs struct {
+	GetDNSConfigArgs
+	GetCredentialsArgs
+	FilterArgs
+	ConcurMode string
+	Format     string
+	Output     string
+}
+
+func (args *CheckDriftArgs) flags() []cli.Flag {
+	flags := args.GetDNSConfigArgs.flags()
+	flags = append(flags, args.GetCredentialsArgs.flags()...)
+	flags = append(flags, args.FilterArgs.flags()...)
+	flags = append(flags, &cli.StringFlag{
+		Name:        "cmode",
+		Destination: &args.ConcurMode,
+		Value:       "default",
+		Usage:       `Which providers to run concurrently: all, default, none`,
+	})
+	flags = append(flags, &cli.StringFlag{
+		Name:        "format",
+		Destination: &args.Format,
+		Value:       "table",
+		Usage:       `Output format: table, json`,
+		Action: func(c *cli.Context, s string) error {
+			if !slices.Contains([]string{"table", "json"}, s) {
+				return fmt.Errorf("%q is not a valid option for --format. Valid are: table, json", s)
+			}
+			return nil
+		},
+	})
+	flags = append(flags, &cli.StringFlag{
+		Name:        "out",
+		Destination: &args.Output,
+		Usage:       `File to write the report to (default stdout)`,
+	})
+	return flags
+}
+
+// Drift categories.
+const (
+	DriftMissing  = "missing"  // Desired but missing.
+	DriftModified = "modified" // Exists but differs from what is desired.
+	DriftPurge    = "purge"    // Present but not desired. Would be purged.
+	DriftNoPurge  = "no_purge" // Present but not desired. Kept because of NO_PURGE.
+	DriftIgnored  = "ignored"  // Present. Not managed because of IGNORE*().
+	DriftError    = "error"    // The zone could not be checked.
+)
+
+var driftDescriptions = map[string]string{
+	DriftMissing:  "desired but missing",
+	DriftModified: "modified",
+	DriftPurge:    "present but not desired (would be purged)",
+	DriftNoPurge:  "present but not desired (NO_PURGE)",
+	DriftIgnored:  "ignored (IGNORE)",
+	DriftError:    "error",
+}
+
+// ErrDriftUnchecked is returned by CheckDrift (wrapped) if some zones
+// could not be checked.
+var ErrDriftUnchecked = errors.New("zones could not be checked")
+
+// DriftItem is a record (or error) that differs between the live zone and dnsconfig.js.
+type DriftItem struct {
+	Domain   string `json:"domain"`
+	Provider string `json:"provider"`
+	Category string `json:"category"`
+	Name     string `json:"name,omitempty"` // FQDN
+	Type     string `json:"type,omitempty"`
+	Existing string `json:"existing,omitempty"` // Target and TTL of the live record.
+	Desired  string `json:"desired,omitempty"`  // Target and TTL of the record in dnsconfig.js.
+	Msg      string `json:"msg,omitempty"`
+}
+
+// CheckDrift implements the check-drift subcommand.
+func CheckDrift(args CheckDriftArgs) error {
+	// Progress messages go to stderr so that the report can be parsed.
+	out := &printer.ConsolePrinter{Writer: os.Stderr, Verbose: printer.DefaultPrinter.Verbose}
+
+	cfg, err := GetDNSConfig(args.GetDNSConfigArgs)
+	if err != nil {
+		return err
+	}
+	providerConfigs, err := credsfile.LoadProviderConfigs(args.CredsFile)
+	if err != nil {
+		return err
+	}
+	if _, err := PInitializeProviders(cfg, providerConfigs, false); err != nil {
+		return err
+	}
+	errs := normalize.ValidateAndNormalizeConfig(cfg)
+	if PrintValidationErrors(errs) {
+		return fmt.Errorf("exiting due to validation errors")
+	}
+
+	pargs := PPreviewArgs{
+		GetDNSConfigArgs:   args.GetDNSConfigArgs,
+		GetCredentialsArgs: args.GetCredentialsArgs,
+		FilterArgs:         args.FilterArgs,
+		ConcurMode:         args.ConcurMode,
+		NoPopulate:         true, // We only want to look.
+	}
+	zonesToProcess := whichZonesToProcess(cfg.Domains, args.Domains)
+	pcollector := newPlanCollector()
+	gatherZones(zonesToProcess, pargs, NewZoneCache(), pcollector, out)
+
+	var items []*DriftItem
+	for _, zone := range zonesToProcess {
+		providersToProcess := whichProvidersToProcess(zone.DNSProviderInstances, args.Providers)
+		for _, provider := range providersToProcess {
+			var zitems []*DriftItem
+			if zc, ok := pcollector.lookup(zone.GetUniqueName(), provider.Name); ok {
+				zitems = genDriftItems(zc)
+			} else {
+				// The zone couldn't be gathered. The reason is in the corrections.
+				for _, c := range zone.GetCorrections(provider.Name) {
+					zitems = append(zitems, &DriftItem{Category: DriftError, Msg: c.Msg})
+				}
+			}
+			for _, item := range zitems {
+				item.Domain = zone.GetUniqueName()
+				item.Provider = provider.Name
+			}
+			items = append(items, zitems...)
+		}
+	}
+
+	w := io.Writer(os.Stdout)
+	if args.Output != "" {
+		f, err := os.Create(args.Output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if args.Format == "json" {
+		err = writeDriftJSON(w, items)
+	} else {
+		err = writeDriftTable(w, items)
+	}
+	if err != nil {
+		return err
+	}
+
+	drift, unchecked := countDrift(items)
+	if unchecked != 0 {
+		return fmt.Errorf("%d differences found, %d %w", drift, unchecked, ErrDriftUnchecked)
+	}
+	if drift != 0 {
+		return fmt.Errorf("%d differences found", drift)
+	}
+	return nil
+}
+
+// genDriftItems categorizes the changes to a zone.
+func genDriftItems(zc *zonerecs.ZoneChanges) []*DriftItem {
+	var items []*DriftItem
+	for _, c := range zc.Changes {
+		switch c.Type {
+		case diff2.CREATE:
+			for _, r := range c.New {
+				items = append(items, newDriftItem(DriftMissing, r, nil, r))
+			}
+		case diff2.CHANGE:
+			for i := range c.New {
+				var old *models.RecordConfig
+				if i < len(c.Old) {
+					old = c.Old[i]
+				}
+				items = append(items, newDriftItem(DriftModified, c.New[i], old, c.New[i]))
+			}
+		case diff2.DELETE:
+			for _, r := range c.Old {
+				items = append(items, newDriftItem(DriftPurge, r, r, nil))
+			}
+		}
+	}
+	for _, r := range zc.Unpurged {
+		items = append(items, newDriftItem(DriftNoPurge, r, r, nil))
+	}
+	for _, r := range zc.Ignored {
+		items = append(items, newDriftItem(DriftIgnored, r, r, nil))
+	}
+	return items
+}
+
+func newDriftItem(category string, r, existing, desired *models.RecordConfig) *DriftItem {
+	item := &DriftItem{
+		Category: category,
+		Name:     r.GetLabelFQDN(),
+		Type:     r.Type,
+	}
+	if existing != nil {
+		item.Existing = fmt.Sprintf("%s ttl=%d", existing.GetTargetCombined(), existing.TTL)
+	}
+	if desired != nil {
+		item.Desired = fmt.Sprintf("%s ttl=%d", desired.GetTargetCombined(), desired.TTL)
+	}
+	return item
+}
+
+// countDrift returns the number of items that indicate the zone
+// differs from dnsconfig.js, and the number of errors. Ignored records
+// and records kept by NO_PURGE are not counted.
+func countDrift(items []*DriftItem) (drift, errs int) {
+	for _, item := range items {
+		switch item.Category {
+		case DriftIgnored, DriftNoPurge:
+		case DriftError:
+			errs++
+		default:
+			drift++
+		}
+	}
+	return drift, errs
+}
+
+func writeDriftJSON(w io.Writer, items []*DriftItem) error {
+	if items == nil {
+		items = []*DriftItem{}
+	}
+	b, err := json.MarshalIndent(items, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", b)
+	return err
+}
+
+func writeDriftTable(w io.Writer, items []*DriftItem) error {
+	if len(items) == 0 {
+		_, err := fmt.Fprintln(w, "No drift found.")
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DOMAIN\tPROVIDER\tCATEGORY\tNAME\tTYPE\tEXISTING\tDESIRED")
+	for _, item := range items {
+		if item.Category == DriftError {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", item.Domain, item.Provider, driftDescriptions[item.Category], strings.ReplaceAll(item.Msg, "\n", " "))
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			item.Domain, item.Provider, driftDescriptions[item.Category],
+			item.Name, item.Type, item.Existing, item.Desired)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+	"github.com/StackExchange/dnscontrol/v4/pkg/diff2"
+	"github.com/StackExchange/dnscontrol/v4/pkg/zonerecs"
+)
+
+func makeDriftRec(name, target string, ttl uint32) *models.RecordConfig {
+	r := &models.RecordConfig{Type: "A", TTL: ttl}
+	r.SetLabel(name, "example.com")
+	r.SetTarget(target)
+	return r
+}
+
+func Test_genDriftItems(t *testing.T) {
+	missing := makeDriftRec("new", "1.1.1.1", 300)
+	oldWWW := makeDriftRec("www", "9.9.9.9", 300)
+	newWWW := makeDriftRec("www", "1.2.3.4", 300)
+	purge := makeDriftRec("old", "2.2.2.2", 300)
+	report := makeDriftRec("rep", "3.3.3.3", 300)
+
+	zc := &zonerecs.ZoneChanges{
+		Changes: diff2.ChangeList{
+			{Type: diff2.CREATE, New: models.Records{missing}},
+			{Type: diff2.CHANGE, Old: models.Records{oldWWW}, New: models.Records{newWWW}},
+			{Type: diff2.DELETE, Old: models.Records{purge}},
+			{Type: diff2.REPORT, Old: models.Records{report}},
+		},
+		Unpurged: models.Records{makeDriftRec("kept", "4.4.4.4", 300)},
+		Ignored:  models.Records{makeDriftRec("ign", "5.5.5.5", 300)},
+	}
+
+	items := genDriftItems(zc)
+
+	want := []DriftItem{
+		{Category: DriftMissing, Name: "new.example.com", Type: "A", Desired: "1.1.1.1 ttl=300"},
+		{Category: DriftModified, Name: "www.example.com", Type: "A", Existing: "9.9.9.9 ttl=300", Desired: "1.2.3.4 ttl=300"},
+		{Category: DriftPurge, Name: "old.example.com", Type: "A", Existing: "2.2.2.2 ttl=300"},
+		{Category: DriftNoPurge, Name: "kept.example.com", Type: "A", Existing: "4.4.4.4 ttl=300"},
+		{Category: DriftIgnored, Name: "ign.example.com", Type: "A", Existing: "5.5.5.5 ttl=300"},
+	}
+	if len(items) != len(want) {
+		t.Fatalf("genDriftItems() got %d items, want %d: %+v", len(items), len(want), items)
+	}
+	for i := range want {
+		if *items[i] != want[i] {
+			t.Errorf("genDriftItems()[%d] = %+v, want %+v", i, *items[i], want[i])
+		}
+	}
+
+	// Ignored and NO_PURGE records are not drift. Errors are counted separately.
+	items = append(items, &DriftItem{Category: DriftError, Msg: "boom"})
+	if drift, errs := countDrift(items); drift != 3 || errs != 1 {
+		t.Errorf("countDrift() = %d, %d, want 3, 1", drift, errs)
+	}
+}
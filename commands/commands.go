@@ -6,6 +6,7 @@ import (
 	"os"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/StackExchange/dnscontrol/v4/models"
 	"github.com/StackExchange/dnscontrol/v4/pkg/diff2"
@@ -60,6 +61,28 @@ func Run(v string) int {
 			Usage:       "Enable JS fetch(), dangerous on untrusted code!",
 			Destination: &js.EnableFetch,
 		},
+		&cli.BoolFlag{
+			Name:        "sandbox",
+			Usage:       "Run dnsconfig.js in a sandbox with limits, for untrusted configurations",
+			Destination: &js.Sandbox.Enabled,
+		},
+		&cli.DurationFlag{
+			Name:        "sandbox-timeout",
+			Usage:       "(--sandbox) Maximum time dnsconfig.js may run",
+			Value:       30 * time.Second,
+			Destination: &js.Sandbox.Timeout,
+		},
+		&cli.IntFlag{
+			Name:        "sandbox-max-records",
+			Usage:       "(--sandbox) Maximum number of records in all domains",
+			Value:       100000,
+			Destination: &js.Sandbox.MaxRecords,
+		},
+		&cli.BoolFlag{
+			Name:        "legacy-js",
+			Usage:       "Use the old ES5-only JavaScript interpreter. Will be removed in a future release",
+			Destination: &js.LegacyEngine,
+		},
 		&cli.BoolFlag{
 			Name:   "diff2",
 			Usage:  "Obsolete flag. Will be removed in v5 or later",
@@ -80,6 +103,17 @@ func Run(v string) int {
 			Destination: &color.NoColor,
 			Value:       false,
 		},
+		&cli.StringFlag{
+			Name:  "printer",
+			Usage: "Output format of preview/push: console, jsonl (one JSON object per event)",
+			Value: "console",
+			Action: func(ctx *cli.Context, s string) error {
+				if s == "jsonl" {
+					color.NoColor = true
+				}
+				return exit(printer.SetCLIFormat(s))
+			},
+		},
 	}
 	sort.Sort(cli.CommandsByName(commands))
 	app.Commands = commands
@@ -222,7 +256,7 @@ func (args *ExecuteDSLArgs) flags() []cli.Flag {
 			Name:        "config",
 			Value:       "dnsconfig.js",
 			Destination: &args.JSFile,
-			Usage:       "File containing dns config in javascript DSL",
+			Usage:       "File containing dns config in javascript DSL (or YAML if it ends in .yaml)",
 		},
 		&cli.StringFlag{
 			Name:        "js",
@@ -3,6 +3,7 @@ package commands
 import (
 	"fmt"
 
+	"github.com/StackExchange/dnscontrol/v4/models"
 	"github.com/StackExchange/dnscontrol/v4/pkg/credsfile"
 	"github.com/StackExchange/dnscontrol/v4/providers"
 	"github.com/urfave/cli/v2"
@@ -53,8 +54,15 @@ func CreateDomains(args CreateDomainsArgs) error {
 	}
 	for _, domain := range cfg.Domains {
 		fmt.Println("*** ", domain.Name)
+		tag := domain.Metadata[models.DomainTag]
 		for _, provider := range domain.DNSProviderInstances {
-			if creator, ok := provider.Driver.(providers.ZoneCreator); ok {
+			if creator, ok := provider.Driver.(providers.SplitHorizonZoneCreator); ok && tag != "" {
+				fmt.Println("  -", provider.Name)
+				err := creator.EnsureTaggedZoneExists(domain.Name, tag)
+				if err != nil {
+					fmt.Printf("Error creating domain: %s\n", err)
+				}
+			} else if creator, ok := provider.Driver.(providers.ZoneCreator); ok {
 				fmt.Println("  -", provider.Name)
 				err := creator.EnsureZoneExists(domain.Name)
 				if err != nil {
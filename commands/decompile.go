@@ -0,0 +1,376 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+	"github.com/StackExchange/dnscontrol/v4/pkg/prettyzone"
+	"github.com/urfave/cli/v2"
+)
+
+var _ = cmd(catUtils, func() *cli.Command {
+	var args DecompileArgs
+	return &cli.Command{
+		Name:  "decompile",
+		Usage: "Convert IR (json) back to dnsconfig.js",
+		Action: func(c *cli.Context) error {
+			return exit(Decompile(args))
+		},
+		Flags:     args.flags(),
+		UsageText: "dnscontrol decompile [command options]",
+		Description: `Generate dnsconfig.js from the IR. This is the opposite of print-ir.
+
+EXAMPLES:
+   dnscontrol decompile --ir generated.json --out dnsconfig.js
+   dnscontrol decompile --config dnsconfig.yaml`,
+	}
+}())
+
+// DecompileArgs encapsulates the flags/arguments for the decompile command.
+type DecompileArgs struct {
+	GetDNSConfigArgs
+	OutputFile string // Filename to send output ("" means stdout)
+}
+
+func (args *DecompileArgs) flags() []cli.Flag {
+	flags := args.GetDNSConfigArgs.flags()
+	flags = append(flags, &cli.StringFlag{
+		Name:        "out",
+		Destination: &args.OutputFile,
+		Usage:       `Instead of stdout, write to this file`,
+	})
+	return flags
+}
+
+// Decompile implements the decompile subcommand.
+func Decompile(args DecompileArgs) error {
+	cfg, err := GetDNSConfig(args.GetDNSConfigArgs)
+	if err != nil {
+		return err
+	}
+
+	w := os.Stdout
+	if args.OutputFile != "" {
+		w, err = os.Create(args.OutputFile)
+		if err != nil {
+			return err
+		}
+		defer w.Close()
+	}
+	return writeDSL(w, cfg)
+}
+
+// writeDSL writes cfg to w as dnsconfig.js.
+func writeDSL(w io.Writer, cfg *models.DNSConfig) error {
+	vars := map[string]bool{}
+	regVars := map[string]string{}
+	for _, reg := range cfg.Registrars {
+		regVars[reg.Name] = jsVariable(vars, "REG_", reg.Name)
+		fmt.Fprintf(w, "var %s = %s;\n", regVars[reg.Name], providerDecl("NewRegistrar", reg.Name, reg.Type, reg.Metadata))
+	}
+	dspVars := map[string]string{}
+	for _, dsp := range cfg.DNSProviders {
+		dspVars[dsp.Name] = jsVariable(vars, "DSP_", dsp.Name)
+		fmt.Fprintf(w, "var %s = %s;\n", dspVars[dsp.Name], providerDecl("NewDnsProvider", dsp.Name, dsp.Type, dsp.Metadata))
+	}
+	fmt.Fprintln(w)
+
+	// The most common TTL of all records becomes the default for all
+	// domains. Domains that mostly use another TTL get their own.
+	// Records that were never given a TTL (0) get the default TTL
+	// during normalization; count them as such. The records are
+	// copied so that cfg is left as it is.
+	var all models.Records
+	domainRecs := make([]models.Records, len(cfg.Domains))
+	for i, dc := range cfg.Domains {
+		for _, rec := range dc.Records {
+			r := *rec
+			if r.TTL == 0 {
+				r.TTL = models.DefaultTTL
+			}
+			domainRecs[i] = append(domainRecs[i], &r)
+		}
+		all = append(all, domainRecs[i]...)
+	}
+	globalTTL := prettyzone.MostCommonTTL(all)
+	if globalTTL == 0 {
+		globalTTL = models.DefaultTTL
+	}
+	if globalTTL != models.DefaultTTL {
+		fmt.Fprintf(w, "DEFAULTS(DefaultTTL(%d));\n\n", globalTTL)
+	}
+
+	for i, dc := range cfg.Domains {
+		name := dc.Name
+		if u := dc.Metadata[models.DomainUniqueName]; u != "" {
+			name = u
+		}
+		// D() and DnsProvider() also accept names that were never declared.
+		reg, ok := regVars[dc.RegistrarName]
+		if !ok {
+			reg = jsonQuoted(dc.RegistrarName)
+		}
+		fmt.Fprintf(w, "D(%s, %s,\n", jsonQuoted(name), reg)
+
+		var o []string
+
+		// Sort the providers so the output is deterministic.
+		var dsps []string
+		for p := range dc.DNSProviderNames {
+			dsps = append(dsps, p)
+		}
+		sort.Strings(dsps)
+		for _, p := range dsps {
+			v, ok := dspVars[p]
+			if !ok {
+				v = jsonQuoted(p)
+			}
+			if n := dc.DNSProviderNames[p]; n >= 0 {
+				o = append(o, fmt.Sprintf("DnsProvider(%s, %d)", v, n))
+			} else {
+				o = append(o, fmt.Sprintf("DnsProvider(%s)", v))
+			}
+		}
+
+		defaultTTL := globalTTL
+		if ttl := prettyzone.MostCommonTTL(domainRecs[i]); ttl != 0 && ttl != globalTTL {
+			defaultTTL = ttl
+			o = append(o, fmt.Sprintf("DefaultTTL(%d)", defaultTTL))
+		}
+
+		meta := map[string]string{}
+		for k, v := range dc.Metadata {
+			if k != models.DomainUniqueName && k != models.DomainTag {
+				meta[k] = v
+			}
+		}
+		if len(meta) != 0 {
+			o = append(o, jsonObject(meta))
+		}
+
+		if dc.KeepUnknown {
+			o = append(o, "NO_PURGE")
+		}
+		if dc.UnmanagedUnsafe {
+			o = append(o, "DISABLE_IGNORE_SAFETY_CHECK")
+		}
+		switch dc.AutoDNSSEC {
+		case "on":
+			o = append(o, "AUTODNSSEC_ON")
+		case "off":
+			o = append(o, "AUTODNSSEC_OFF")
+		}
+		for _, ns := range dc.Nameservers {
+			// Normalization removes the trailing dot. NAMESERVER() requires it.
+			n := ns.Name
+			if !strings.HasSuffix(n, ".") {
+				n += "."
+			}
+			o = append(o, fmt.Sprintf("NAMESERVER(%s)", jsonQuoted(n)))
+		}
+		for _, u := range dc.Unmanaged {
+			o = append(o, fmt.Sprintf("IGNORE(%s, %s, %s)", jsonQuoted(u.LabelPattern), jsonQuoted(u.RTypePattern), jsonQuoted(u.TargetPattern)))
+		}
+		for _, p := range dc.Protected {
+			o = append(o, fmt.Sprintf("PROTECT(%s, %s, %s)", jsonQuoted(p.LabelPattern), jsonQuoted(p.RTypePattern), jsonQuoted(p.TargetPattern)))
+		}
+
+		// Records that were added with D_EXTEND() on a subdomain are
+		// written the same way. Each run of records with the same
+		// subdomain gets its own D_EXTEND() so that the order is kept.
+		recs := domainRecs[i]
+		for len(recs) != 0 && recs[0].SubDomain == "" {
+			o = append(o, decompileRecord(recs[0], defaultTTL, ""))
+			recs = recs[1:]
+		}
+		for _, rec := range dc.EnsureAbsent {
+			o = append(o, decompileRecord(rec, defaultTTL, ", ENSURE_ABSENT_REC()"))
+		}
+		writeDomainBlock(w, o)
+
+		for len(recs) != 0 {
+			sub := recs[0].SubDomain
+			extName := name
+			if sub != "" {
+				extName = sub + "." + name
+			}
+			fmt.Fprintf(w, "D_EXTEND(%s,\n", jsonQuoted(extName))
+			o = nil
+			for len(recs) != 0 && recs[0].SubDomain == sub {
+				r := *recs[0]
+				switch {
+				case sub == "":
+				case r.Name == sub:
+					r.Name = "@"
+				case r.Type != "PTR" && strings.HasSuffix(r.Name, "."+sub):
+					r.Name = strings.TrimSuffix(r.Name, "."+sub)
+				}
+				o = append(o, decompileRecord(&r, defaultTTL, ""))
+				recs = recs[1:]
+			}
+			writeDomainBlock(w, o)
+		}
+	}
+	return nil
+}
+
+// writeDomainBlock writes the arguments of D() or D_EXTEND() and closes it.
+func writeDomainBlock(w io.Writer, items []string) {
+	for _, s := range items {
+		fmt.Fprintf(w, "\t%s,\n", s)
+	}
+	fmt.Fprint(w, "END);\n\n")
+}
+
+// providerDecl returns the call to NewRegistrar or NewDnsProvider that
+// declares a provider.
+func providerDecl(fn, name, typ string, meta []byte) string {
+	if typ == "" {
+		typ = "-"
+	}
+	switch {
+	case len(meta) != 0:
+		return fmt.Sprintf("%s(%s, %s, %s)", fn, jsonQuoted(name), jsonQuoted(typ), meta)
+	case typ == "-":
+		return fmt.Sprintf("%s(%s)", fn, jsonQuoted(name))
+	default:
+		return fmt.Sprintf("%s(%s, %s)", fn, jsonQuoted(name), jsonQuoted(typ))
+	}
+}
+
+var nonIdentifier = regexp.MustCompile(`[^A-Z0-9_]+`)
+
+// jsVariable returns a unique variable name for a provider. used
+// records which names were already returned.
+func jsVariable(used map[string]bool, prefix, name string) string {
+	base := prefix + nonIdentifier.ReplaceAllString(strings.ToUpper(name), "_")
+	v := base
+	for i := 2; used[v]; i++ {
+		v = fmt.Sprintf("%s_%d", base, i)
+	}
+	used[v] = true
+	return v
+}
+
+// cfProxyModifiers maps the value of the cloudflare_proxy metadata to
+// the modifier that sets it.
+var cfProxyModifiers = map[string]string{
+	"on":   "CF_PROXY_ON",
+	"off":  "CF_PROXY_OFF",
+	"full": "CF_PROXY_FULL",
+}
+
+// decompileRecord returns rec as a dnsconfig.js record. Unlike the
+// records generated by get-zones, the result must produce the same IR,
+// so the metadata is retained and types that formatRecord only
+// approximates are handled here.
+func decompileRecord(rec *models.RecordConfig, defaultTTL uint32, mods string) string {
+	if rec.Type == "IMPORT_TRANSFORM" {
+		return importTransform(rec)
+	}
+
+	meta := map[string]string{}
+	for k, v := range rec.Metadata {
+		if m, ok := cfProxyModifiers[v]; ok && k == "cloudflare_proxy" {
+			mods += ", " + m
+		} else {
+			meta[k] = v
+		}
+	}
+	if len(meta) != 0 {
+		mods += ", " + jsonObject(meta)
+	}
+	mods += r53RoutingModifiers(rec)
+	if rec.TTL != defaultTTL && rec.TTL != 0 {
+		mods += fmt.Sprintf(", TTL(%d)", rec.TTL)
+	}
+
+	var args []string
+	switch rec.Type { // #rtype_variations
+	case "NS", "SOA":
+		// formatRecord turns these into comments.
+		args = []string{jsonQuoted(rec.GetTargetField())}
+		if rec.Type == "SOA" {
+			args = append(args, jsonQuoted(rec.SoaMbox),
+				fmt.Sprint(rec.SoaRefresh), fmt.Sprint(rec.SoaRetry), fmt.Sprint(rec.SoaExpire), fmt.Sprint(rec.SoaMinttl))
+		}
+	case "LOC":
+		// The target is "d1 m1 s1 N d2 m2 s2 E alt siz hp vp", each
+		// size with an "m" suffix. LOC() takes the same numbers.
+		fields := strings.Fields(rec.GetTargetField())
+		if len(fields) != 12 {
+			return fmt.Sprintf("// LOC(%s, %s)", jsonQuoted(rec.Name), jsonQuoted(rec.GetTargetField()))
+		}
+		for i, f := range fields {
+			if i == 3 || i == 7 {
+				args = append(args, jsonQuoted(f))
+			} else {
+				args = append(args, strings.TrimSuffix(f, "m"))
+			}
+		}
+	case "AZURE_ALIAS":
+		args = []string{jsonQuoted(rec.AzureAlias["type"]), jsonQuoted(rec.GetTargetField())}
+	case "CF_REDIRECT", "CF_TEMP_REDIRECT", "CF_WORKER_ROUTE":
+		// The target is "source,destination" and the label is always "@".
+		src, dst, _ := strings.Cut(rec.GetTargetField(), ",")
+		return fmt.Sprintf("%s(%s, %s%s)", rec.Type, jsonQuoted(src), jsonQuoted(dst), mods)
+	case "R53_ALIAS":
+		ttl := uint32(0)
+		if rec.TTL != defaultTTL {
+			ttl = rec.TTL
+		}
+		return makeR53alias(rec, ttl)
+	case "CLOUDFLAREAPI_SINGLE_REDIRECT":
+		if sr := rec.CloudflareRedirect; sr != nil {
+			return fmt.Sprintf("CF_SINGLE_REDIRECT(%s, %d, %s, %s%s)", jsonQuoted(sr.SRName), sr.Code, jsonQuoted(sr.SRWhen), jsonQuoted(sr.SRThen), mods)
+		}
+		fallthrough
+	default:
+		// formatRecord adds the TTL itself.
+		r := *rec
+		r.TTL = defaultTTL
+		return formatRecord(&r, defaultTTL, mods)
+	}
+	return fmt.Sprintf("%s(%s, %s%s)", rec.Type, jsonQuoted(rec.Name), strings.Join(args, ", "), mods)
+}
+
+// jsonObject returns m as a JavaScript object literal.
+func jsonObject(m map[string]string) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	items := make([]string, len(keys))
+	for i, k := range keys {
+		items[i] = jsonQuoted(k) + ": " + jsonQuoted(m[k])
+	}
+	return "{" + strings.Join(items, ", ") + "}"
+}
+
+// importTransform returns an IMPORT_TRANSFORM record. The transform
+// table was converted to "low ~ high ~ newBase ~ newIP ; ..." by
+// IMPORT_TRANSFORM, which also accepts strings instead of numbers.
+func importTransform(rec *models.RecordConfig) string {
+	var rows []string
+	for _, line := range strings.Split(rec.Metadata["transform_table"], ";") {
+		f := strings.Split(line, "~")
+		if len(f) != 4 {
+			continue
+		}
+		row := fmt.Sprintf("{low: %s, high: %s", jsonQuoted(strings.TrimSpace(f[0])), jsonQuoted(strings.TrimSpace(f[1])))
+		if b := strings.TrimSpace(f[2]); b != "" {
+			row += ", newBase: " + jsonQuoted(b)
+		}
+		if ip := strings.TrimSpace(f[3]); ip != "" {
+			row += ", newIP: " + jsonQuoted(ip)
+		}
+		rows = append(rows, row+"}")
+	}
+	return fmt.Sprintf("IMPORT_TRANSFORM([%s], %s, %d)", strings.Join(rows, ", "), jsonQuoted(rec.GetTargetField()), rec.TTL)
+}
@@ -0,0 +1,131 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+	"github.com/StackExchange/dnscontrol/v4/pkg/js"
+	"github.com/StackExchange/dnscontrol/v4/pkg/normalize"
+	"github.com/StackExchange/dnscontrol/v4/pkg/rtypes"
+	testifyrequire "github.com/stretchr/testify/require"
+)
+
+// executeForTest runs dnsconfig.js the way ExecuteDSL does and returns
+// the normalized IR as JSON.
+func executeForTest(t *testing.T, run func() (*models.DNSConfig, error)) (*models.DNSConfig, string) {
+	t.Helper()
+	cfg, err := run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rtypes.PostProcess(cfg.Domains); err != nil {
+		t.Fatal(err)
+	}
+	ir, err := run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The decompiled file defines the records on other lines.
+	clearSources(ir)
+	if err := rtypes.PostProcess(ir.Domains); err != nil {
+		t.Fatal(err)
+	}
+	if errs := normalize.ValidateAndNormalizeConfig(ir); len(errs) != 0 {
+		t.Fatal(errs[0])
+	}
+	b, err := json.MarshalIndent(ir, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg, string(b)
+}
+
+// clearSources removes the source locations from the records of cfg.
+func clearSources(cfg *models.DNSConfig) {
+	for _, d := range cfg.Domains {
+		for _, r := range d.Records {
+			r.Source = nil
+		}
+		for _, r := range d.EnsureAbsent {
+			r.Source = nil
+		}
+		for i := range d.RawRecords {
+			d.RawRecords[i].Source = nil
+		}
+	}
+}
+
+// TestDecompileRoundTrip checks that decompiling the parse tests
+// produces dnsconfig.js files that result in the same IR.
+func TestDecompileRoundTrip(t *testing.T) {
+	files, err := filepath.Glob("../pkg/js/parse_tests/0*.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		name := filepath.Base(f)
+		if name == "020-complexRequire.js" {
+			// Its require()s only work from the repository root.
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			cfg, want := executeForTest(t, func() (*models.DNSConfig, error) {
+				return js.ExecuteJavaScript(f, false, nil)
+			})
+
+			// Decompile both the output of dnsconfig.js and the
+			// normalized IR, as read by --ir.
+			ir := &models.DNSConfig{}
+			if err := json.Unmarshal([]byte(want), ir); err != nil {
+				t.Fatal(err)
+			}
+			for _, c := range []*models.DNSConfig{cfg, ir} {
+				before, _ := json.Marshal(c)
+				var buf bytes.Buffer
+				if err := writeDSL(&buf, c); err != nil {
+					t.Fatal(err)
+				}
+				if after, _ := json.Marshal(c); !bytes.Equal(before, after) {
+					t.Error("writeDSL() changed the configuration")
+				}
+
+				_, got := executeForTest(t, func() (*models.DNSConfig, error) {
+					return js.ExecuteJavascriptString(buf.Bytes(), false, nil)
+				})
+				testifyrequire.JSONEqf(t, want, got, "decompiled:\n%s", buf.String())
+			}
+		})
+	}
+}
+
+func TestDecompileRecord(t *testing.T) {
+	rec := func(typ, name, target string, ttl uint32, meta map[string]string) *models.RecordConfig {
+		r := &models.RecordConfig{Type: typ, TTL: ttl, Metadata: meta}
+		r.SetLabel(name, "example.com")
+		r.SetTarget(target)
+		return r
+	}
+	tests := []struct {
+		name string
+		rec  *models.RecordConfig
+		want string
+	}{
+		{"plain", rec("A", "www", "1.2.3.4", 300, nil), `A("www", "1.2.3.4")`},
+		{"ttl", rec("A", "www", "1.2.3.4", 600, nil), `A("www", "1.2.3.4", TTL(600))`},
+		{"proxy", rec("A", "www", "1.2.3.4", 300, map[string]string{"cloudflare_proxy": "on"}), `A("www", "1.2.3.4", CF_PROXY_ON)`},
+		{"meta", rec("CNAME", "ftp", "www", 300, map[string]string{"b": "2", "a": "1"}), `CNAME("ftp", "www", {"a": "1", "b": "2"})`},
+		{"apexNS", rec("NS", "@", "ns1.example.net.", 300, nil), `NS("@", "ns1.example.net.")`},
+		{"cfRedirect", rec("CF_REDIRECT", "@", "a.example.com/*,https://b.example.com/$1", 300, nil), `CF_REDIRECT("a.example.com/*", "https://b.example.com/$1")`},
+		{"loc", rec("LOC", "loc", "42 21 54 N 71 6 18 W -24m 30m 0m 0m", 300, nil), `LOC("loc", 42, 21, 54, "N", 71, 6, 18, "W", -24, 30, 0, 0)`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := decompileRecord(tt.rec, 300, ""); got != tt.want {
+				t.Errorf("decompileRecord() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/StackExchange/dnscontrol/v4/pkg/js"
 	"github.com/ditashi/jsbeautifier-go/jsbeautifier"
 	"github.com/urfave/cli/v2"
 )
@@ -24,6 +25,8 @@ var _ = cmd(catUtils, func() *cli.Command {
 type FmtArgs struct {
 	InputFile  string
 	OutputFile string
+	Semantic   bool
+	Check      bool
 }
 
 func (args *FmtArgs) flags() []cli.Flag {
@@ -41,6 +44,16 @@ func (args *FmtArgs) flags() []cli.Flag {
 		Usage:       "Output file",
 		Destination: &args.OutputFile,
 	})
+	flags = append(flags, &cli.BoolFlag{
+		Name:        "semantic",
+		Usage:       "Also sort records and normalize quotes, TTLs and duplicate modifiers",
+		Destination: &args.Semantic,
+	})
+	flags = append(flags, &cli.BoolFlag{
+		Name:        "check",
+		Usage:       "Don't write anything; fail if the input file is not already formatted",
+		Destination: &args.Check,
+	})
 	return flags
 }
 
@@ -51,6 +64,13 @@ func FmtFile(args FmtArgs) error {
 		return readErr
 	}
 
+	if args.Semantic {
+		var err error
+		if fileBytes, err = js.FormatSemantic(args.InputFile, fileBytes); err != nil {
+			return err
+		}
+	}
+
 	opts := jsbeautifier.DefaultOptions()
 
 	str := string(fileBytes)
@@ -63,6 +83,17 @@ func FmtFile(args FmtArgs) error {
 		beautified = beautified + "\n"
 	}
 
+	if args.Check {
+		original, err := os.ReadFile(args.InputFile)
+		if err != nil {
+			return err
+		}
+		if string(original) != beautified {
+			return fmt.Errorf("%s is not formatted", args.InputFile)
+		}
+		return nil
+	}
+
 	if args.OutputFile == "" {
 		fmt.Print(beautified)
 	} else {
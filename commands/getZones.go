@@ -162,6 +162,9 @@ func GetZone(args GetZoneArgs) error {
 	if err != nil {
 		return fmt.Errorf("failed GetZone LoadProviderConfigs(%q): %w", args.CredsFile, err)
 	}
+	if err := credsfile.ResolveSecrets(args.CredName, providerConfigs[args.CredName]); err != nil {
+		return err
+	}
 	provider, err := providers.CreateDNSProvider(args.ProviderName, providerConfigs[args.CredName], nil)
 	if err != nil {
 		return fmt.Errorf("failed GetZone CDP: %w", err)
@@ -315,6 +318,18 @@ func jsonQuoted(i string) string {
 }
 
 func formatDsl(rec *models.RecordConfig, defaultTTL uint32) string {
+	cfproxy := ""
+	if cp, ok := rec.Metadata["cloudflare_proxy"]; ok {
+		if cp == "true" {
+			cfproxy = ", CF_PROXY_ON"
+		}
+	}
+	return formatRecord(rec, defaultTTL, cfproxy+r53RoutingModifiers(rec))
+}
+
+// formatRecord returns rec as a dnsconfig.js record. mods is inserted
+// after the record's arguments; it is empty or starts with ", ".
+func formatRecord(rec *models.RecordConfig, defaultTTL uint32, mods string) string {
 
 	target := rec.GetTargetCombined()
 
@@ -325,16 +340,9 @@ func formatDsl(rec *models.RecordConfig, defaultTTL uint32) string {
 		ttlop = fmt.Sprintf(", TTL(%d)", ttl)
 	}
 
-	cfproxy := ""
-	if cp, ok := rec.Metadata["cloudflare_proxy"]; ok {
-		if cp == "true" {
-			cfproxy = ", CF_PROXY_ON"
-		}
-	}
-
 	switch rec.Type { // #rtype_variations
 	case "CAA":
-		return makeCaa(rec, ttlop)
+		return makeCaa(rec, mods+ttlop)
 	case "DS":
 		target = fmt.Sprintf(`%d, %d, %d, "%s"`, rec.DsKeyTag, rec.DsAlgorithm, rec.DsDigestType, rec.DsDigest)
 	case "DNSKEY":
@@ -358,7 +366,7 @@ func formatDsl(rec *models.RecordConfig, defaultTTL uint32) string {
 	case "SRV":
 		target = fmt.Sprintf(`%d, %d, %d, "%s"`, rec.SrvPriority, rec.SrvWeight, rec.SrvPort, rec.GetTargetField())
 	case "SVCB", "HTTPS":
-		target = fmt.Sprintf(`%d, "%s", "%s"`, rec.SvcPriority, rec.GetTargetField(), rec.SvcParams)
+		target = fmt.Sprintf(`%d, "%s", %s`, rec.SvcPriority, rec.GetTargetField(), jsonQuoted(rec.SvcParams))
 	case "TLSA":
 		target = fmt.Sprintf(`%d, %d, %d, "%s"`, rec.TlsaUsage, rec.TlsaSelector, rec.TlsaMatchingType, rec.GetTargetField())
 	case "TXT":
@@ -380,7 +388,7 @@ func formatDsl(rec *models.RecordConfig, defaultTTL uint32) string {
 		target = `"` + target + `"`
 	}
 
-	return fmt.Sprintf(`%s("%s", %s%s%s)`, rec.Type, rec.Name, target, cfproxy, ttlop)
+	return fmt.Sprintf(`%s("%s", %s%s%s)`, rec.Type, rec.Name, target, mods, ttlop)
 }
 
 func makeCaa(rec *models.RecordConfig, ttlop string) string {
@@ -407,12 +415,58 @@ func makeR53alias(rec *models.RecordConfig, ttl uint32) string {
 	if e, ok := rec.R53Alias["evaluate_target_health"]; ok && e == "true" {
 		items = append(items, "R53_EVALUATE_TARGET_HEALTH(true)")
 	}
+	if mods := r53RoutingModifiers(rec); mods != "" {
+		items = append(items, strings.TrimPrefix(mods, ", "))
+	}
 	if ttl != 0 {
 		items = append(items, fmt.Sprintf("TTL(%d)", ttl))
 	}
 	return rec.Type + "(" + strings.Join(items, ", ") + ")"
 }
 
+// r53RoutingModifiers returns the modifiers that set the Route53
+// routing policy and health check of rec. The result is empty or starts
+// with ", ".
+func r53RoutingModifiers(rec *models.RecordConfig) string {
+	routing := rec.R53Routing
+	var mods []string
+	if id, ok := routing[models.R53RoutingSetIdentifier]; ok {
+		id = jsonQuoted(id)
+		if w, ok := routing[models.R53RoutingWeight]; ok {
+			mods = append(mods, fmt.Sprintf("R53_WEIGHT(%s, %s)", id, w))
+		}
+		if r, ok := routing[models.R53RoutingRegion]; ok {
+			mods = append(mods, fmt.Sprintf("R53_LATENCY_REGION(%s, %s)", id, jsonQuoted(r)))
+		}
+		if f, ok := routing[models.R53RoutingFailover]; ok {
+			mods = append(mods, fmt.Sprintf("R53_FAILOVER(%s, %s)", id, jsonQuoted(f)))
+		}
+		var loc []string
+		for _, k := range []struct{ field, key string }{
+			{"continent", models.R53RoutingGeoContinent},
+			{"country", models.R53RoutingGeoCountry},
+			{"subdivision", models.R53RoutingGeoSubdivision},
+		} {
+			if v, ok := routing[k.key]; ok {
+				loc = append(loc, k.field+": "+jsonQuoted(v))
+			}
+		}
+		if len(loc) != 0 {
+			mods = append(mods, fmt.Sprintf("R53_GEO(%s, {%s})", id, strings.Join(loc, ", ")))
+		}
+		if routing[models.R53RoutingMultiValue] == "true" {
+			mods = append(mods, fmt.Sprintf("R53_MULTIVALUE(%s)", id))
+		}
+	}
+	if h, ok := routing[models.R53RoutingHealthCheckID]; ok {
+		mods = append(mods, fmt.Sprintf("R53_HEALTH_CHECK_ID(%s)", jsonQuoted(h)))
+	}
+	if len(mods) == 0 {
+		return ""
+	}
+	return ", " + strings.Join(mods, ", ")
+}
+
 func makeUknown(rc *models.RecordConfig, ttl uint32) string {
 	return fmt.Sprintf(`// %s("%s", TTL(%d))`, rc.UnknownTypeName, rc.GetTargetField(), ttl)
 }
@@ -0,0 +1,128 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+	"github.com/StackExchange/dnscontrol/v4/pkg/diff2"
+	"github.com/StackExchange/dnscontrol/v4/pkg/zonerecs"
+)
+
+// Metadata keys set by MAX_CHANGES() and MAX_DELETE_PERCENT().
+const (
+	metaMaxChanges       = "max_changes"
+	metaMaxDeletePercent = "max_delete_percent"
+)
+
+// blastLimits is the maximum size of the changes to a zone that ppush
+// will make without --force. A negative value means "no limit".
+type blastLimits struct {
+	MaxChanges       int     // Records created, changed or deleted.
+	MaxDeletePercent float64 // Records deleted, as a percent of the existing records.
+}
+
+// getBlastLimits returns the limits of each zone that has any, keyed
+// by the zone's unique name. The --max-changes and --max-delete-percent
+// flags override the values set in dnsconfig.js. --force disables all
+// limits.
+func getBlastLimits(zones []*models.DomainConfig, args PPushArgs) (map[string]blastLimits, error) {
+	m := map[string]blastLimits{}
+	if args.Force {
+		return m, nil
+	}
+	for _, zone := range zones {
+		l := blastLimits{MaxChanges: -1, MaxDeletePercent: -1}
+		if s, ok := zone.Metadata[metaMaxChanges]; ok {
+			v, err := strconv.Atoi(s)
+			if err != nil || v < 0 {
+				return nil, fmt.Errorf("domain %q: MAX_CHANGES must be an integer >= 0, got %q", zone.GetUniqueName(), s)
+			}
+			l.MaxChanges = v
+		}
+		if s, ok := zone.Metadata[metaMaxDeletePercent]; ok {
+			v, err := strconv.ParseFloat(s, 64)
+			if err != nil || v < 0 || v > 100 {
+				return nil, fmt.Errorf("domain %q: MAX_DELETE_PERCENT must be a number from 0 to 100, got %q", zone.GetUniqueName(), s)
+			}
+			l.MaxDeletePercent = v
+		}
+		if args.MaxChanges >= 0 {
+			l.MaxChanges = args.MaxChanges
+		}
+		if args.MaxDeletePercent >= 0 {
+			l.MaxDeletePercent = args.MaxDeletePercent
+		}
+		if l.MaxChanges >= 0 || l.MaxDeletePercent >= 0 {
+			m[zone.GetUniqueName()] = l
+		}
+	}
+	return m, nil
+}
+
+// checkBlastRadius returns an error if the changes to the zone at the
+// provider exceed the zone's limits.
+func checkBlastRadius(limits map[string]blastLimits, pc *planCollector, zone *models.DomainConfig, provider *models.DNSProviderInstance) error {
+	l, ok := limits[zone.GetUniqueName()]
+	if !ok || pc == nil {
+		return nil
+	}
+	zc, ok := pc.lookup(zone.GetUniqueName(), provider.Name)
+	if !ok {
+		return nil // Nothing was gathered, so nothing will be changed.
+	}
+	return l.check(zc)
+}
+
+// checkServerConfigBlastRadius returns an error if the corrections of
+// the server configuration exceed the MAX_CHANGES of any of the zones
+// it lists, selected or not.
+func checkServerConfigBlastRadius(sc serverConfigCorrections, args PPushArgs) error {
+	limits, err := getBlastLimits(sc.zones, args)
+	if err != nil {
+		return err
+	}
+	changes := countActions(sc.corrections)
+	for _, zone := range sc.zones {
+		l, ok := limits[zone.GetUniqueName()]
+		if ok && l.MaxChanges >= 0 && changes > l.MaxChanges {
+			return fmt.Errorf("%d server configuration changes (MAX_CHANGES of %s is %d)", changes, zone.GetUniqueName(), l.MaxChanges)
+		}
+	}
+	return nil
+}
+
+// check returns an error if zc exceeds the limits.
+func (l blastLimits) check(zc *zonerecs.ZoneChanges) error {
+	changes, deletes := countChanges(zc.Changes)
+	if l.MaxChanges >= 0 && changes > l.MaxChanges {
+		return fmt.Errorf("%d records would change (MAX_CHANGES is %d)", changes, l.MaxChanges)
+	}
+	if l.MaxDeletePercent >= 0 && deletes > 0 {
+		pct := 100.0
+		if n := len(zc.Existing); n > 0 {
+			pct = float64(deletes) * 100 / float64(n)
+		}
+		if pct > l.MaxDeletePercent {
+			return fmt.Errorf("%d of %d records (%.1f%%) would be deleted (MAX_DELETE_PERCENT is %g)", deletes, len(zc.Existing), pct, l.MaxDeletePercent)
+		}
+	}
+	return nil
+}
+
+// countChanges returns the number of records that the changes create,
+// change or delete, and how many of those are deleted.
+func countChanges(cl diff2.ChangeList) (changes, deletes int) {
+	for _, c := range cl {
+		switch c.Type {
+		case diff2.CREATE:
+			changes += len(c.New)
+		case diff2.CHANGE:
+			changes += max(len(c.Old), len(c.New))
+		case diff2.DELETE:
+			changes += len(c.Old)
+			deletes += len(c.Old)
+		}
+	}
+	return changes, deletes
+}
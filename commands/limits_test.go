@@ -0,0 +1,108 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+	"github.com/StackExchange/dnscontrol/v4/pkg/diff2"
+	"github.com/StackExchange/dnscontrol/v4/pkg/zonerecs"
+)
+
+func Test_blastLimits(t *testing.T) {
+	var existing models.Records
+	for i := 0; i < 10; i++ {
+		existing = append(existing, makeDriftRec("r", "1.1.1.1", 300))
+	}
+	rec := makeDriftRec("x", "2.2.2.2", 300)
+	zc := &zonerecs.ZoneChanges{
+		Existing: existing,
+		Changes: diff2.ChangeList{
+			{Type: diff2.CREATE, New: models.Records{rec}},
+			{Type: diff2.CHANGE, Old: models.Records{rec}, New: models.Records{rec}},
+			{Type: diff2.DELETE, Old: models.Records{rec, rec, rec}},
+			{Type: diff2.REPORT, Msgs: []string{"not a change"}},
+		},
+	}
+
+	tests := []struct {
+		limits  blastLimits
+		wantErr string
+	}{
+		{blastLimits{MaxChanges: -1, MaxDeletePercent: -1}, ""},
+		{blastLimits{MaxChanges: 5, MaxDeletePercent: -1}, ""},
+		{blastLimits{MaxChanges: 4, MaxDeletePercent: -1}, "5 records would change"},
+		{blastLimits{MaxChanges: -1, MaxDeletePercent: 30}, ""},
+		{blastLimits{MaxChanges: -1, MaxDeletePercent: 29.9}, "3 of 10 records (30.0%) would be deleted"},
+	}
+	for _, tt := range tests {
+		err := tt.limits.check(zc)
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("%+v: unexpected error %v", tt.limits, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%+v: got %v, want error containing %q", tt.limits, err, tt.wantErr)
+		}
+	}
+}
+
+func Test_getBlastLimits(t *testing.T) {
+	a := &models.DomainConfig{Name: "a.com", Metadata: map[string]string{metaMaxChanges: "10"}}
+	b := &models.DomainConfig{Name: "b.com", Metadata: map[string]string{metaMaxDeletePercent: "25"}}
+	c := &models.DomainConfig{Name: "c.com"}
+	for _, dc := range []*models.DomainConfig{a, b, c} {
+		dc.UpdateSplitHorizonNames()
+	}
+	zones := []*models.DomainConfig{a, b, c}
+
+	m, err := getBlastLimits(zones, PPushArgs{MaxChanges: -1, MaxDeletePercent: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 2 || m["a.com"].MaxChanges != 10 || m["a.com"].MaxDeletePercent != -1 || m["b.com"].MaxDeletePercent != 25 {
+		t.Errorf("from dnsconfig.js: got %+v", m)
+	}
+
+	// Flags override dnsconfig.js and apply to every zone.
+	m, _ = getBlastLimits(zones, PPushArgs{MaxChanges: 3, MaxDeletePercent: -1})
+	if len(m) != 3 || m["a.com"].MaxChanges != 3 || m["c.com"].MaxChanges != 3 {
+		t.Errorf("with --max-changes: got %+v", m)
+	}
+
+	// --force disables everything.
+	if m, _ = getBlastLimits(zones, PPushArgs{MaxChanges: 3, Force: true}); len(m) != 0 {
+		t.Errorf("with --force: got %+v", m)
+	}
+
+	a.Metadata[metaMaxChanges] = "lots"
+	if _, err := getBlastLimits(zones, PPushArgs{MaxChanges: -1, MaxDeletePercent: -1}); err == nil {
+		t.Error("expected an error for an invalid MAX_CHANGES")
+	}
+}
+
+func Test_checkServerConfigBlastRadius(t *testing.T) {
+	a := &models.DomainConfig{Name: "a.com", Metadata: map[string]string{}}
+	b := &models.DomainConfig{Name: "b.com", Metadata: map[string]string{}}
+	for _, dc := range []*models.DomainConfig{a, b} {
+		dc.UpdateSplitHorizonNames()
+	}
+	sc := serverConfigCorrections{
+		provider:    "bind",
+		zones:       []*models.DomainConfig{a, b},
+		corrections: []*models.Correction{{Msg: "write config", F: func() error { return nil }}},
+	}
+
+	if err := checkServerConfigBlastRadius(sc, PPushArgs{MaxChanges: -1, MaxDeletePercent: -1}); err != nil {
+		t.Errorf("no limits: unexpected error %v", err)
+	}
+	b.Metadata[metaMaxChanges] = "0"
+	if err := checkServerConfigBlastRadius(sc, PPushArgs{MaxChanges: -1, MaxDeletePercent: -1}); err == nil || !strings.Contains(err.Error(), "MAX_CHANGES of b.com is 0") {
+		t.Errorf("MAX_CHANGES(0): got %v", err)
+	}
+	if err := checkServerConfigBlastRadius(sc, PPushArgs{MaxChanges: 1, MaxDeletePercent: -1}); err != nil {
+		t.Errorf("--max-changes 1: unexpected error %v", err)
+	}
+	if err := checkServerConfigBlastRadius(sc, PPushArgs{MaxChanges: 0, Force: true}); err != nil {
+		t.Errorf("--force: unexpected error %v", err)
+	}
+}
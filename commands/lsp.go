@@ -0,0 +1,171 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+	"github.com/StackExchange/dnscontrol/v4/pkg/credsfile"
+	"github.com/StackExchange/dnscontrol/v4/pkg/js"
+	"github.com/StackExchange/dnscontrol/v4/pkg/lsp"
+	"github.com/StackExchange/dnscontrol/v4/pkg/normalize"
+	"github.com/StackExchange/dnscontrol/v4/pkg/printer"
+	"github.com/StackExchange/dnscontrol/v4/pkg/rtypes"
+	"github.com/urfave/cli/v2"
+)
+
+var _ = cmd(catUtils, func() *cli.Command {
+	var args LSPArgs
+	return &cli.Command{
+		Name:  "lsp",
+		Usage: "[BETA] Run a Language Server Protocol server for dnsconfig.js on stdin/stdout",
+		Action: func(c *cli.Context) error {
+			return exit(LSP(args))
+		},
+		Flags: args.flags(),
+	}
+}())
+
+// LSPArgs contains all data/flags needed to run the language server.
+type LSPArgs struct {
+	GetCredentialsArgs
+	DevMode  bool
+	Variable cli.StringSlice
+}
+
+func (args *LSPArgs) flags() []cli.Flag {
+	flags := args.GetCredentialsArgs.flags()
+	flags = append(flags, &cli.BoolFlag{
+		Name:        "dev",
+		Destination: &args.DevMode,
+		Usage:       "Use helpers.js from disk instead of embedded copy",
+	})
+	flags = append(flags, &cli.StringSliceFlag{
+		Name:        "variable",
+		Aliases:     []string{"v"},
+		Destination: &args.Variable,
+		Usage:       "Add variable that is passed to JS",
+	})
+	return flags
+}
+
+// lspTimeout is the longest the server runs dnsconfig.js for each change.
+var lspTimeout = 10 * time.Second
+
+// LSP runs the language server until the client exits.
+func LSP(args LSPArgs) error {
+	if js.LegacyEngine {
+		return fmt.Errorf("lsp runs dnsconfig.js in a sandbox, which --legacy-js does not support")
+	}
+
+	// stdout is reserved for the protocol. Anything else printed, such as
+	// console.log() in dnsconfig.js, goes to stderr.
+	stdout := os.Stdout
+	os.Stdout = os.Stderr
+	printer.DefaultPrinter.Writer = os.Stderr
+
+	// creds.json is read once: it may run a program or read secrets.
+	creds, err := credsfile.LoadProviderConfigs(args.CredsFile)
+	if err != nil {
+		printer.Warnf("lsp: %s\n", err)
+	}
+
+	check := func(file string, src []byte) []lsp.Problem {
+		return checkDocument(args, creds, file, src)
+	}
+	providers := func() []string {
+		var names []string
+		for name := range creds {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return names
+	}
+	return lsp.NewServer(dtsContent, check, providers).Serve(os.Stdin, stdout)
+}
+
+// checkDocument runs file, whose contents are src, and validates the
+// result like the check command does. Provider types are looked up in
+// creds so that the providers can audit the records.
+//
+// The file is run in the sandbox, as it is run on every change: a loop
+// that is being typed must not hang the server, and PANIC() must not
+// exit it.
+func checkDocument(args LSPArgs, creds map[string]map[string]string, file string, src []byte) []lsp.Problem {
+	sandbox := js.Sandbox
+	defer func() { js.Sandbox = sandbox }()
+	js.Sandbox.Enabled = true
+	if js.Sandbox.Timeout <= 0 || js.Sandbox.Timeout > lspTimeout {
+		js.Sandbox.Timeout = lspTimeout
+	}
+
+	cfg, err := js.ExecuteJavaScriptSource(file, src, args.DevMode, stringSliceToMap(args.Variable))
+	if err != nil {
+		line, _, _ := js.ErrorPosition(file, err)
+		return []lsp.Problem{{Line: line, Message: err.Error()}}
+	}
+	if err := rtypes.PostProcess(cfg.Domains); err != nil {
+		return []lsp.Problem{{Message: err.Error()}}
+	}
+	if _, err := preloadProviders(cfg); err != nil {
+		return []lsp.Problem{{Message: err.Error()}}
+	}
+	if creds != nil {
+		// Missing types are reported by preview and push, not here.
+		populateProviderTypes(cfg, creds)
+	}
+
+	var problems []lsp.Problem
+	for _, err := range normalize.ValidateAndNormalizeConfig(cfg) {
+		_, isWarning := err.(normalize.Warning)
+		line := sourceLine(file, err.Error())
+		if line == 0 {
+			line = domainLine(src, cfg.Domains, err.Error())
+		}
+		problems = append(problems, lsp.Problem{
+			Line:    line,
+			Message: err.Error(),
+			Warning: isWarning,
+		})
+	}
+	return problems
+}
+
+// sourceLine returns the line of file that msg cites as the location of
+// the record it is about, or 0.
+func sourceLine(file string, msg string) int {
+	re := regexp.MustCompile(regexp.QuoteMeta(filepath.ToSlash(file)) + `:(\d+): `)
+	m := re.FindStringSubmatch(msg)
+	if m == nil {
+		return 0
+	}
+	line, _ := strconv.Atoi(m[1])
+	return line
+}
+
+// domainLine returns the line of the D() of the domain that msg is
+// about, or 0. If several domains are mentioned, the longest name wins,
+// so that a subdomain is preferred to its parent.
+func domainLine(src []byte, domains []*models.DomainConfig, msg string) int {
+	name := ""
+	for _, d := range domains {
+		if strings.Contains(msg, d.Name) && len(d.Name) > len(name) {
+			name = d.Name
+		}
+	}
+	if name == "" {
+		return 0
+	}
+	re := regexp.MustCompile(`(?i)\bD(_EXTEND)?\(\s*["']` + regexp.QuoteMeta(name) + `["'!]`)
+	loc := re.FindIndex(src)
+	if loc == nil {
+		return 0
+	}
+	return 1 + strings.Count(string(src[:loc[0]]), "\n")
+}
@@ -0,0 +1,90 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckDocument(t *testing.T) {
+	creds := map[string]map[string]string{"none": {"TYPE": "NONE"}, "bind": {"TYPE": "BIND"}}
+	tests := []struct {
+		name    string
+		src     string
+		line    int
+		message string
+	}{
+		{
+			name: "jsError",
+			src: `var REG = NewRegistrar("none");
+D("example.com", REG,
+  FOO("www")
+);`,
+			line:    3,
+			message: "FOO is not defined",
+		},
+		{
+			name: "syntaxError",
+			src: `var REG = NewRegistrar("none");
+D("example.com", REG,
+  A("www", "1.2.3.4"
+);`,
+			line:    4,
+			message: "SyntaxError",
+		},
+		{
+			name: "validation",
+			src: `var REG = NewRegistrar("none");
+var DSP = NewDnsProvider("bind");
+D("example.com", REG, DnsProvider(DSP));
+D("example.org", REG, DnsProvider(DSP),
+  CNAME("@", "www.example.com.")
+);`,
+			line:    5,
+			message: "cannot create CNAME record for bare domain",
+		},
+		{
+			name: "noRecordLocation",
+			src: `var REG = NewRegistrar("none");
+var DSP = NewDnsProvider("bind");
+D("example.com", REG, DnsProvider(DSP));
+D("example.org", REG, DnsProvider(DSP),
+  A("@", "1.2.3.4", TTL(300)),
+  A("@", "1.2.3.5", TTL(600))
+);`,
+			line:    4,
+			message: "inconsistent TTLs",
+		},
+		{
+			name:    "panic",
+			src:     `PANIC("stop");`,
+			line:    1,
+			message: "PANIC: stop",
+		},
+		{
+			name:    "loop",
+			src:     `while (true) {}`,
+			line:    1,
+			message: "sandbox: execution took longer than",
+		},
+		{
+			name:    "require",
+			src:     `require("/etc/passwd.json");`,
+			line:    1,
+			message: "is outside of",
+		},
+	}
+	defer func(d time.Duration) { lspTimeout = d }(lspTimeout)
+	lspTimeout = 100 * time.Millisecond
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems := checkDocument(LSPArgs{}, creds, "dnsconfig.js", []byte(tt.src))
+			if len(problems) != 1 {
+				t.Fatalf("got %d problems, want 1: %v", len(problems), problems)
+			}
+			if p := problems[0]; p.Line != tt.line || !strings.Contains(p.Message, tt.message) {
+				t.Errorf("got line %d %q, want line %d %q", p.Line, p.Message, tt.line, tt.message)
+			}
+		})
+	}
+}
@@ -0,0 +1,300 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+	"github.com/StackExchange/dnscontrol/v4/pkg/diff2"
+	"github.com/StackExchange/dnscontrol/v4/pkg/zonerecs"
+)
+
+// Plan is a machine-parseable description of every change that
+// ppreview would make. It is written by "ppreview --plan=FILE".
+type Plan struct {
+	Items []*PlanItem `json:"items"`
+}
+
+// PlanItem is the list of changes for a particular domain/provider/registrar.
+type PlanItem struct {
+	Domain      string        `json:"domain"`
+	Provider    string        `json:"provider,omitempty"`
+	Registrar   string        `json:"registrar,omitempty"`
+	Corrections []string      `json:"corrections"`       // The Msg of each correction that would run.
+	Changes     []*PlanChange `json:"changes,omitempty"` // Record-level changes (DNS providers only).
+}
+
+// PlanChange is the serializable form of a diff2.Change.
+type PlanChange struct {
+	Type        string         `json:"type"` // CREATE, CHANGE, DELETE, REPORT
+	NameFQDN    string         `json:"name,omitempty"`
+	RType       string         `json:"rtype,omitempty"`
+	Old         models.Records `json:"old,omitempty"`
+	New         models.Records `json:"new,omitempty"`
+	HintOnlyTTL bool           `json:"hint_only_ttl,omitempty"`
+	Msgs        []string       `json:"msgs,omitempty"`
+}
+
+// planCollector accumulates the record-level changes generated during
+// the gathering phase. It is safe for concurrent use.
+type planCollector struct {
+	changes map[string]*zonerecs.ZoneChanges
+	sync.Mutex
+}
+
+func newPlanCollector() *planCollector {
+	return &planCollector{changes: map[string]*zonerecs.ZoneChanges{}}
+}
+
+func planKey(zoneName, providerName string) string {
+	return zoneName + "\t" + providerName
+}
+
+func (pc *planCollector) store(zoneName, providerName string, zc *zonerecs.ZoneChanges) {
+	pc.Lock()
+	defer pc.Unlock()
+	pc.changes[planKey(zoneName, providerName)] = zc
+}
+
+// get returns the changes for a zone/provider. The result is never nil.
+func (pc *planCollector) get(zoneName, providerName string) *zonerecs.ZoneChanges {
+	if zc, ok := pc.lookup(zoneName, providerName); ok {
+		return zc
+	}
+	return &zonerecs.ZoneChanges{}
+}
+
+// lookup returns the changes for a zone/provider. ok is false if
+// the changes could not be gathered (for example, due to an error).
+func (pc *planCollector) lookup(zoneName, providerName string) (zc *zonerecs.ZoneChanges, ok bool) {
+	pc.Lock()
+	defer pc.Unlock()
+	zc, ok = pc.changes[planKey(zoneName, providerName)]
+	return zc, ok
+}
+
+// genPlan generates the Plan for the corrections gathered for zones and
+// the server configurations. It visits the zones and providers in the
+// same order as prun() runs them.
+func genPlan(zones []*models.DomainConfig, providerFilter string, pc *planCollector, serverConfigs []serverConfigCorrections) *Plan {
+	plan := &Plan{Items: []*PlanItem{}}
+	for _, zone := range zones {
+		providersToProcess := whichProvidersToProcess(zone.DNSProviderInstances, providerFilter)
+		for _, provider := range zone.DNSProviderInstances {
+			if skipProvider(provider.Name, providersToProcess) {
+				continue
+			}
+			pi := genPlanItem(zone.GetUniqueName(), zone.GetCorrections(provider.Name), pc.get(zone.GetUniqueName(), provider.Name).Changes)
+			pi.Provider = provider.Name
+			plan.Items = append(plan.Items, pi)
+		}
+
+		if skipProvider(zone.RegistrarInstance.Name, providersToProcess) {
+			pi := genPlanItem(zone.GetUniqueName(), zone.GetCorrections(zone.RegistrarInstance.Name), nil)
+			pi.Registrar = zone.RegistrarName
+			plan.Items = append(plan.Items, pi)
+		}
+	}
+
+	// A server configuration is not part of any domain.
+	for _, sc := range serverConfigs {
+		if len(sc.corrections) == 0 || sc.err != nil {
+			continue
+		}
+		pi := genPlanItem("", sc.corrections, nil)
+		pi.Provider = sc.provider
+		plan.Items = append(plan.Items, pi)
+	}
+	return plan
+}
+
+// genPlanItem generates the PlanItem for a zone/provider.
+func genPlanItem(zname string, corrections []*models.Correction, changes diff2.ChangeList) *PlanItem {
+	pi := &PlanItem{
+		Domain:      zname,
+		Corrections: []string{},
+	}
+	for _, cor := range corrections {
+		if cor.F != nil {
+			pi.Corrections = append(pi.Corrections, stripColor(cor.Msg))
+		}
+	}
+	for _, c := range changes {
+		pi.Changes = append(pi.Changes, newPlanChange(c))
+	}
+	return pi
+}
+
+func newPlanChange(c diff2.Change) *PlanChange {
+	pc := &PlanChange{
+		Type:        c.Type.String(),
+		NameFQDN:    c.Key.NameFQDN,
+		RType:       c.Key.Type,
+		Old:         c.Old,
+		New:         c.New,
+		HintOnlyTTL: c.HintOnlyTTL,
+	}
+	for _, m := range c.Msgs {
+		pc.Msgs = append(pc.Msgs, stripColor(m))
+	}
+	return pc
+}
+
+// ansiEscape matches the color codes that diff2 adds to messages.
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+func stripColor(s string) string {
+	return ansiEscape.ReplaceAllString(s, "")
+}
+
+func writePlan(filename string, plan *Plan) error {
+	// No filename? No plan.
+	if filename == "" {
+		return nil
+	}
+
+	b, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, b, 0644)
+}
+
+func readPlan(filename string) (*Plan, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var plan Plan
+	if err := json.Unmarshal(b, &plan); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return &plan, nil
+}
+
+// String returns a human-readable name for the item.
+func (pi *PlanItem) String() string {
+	if pi.Registrar != "" {
+		return fmt.Sprintf("domain %q registrar %q", pi.Domain, pi.Registrar)
+	}
+	if pi.Domain == "" {
+		return fmt.Sprintf("server configuration of provider %q", pi.Provider)
+	}
+	return fmt.Sprintf("domain %q provider %q", pi.Domain, pi.Provider)
+}
+
+func (pi *PlanItem) key() string {
+	return pi.Domain + "\t" + pi.Provider + "\t" + pi.Registrar
+}
+
+// fingerprints returns a string that uniquely identifies each
+// correction and (non-REPORT) change of the item, mapped to a
+// human-readable description.
+func (pi *PlanItem) fingerprints() (map[string]string, error) {
+	m := map[string]string{}
+	// Where records are defined in dnsconfig.js is not part of the
+	// fingerprint: moving a record doesn't change what will be done.
+	for _, c := range pi.Corrections {
+		m["correction\t"+sourceNote.ReplaceAllString(c, "")] = c
+	}
+	for _, c := range pi.Changes {
+		if c.Type == diff2.REPORT.String() {
+			continue // Informational. Nothing will be changed.
+		}
+		fc := *c
+		fc.Msgs = nil // Generated from Old and New.
+		fc.Old, fc.New = withoutSource(c.Old), withoutSource(c.New)
+		b, err := json.Marshal(fc)
+		if err != nil {
+			return nil, err
+		}
+		m["change\t"+string(b)] = strings.Join(c.Msgs, "; ")
+	}
+	return m, nil
+}
+
+// sourceNote matches the location that diff2 adds to messages about
+// records from dnsconfig.js.
+var sourceNote = regexp.MustCompile(` \(from [^ ()]+:\d+\)`)
+
+// withoutSource returns copies of recs without their Source.
+func withoutSource(recs models.Records) models.Records {
+	if recs == nil {
+		return nil
+	}
+	result := make(models.Records, len(recs))
+	for i, r := range recs {
+		c := *r
+		c.Source = nil
+		result[i] = &c
+	}
+	return result
+}
+
+// diffPlans compares the approved plan with the current plan and
+// returns a human-readable description of each difference.  An empty
+// list means the current plan would make exactly the approved changes.
+func diffPlans(approved, current *Plan) []string {
+	var diffs []string
+
+	approvedByKey := map[string]*PlanItem{}
+	for _, pi := range approved.Items {
+		approvedByKey[pi.key()] = pi
+	}
+
+	seen := map[string]bool{}
+	for _, cur := range current.Items {
+		seen[cur.key()] = true
+		app, ok := approvedByKey[cur.key()]
+		if !ok {
+			app = &PlanItem{Domain: cur.Domain, Provider: cur.Provider, Registrar: cur.Registrar}
+		}
+		diffs = append(diffs, diffPlanItems(app, cur)...)
+	}
+
+	for _, app := range approved.Items {
+		if !seen[app.key()] && len(app.Corrections) != 0 {
+			diffs = append(diffs, fmt.Sprintf("%s: in the approved plan but not being processed", app))
+		}
+	}
+
+	return diffs
+}
+
+func diffPlanItems(approved, current *PlanItem) []string {
+	a, err := approved.fingerprints()
+	if err != nil {
+		return []string{fmt.Sprintf("%s: %s", approved, err)}
+	}
+	c, err := current.fingerprints()
+	if err != nil {
+		return []string{fmt.Sprintf("%s: %s", current, err)}
+	}
+
+	var diffs []string
+	for _, k := range sortedKeys(c) {
+		if _, ok := a[k]; !ok {
+			diffs = append(diffs, fmt.Sprintf("%s: not in the approved plan: %s", current, c[k]))
+		}
+	}
+	for _, k := range sortedKeys(a) {
+		if _, ok := c[k]; !ok {
+			diffs = append(diffs, fmt.Sprintf("%s: approved but no longer needed: %s", current, a[k]))
+		}
+	}
+	return diffs
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
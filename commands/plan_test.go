@@ -0,0 +1,153 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+	"github.com/StackExchange/dnscontrol/v4/pkg/diff2"
+)
+
+func Test_genPlanItem(t *testing.T) {
+	rOld := &models.RecordConfig{Type: "A", Name: "www", TTL: 300}
+	rOld.SetTarget("1.2.3.4")
+	rNew := &models.RecordConfig{Type: "A", Name: "www", TTL: 600}
+	rNew.SetTarget("1.2.3.4")
+
+	corrections := []*models.Correction{
+		{Msg: "info only"},
+		{Msg: "\x1b[33m± MODIFY www.example.com A\x1b[0m", F: func() error { return nil }},
+	}
+	changes := diff2.ChangeList{{
+		Type:        diff2.CHANGE,
+		Key:         models.RecordKey{NameFQDN: "www.example.com", Type: "A"},
+		Old:         models.Records{rOld},
+		New:         models.Records{rNew},
+		Msgs:        []string{"\x1b[33m± MODIFY-TTL www.example.com A\x1b[0m"},
+		HintOnlyTTL: true,
+	}}
+
+	pi := genPlanItem("example.com", corrections, changes)
+
+	if len(pi.Corrections) != 1 || pi.Corrections[0] != "± MODIFY www.example.com A" {
+		t.Errorf("genPlanItem() corrections = %q", pi.Corrections)
+	}
+	if len(pi.Changes) != 1 {
+		t.Fatalf("genPlanItem() got %d changes, want 1", len(pi.Changes))
+	}
+	c := pi.Changes[0]
+	if c.Type != "CHANGE" || c.NameFQDN != "www.example.com" || c.RType != "A" || !c.HintOnlyTTL {
+		t.Errorf("genPlanItem() change = %+v", c)
+	}
+	if c.Msgs[0] != "± MODIFY-TTL www.example.com A" {
+		t.Errorf("genPlanItem() msgs = %q", c.Msgs)
+	}
+
+	// The plan must survive a round trip through JSON.
+	b, err := json.Marshal(pi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got PlanItem
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Changes[0].New[0].GetTargetField() != "1.2.3.4" || got.Changes[0].New[0].TTL != 600 {
+		t.Errorf("round trip lost data: %+v", got.Changes[0].New[0])
+	}
+}
+
+func Test_diffPlans(t *testing.T) {
+	mkPlan := func(target string) *Plan {
+		r := &models.RecordConfig{Type: "A", Name: "www", TTL: 300}
+		r.SetTarget(target)
+		changes := diff2.ChangeList{
+			{Type: diff2.REPORT, Msgs: []string{"ignored " + target}},
+			{
+				Type: diff2.CREATE,
+				Key:  models.RecordKey{NameFQDN: "www.example.com", Type: "A"},
+				New:  models.Records{r},
+				Msgs: []string{"+ CREATE www.example.com A " + target},
+			},
+		}
+		corrections := []*models.Correction{{Msg: "create " + target, F: func() error { return nil }}}
+		pi := genPlanItem("example.com", corrections, changes)
+		pi.Provider = "bind"
+		return &Plan{Items: []*PlanItem{pi}}
+	}
+
+	// Round trip the approved plan through JSON as if read from a file.
+	roundTrip := func(p *Plan) *Plan {
+		b, err := json.Marshal(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got Plan
+		if err := json.Unmarshal(b, &got); err != nil {
+			t.Fatal(err)
+		}
+		return &got
+	}
+
+	if diffs := diffPlans(roundTrip(mkPlan("1.2.3.4")), mkPlan("1.2.3.4")); len(diffs) != 0 {
+		t.Errorf("diffPlans() identical plans: got %q", diffs)
+	}
+
+	// A change in the REPORT messages isn't a difference.
+	approved := mkPlan("1.2.3.4")
+	approved.Items[0].Changes[0].Msgs = []string{"something else"}
+	if diffs := diffPlans(roundTrip(approved), mkPlan("1.2.3.4")); len(diffs) != 0 {
+		t.Errorf("diffPlans() REPORT only: got %q", diffs)
+	}
+
+	// Moving the record in dnsconfig.js isn't a difference.
+	moved := func(line int) *Plan {
+		p := mkPlan("1.2.3.4")
+		pi := p.Items[0]
+		pi.Changes[1].New[0].Source = &models.SourceLocation{File: "dnsconfig.js", Line: line}
+		note := fmt.Sprintf(" (from dnsconfig.js:%d)", line)
+		pi.Changes[1].Msgs[0] += note
+		pi.Corrections[0] += note
+		return p
+	}
+	if diffs := diffPlans(roundTrip(moved(3)), moved(7)); len(diffs) != 0 {
+		t.Errorf("diffPlans() moved record: got %q", diffs)
+	}
+
+	// Drift: 1 correction and 1 change added, 1 of each removed.
+	if diffs := diffPlans(roundTrip(mkPlan("1.2.3.4")), mkPlan("5.6.7.8")); len(diffs) != 4 {
+		t.Errorf("diffPlans() drifted: got %d diffs, want 4: %q", len(diffs), diffs)
+	}
+
+	// Items with work that aren't in the approved plan are differences.
+	if diffs := diffPlans(&Plan{}, mkPlan("1.2.3.4")); len(diffs) != 2 {
+		t.Errorf("diffPlans() unapproved item: got %d diffs, want 2: %q", len(diffs), diffs)
+	}
+
+	// Approved items that are not being processed are differences.
+	if diffs := diffPlans(mkPlan("1.2.3.4"), &Plan{}); len(diffs) != 1 {
+		t.Errorf("diffPlans() missing item: got %d diffs, want 1: %q", len(diffs), diffs)
+	}
+}
+
+func Test_genPlanServerConfig(t *testing.T) {
+	sc := func(msg string) []serverConfigCorrections {
+		return []serverConfigCorrections{
+			{provider: "bind", corrections: []*models.Correction{{Msg: msg, F: func() error { return nil }}}},
+			{provider: "unchanged"},
+		}
+	}
+	plan := genPlan(nil, "", newPlanCollector(), sc("write config"))
+	if len(plan.Items) != 1 || plan.Items[0].Provider != "bind" || plan.Items[0].Corrections[0] != "write config" {
+		t.Fatalf("genPlan() = %+v", plan.Items)
+	}
+
+	// A server configuration that wasn't approved is a difference.
+	if diffs := diffPlans(&Plan{}, plan); len(diffs) != 1 || diffs[0] != `server configuration of provider "bind": not in the approved plan: write config` {
+		t.Errorf("diffPlans() unapproved server config: got %q", diffs)
+	}
+	if diffs := diffPlans(plan, genPlan(nil, "", newPlanCollector(), sc("write other config"))); len(diffs) != 2 {
+		t.Errorf("diffPlans() changed server config: got %d diffs, want 2: %q", len(diffs), diffs)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/StackExchange/dnscontrol/v4/models"
 	"github.com/StackExchange/dnscontrol/v4/pkg/bindserial"
 	"github.com/StackExchange/dnscontrol/v4/pkg/credsfile"
+	"github.com/StackExchange/dnscontrol/v4/pkg/diff2"
 	"github.com/StackExchange/dnscontrol/v4/pkg/nameservers"
 	"github.com/StackExchange/dnscontrol/v4/pkg/normalize"
 	"github.com/StackExchange/dnscontrol/v4/pkg/notifications"
@@ -36,7 +37,11 @@ var _ = cmd(catMain, func() *cli.Command {
 		Action: func(ctx *cli.Context) error {
 			return exit(PPreview(args))
 		},
-		Flags: args.flags(),
+		Flags: append(args.flags(), &cli.StringFlag{
+			Name:        "plan",
+			Destination: &args.PlanFile,
+			Usage:       `Write a machine-parseable plan of every record-level change to this file`,
+		}),
 	}
 }())
 
@@ -51,6 +56,7 @@ type PPreviewArgs struct {
 	NoPopulate  bool
 	DePopulate  bool
 	Full        bool
+	PlanFile    string
 }
 
 // ReportItem is a record of corrections for a particular domain/provider/registrar.
@@ -116,6 +122,11 @@ func (args *PPreviewArgs) flags() []cli.Flag {
 		Destination: &bindserial.ForcedValue,
 		Usage:       `Force BIND serial numbers to this value (for reproducibility)`,
 	})
+	flags = append(flags, &cli.BoolFlag{
+		Name:        "allow-protected",
+		Destination: &diff2.AllowProtected,
+		Usage:       `Permit changes to records that match a PROTECT() pattern`,
+	})
 	return flags
 }
 
@@ -134,8 +145,13 @@ var _ = cmd(catMain, func() *cli.Command {
 // PPushArgs contains all data/flags needed to run push, independently of CLI
 type PPushArgs struct {
 	PPreviewArgs
-	Interactive bool
-	Report      string
+	Interactive      bool
+	Report           string
+	ApplyPlan        string
+	RollbackOnError  bool
+	MaxChanges       int     // -1 means use the domain's MAX_CHANGES().
+	MaxDeletePercent float64 // -1 means use the domain's MAX_DELETE_PERCENT().
+	Force            bool    // Ignore MAX_CHANGES() and MAX_DELETE_PERCENT().
 }
 
 func (args *PPushArgs) flags() []cli.Flag {
@@ -150,23 +166,62 @@ func (args *PPushArgs) flags() []cli.Flag {
 		Destination: &args.Report,
 		Usage:       `Generate a machine-parseable report of performed corrections.`,
 	})
+	flags = append(flags, &cli.StringFlag{
+		Name:        "plan",
+		Destination: &args.ApplyPlan,
+		Usage:       `Apply the plan written by "ppreview --plan". Abort if the changes differ from the plan`,
+	})
+	flags = append(flags, &cli.BoolFlag{
+		Name:        "rollback-on-error",
+		Destination: &args.RollbackOnError,
+		Usage:       `If a correction fails, restore the zone's records to how they were before the push`,
+	})
+	flags = append(flags, &cli.IntFlag{
+		Name:        "max-changes",
+		Destination: &args.MaxChanges,
+		Value:       -1,
+		Usage:       `Refuse to push a zone if more than this many records would change (overrides MAX_CHANGES; -1 = no override)`,
+	})
+	flags = append(flags, &cli.Float64Flag{
+		Name:        "max-delete-percent",
+		Destination: &args.MaxDeletePercent,
+		Value:       -1,
+		Usage:       `Refuse to push a zone if more than this percent of its records would be deleted (overrides MAX_DELETE_PERCENT; -1 = no override)`,
+	})
+	flags = append(flags, &cli.BoolFlag{
+		Name:        "force",
+		Destination: &args.Force,
+		Usage:       `Push even if MAX_CHANGES or MAX_DELETE_PERCENT would be exceeded`,
+	})
 	return flags
 }
 
 // PPreview implements the preview subcommand.
 func PPreview(args PPreviewArgs) error {
-	return prun(args, false, false, printer.DefaultPrinter, "")
+	return prun(PPushArgs{PPreviewArgs: args, MaxChanges: -1, MaxDeletePercent: -1}, false, printer.DefaultCLI, nil)
 }
 
 // PPush implements the push subcommand.
 func PPush(args PPushArgs) error {
-	return prun(args.PPreviewArgs, true, args.Interactive, printer.DefaultPrinter, args.Report)
+	var approved *Plan
+	if args.ApplyPlan != "" {
+		var err error
+		approved, err = readPlan(args.ApplyPlan)
+		if err != nil {
+			return fmt.Errorf("could not read plan: %w", err)
+		}
+	}
+	return prun(args, true, printer.DefaultCLI, approved)
 }
 
 var pobsoleteDiff2FlagUsed = false
 
 // run is the main routine common to preview/push
-func prun(args PPreviewArgs, push bool, interactive bool, out printer.CLI, report string) error {
+func prun(pargs PPushArgs, push bool, out printer.CLI, approved *Plan) error {
+	args := pargs.PPreviewArgs
+	interactive := pargs.Interactive
+	report := pargs.Report
+	rollback := pargs.RollbackOnError
 
 	// This is a hack until we have the new printer replacement.
 	printer.SkinnyReport = !args.Full
@@ -200,30 +255,50 @@ func prun(args PPreviewArgs, push bool, interactive bool, out printer.CLI, repor
 		return fmt.Errorf("exiting due to validation errors")
 	}
 
-	zcache := NewZoneCache()
-
 	// Loop over all (or some) zones:
 	zonesToProcess := whichZonesToProcess(cfg.Domains, args.Domains)
-	zonesSerial, zonesConcurrent := splitConcurrent(zonesToProcess, args.ConcurMode)
+
+	limits, err := getBlastLimits(zonesToProcess, pargs)
+	if err != nil {
+		return err
+	}
+
+	zcache := NewZoneCache()
+
+	// Collect the record-level changes only if a plan was requested,
+	// a rollback may be needed (the changes include a snapshot of the zone),
+	// or the size of the changes must be checked.
+	var pcollector *planCollector
+	if args.PlanFile != "" || approved != nil || (push && rollback) || len(limits) != 0 {
+		pcollector = newPlanCollector()
+	}
+
 	out.PrintfIf(fullMode, "PHASE 1: GATHERING data\n")
-	var wg sync.WaitGroup
-	wg.Add(len(zonesConcurrent))
-	out.Printf("CONCURRENTLY gathering %d zone(s)\n", len(zonesConcurrent))
-	for _, zone := range optimizeOrder(zonesConcurrent) {
-		out.PrintfIf(fullMode, "Concurrently gathering: %q\n", zone.Name)
-		go func(zone *models.DomainConfig, args PPreviewArgs, zcache *zoneCache) {
-			defer wg.Done()
-			oneZone(zone, args, zcache)
-		}(zone, args, zcache)
+	gatherZones(zonesToProcess, args, zcache, pcollector, out)
+
+	// The server configurations list the zones of a provider:
+	shouldRun := func(name string, dc *models.DomainConfig) bool {
+		return !skipProvider(name, whichProvidersToProcess(dc.DNSProviderInstances, args.Providers))
 	}
-	out.Printf("SERIALLY gathering %d zone(s)\n", len(zonesSerial))
-	for _, zone := range zonesSerial {
-		out.Printf("Serially Gathering: %q\n", zone.Name)
-		oneZone(zone, args, zcache)
+	serverConfigs := generateServerConfigCorrections(cfg.Domains, shouldRun)
+
+	if args.PlanFile != "" || approved != nil {
+		plan := genPlan(zonesToProcess, args.Providers, pcollector, serverConfigs)
+		if err := writePlan(args.PlanFile, plan); err != nil {
+			return fmt.Errorf("could not write plan: %w", err)
+		}
+
+		// Refuse to make any changes if they aren't exactly what was approved.
+		if approved != nil {
+			out.PrintfIf(fullMode, "Comparing changes to the approved plan\n")
+			if diffs := diffPlans(approved, plan); len(diffs) != 0 {
+				for _, d := range diffs {
+					out.Errorf("%s\n", d)
+				}
+				return fmt.Errorf("live data has drifted from the approved plan. No changes were made")
+			}
+		}
 	}
-	out.PrintfIf(len(zonesConcurrent) > 0, "Waiting for concurrent gathering(s) to complete...")
-	wg.Wait()
-	out.PrintfIf(len(zonesConcurrent) > 0, "DONE\n")
 
 	// Now we know what to do, print or do the tasks.
 	out.PrintfIf(fullMode, "PHASE 2: CORRECTIONS\n")
@@ -244,7 +319,23 @@ func prun(args PPreviewArgs, push bool, interactive bool, out printer.CLI, repor
 				totalCorrections += numActions
 				out.EndProvider2(provider.Name, numActions)
 				reportItems = append(reportItems, genReportItem(zone.Name, corrections, provider.Name))
-				anyErrors = cmp.Or(anyErrors, pprintOrRunCorrections(zone.Name, provider.Name, corrections, out, push, interactive, notifier, report))
+				zpush := push
+				if err := checkBlastRadius(limits, pcollector, zone, provider); err != nil {
+					if push {
+						out.Errorf("REFUSING to push %s to %s: %s. Use --force to override.\n", zone.GetUniqueName(), provider.Name, err)
+						anyErrors = true
+						zpush = false // Print the corrections but don't run them.
+					} else {
+						out.Warnf("ppush will refuse to push %s to %s: %s\n", zone.GetUniqueName(), provider.Name, err)
+					}
+				}
+				failed := pprintOrRunCorrections(zone.Name, provider.Name, corrections, out, zpush, interactive, notifier, report)
+				anyErrors = cmp.Or(anyErrors, failed)
+				if failed && push && rollback {
+					if zc, ok := pcollector.lookup(zone.GetUniqueName(), provider.Name); ok {
+						rollbackZone(zone, provider, zc.Existing, out, notifier)
+					}
+				}
 			}
 		}
 
@@ -262,6 +353,34 @@ func prun(args PPreviewArgs, push bool, interactive bool, out printer.CLI, repor
 
 	}
 
+	// Process the server configurations:
+	for _, sc := range serverConfigs {
+		if len(sc.corrections) == 0 && sc.err == nil {
+			continue
+		}
+		out.StartDNSProvider(sc.provider, false)
+		if sc.err != nil {
+			out.Errorf("%s\n", sc.err)
+			anyErrors = true
+			continue
+		}
+		numActions := countActions(sc.corrections)
+		totalCorrections += numActions
+		out.EndProvider2(sc.provider, numActions)
+		reportItems = append(reportItems, genReportItem("", sc.corrections, sc.provider))
+		zpush := push
+		if err := checkServerConfigBlastRadius(sc, pargs); err != nil {
+			if push {
+				out.Errorf("REFUSING to write the server configuration of %s: %s. Use --force to override.\n", sc.provider, err)
+				anyErrors = true
+				zpush = false // Print the corrections but don't run them.
+			} else {
+				out.Warnf("ppush will refuse to write the server configuration of %s: %s\n", sc.provider, err)
+			}
+		}
+		anyErrors = cmp.Or(anyErrors, pprintOrRunCorrections("", sc.provider, sc.corrections, out, zpush, interactive, notifier, report))
+	}
+
 	if os.Getenv("TEAMCITY_VERSION") != "" {
 		fmt.Fprintf(os.Stderr, "##teamcity[buildStatus status='SUCCESS' text='%d corrections']", totalCorrections)
 	}
@@ -281,6 +400,31 @@ func prun(args PPreviewArgs, push bool, interactive bool, out printer.CLI, repor
 	return nil
 }
 
+// gatherZones gathers the corrections for each zone (see oneZone),
+// concurrently if the providers permit.
+func gatherZones(zonesToProcess []*models.DomainConfig, args PPreviewArgs, zcache *zoneCache, pcollector *planCollector, out printer.CLI) {
+	fullMode := args.Full
+	zonesSerial, zonesConcurrent := splitConcurrent(zonesToProcess, args.ConcurMode)
+	var wg sync.WaitGroup
+	wg.Add(len(zonesConcurrent))
+	out.Printf("CONCURRENTLY gathering %d zone(s)\n", len(zonesConcurrent))
+	for _, zone := range optimizeOrder(zonesConcurrent) {
+		out.PrintfIf(fullMode, "Concurrently gathering: %q\n", zone.Name)
+		go func(zone *models.DomainConfig, args PPreviewArgs, zcache *zoneCache) {
+			defer wg.Done()
+			oneZone(zone, args, zcache, pcollector)
+		}(zone, args, zcache)
+	}
+	out.Printf("SERIALLY gathering %d zone(s)\n", len(zonesSerial))
+	for _, zone := range zonesSerial {
+		out.Printf("Serially Gathering: %q\n", zone.Name)
+		oneZone(zone, args, zcache, pcollector)
+	}
+	out.PrintfIf(len(zonesConcurrent) > 0, "Waiting for concurrent gathering(s) to complete...")
+	wg.Wait()
+	out.PrintfIf(len(zonesConcurrent) > 0, "DONE\n")
+}
+
 func countActions(corrections []*models.Correction) int {
 	r := 0
 	for _, c := range corrections {
@@ -361,7 +505,7 @@ func optimizeOrder(zones []*models.DomainConfig) []*models.DomainConfig {
 	return zones
 }
 
-func oneZone(zone *models.DomainConfig, args PPreviewArgs, zc *zoneCache) {
+func oneZone(zone *models.DomainConfig, args PPreviewArgs, zc *zoneCache, pc *planCollector) {
 	// Fix the parent zone's delegation: (if able/needed)
 	//zone.NameserversMutex.Lock()
 	delegationCorrections := generateDelegationCorrections(zone, zone.DNSProviderInstances, zone.RegistrarInstance)
@@ -373,12 +517,12 @@ func oneZone(zone *models.DomainConfig, args PPreviewArgs, zc *zoneCache) {
 
 		// Populate the zones at the provider (if desired/needed/able):
 		if !args.NoPopulate {
-			populateCorrections := generatePopulateCorrections(provider, zone.Name, zc)
+			populateCorrections := generatePopulateCorrections(provider, zone.Name, zone.Metadata[models.DomainTag], zc)
 			zone.StoreCorrections(provider.Name, populateCorrections)
 		}
 
 		// Update the zone's records at the provider:
-		zoneCor, rep := generateZoneCorrections(zone, provider)
+		zoneCor, rep := generateZoneCorrections(zone, provider, pc)
 		zone.StoreCorrections(provider.Name, rep)
 		zone.StoreCorrections(provider.Name, zoneCor)
 	}
@@ -504,7 +648,21 @@ func writeReport(report string, reportItems []*ReportItem) error {
 	return nil
 }
 
-func generatePopulateCorrections(provider *models.DNSProviderInstance, zoneName string, zcache *zoneCache) []*models.Correction {
+func generatePopulateCorrections(provider *models.DNSProviderInstance, zoneName, tag string, zcache *zoneCache) []*models.Correction {
+
+	if creator, ok := provider.Driver.(providers.SplitHorizonZoneCreator); ok && tag != "" {
+		exists, err := creator.TaggedZoneExists(zoneName, tag)
+		if err != nil {
+			return []*models.Correction{{Msg: fmt.Sprintf("TaggedZoneExists failed for %q: %s", provider.Name, err)}}
+		}
+		if exists {
+			return nil
+		}
+		return []*models.Correction{{
+			Msg: fmt.Sprintf("Create zone '%s!%s' in the '%s' profile", zoneName, tag, provider.Name),
+			F:   func() error { return creator.EnsureTaggedZoneExists(zoneName, tag) },
+		}}
+	}
 
 	lister, ok := provider.Driver.(providers.ZoneLister)
 	if !ok {
@@ -533,11 +691,20 @@ func generatePopulateCorrections(provider *models.DNSProviderInstance, zoneName
 	}}
 }
 
-func generateZoneCorrections(zone *models.DomainConfig, provider *models.DNSProviderInstance) ([]*models.Correction, []*models.Correction) {
-	reports, zoneCorrections, err := zonerecs.CorrectZoneRecords(provider.Driver, zone)
+func generateZoneCorrections(zone *models.DomainConfig, provider *models.DNSProviderInstance, pc *planCollector) ([]*models.Correction, []*models.Correction) {
+	if pc == nil {
+		reports, zoneCorrections, err := zonerecs.CorrectZoneRecords(provider.Driver, zone)
+		if err != nil {
+			return []*models.Correction{{Msg: fmt.Sprintf("Domain %q provider %s Error: %s", zone.Name, provider.Name, err)}}, nil
+		}
+		return zoneCorrections, reports
+	}
+
+	reports, zoneCorrections, changes, err := zonerecs.CorrectZoneRecordsWithChanges(provider.Driver, zone)
 	if err != nil {
 		return []*models.Correction{{Msg: fmt.Sprintf("Domain %q provider %s Error: %s", zone.Name, provider.Name, err)}}, nil
 	}
+	pc.store(zone.GetUniqueName(), provider.Name, changes)
 	return zoneCorrections, reports
 }
 
@@ -558,7 +725,91 @@ func generateDelegationCorrections(zone *models.DomainConfig, providers []*model
 	if err != nil {
 		return msg(fmt.Sprintf("zone %q; Rprovider %q; Error: %s", zone.Name, zone.RegistrarInstance.Name, err))
 	}
-	return corrections
+	dsCorrections, err := generateDSCorrections(zone, providers)
+	if err != nil {
+		return msg(fmt.Sprintf("zone %q; Rprovider %q; Error: %s", zone.Name, zone.RegistrarInstance.Name, err))
+	}
+	return append(corrections, dsCorrections...)
+}
+
+// generateDSCorrections returns the registrar corrections that update the
+// DS records at the parent to the DS set reported by the DNS providers
+// that manage the zone's DNSSEC keys (providers.DSLister). The parent's
+// DS records are left alone if no provider manages the keys or the
+// registrar can't update DS records.
+func generateDSCorrections(zone *models.DomainConfig, dsps []*models.DNSProviderInstance) ([]*models.Correction, error) {
+	registrar, ok := zone.RegistrarInstance.Driver.(providers.DSRegistrar)
+	if !ok {
+		return nil, nil
+	}
+
+	var ds models.Records
+	managed := false
+	for _, dsp := range dsps {
+		lister, ok := dsp.Driver.(providers.DSLister)
+		if !ok {
+			continue
+		}
+		recs, err := lister.GetDSRecords(zone)
+		if err != nil {
+			return nil, fmt.Errorf("error while getting DS records for zone=%q with provider=%q: %w", zone.Name, dsp.Name, err)
+		}
+		if recs != nil {
+			managed = true
+			ds = append(ds, recs...)
+		}
+	}
+	if !managed {
+		return nil, nil
+	}
+
+	return registrar.GetRegistrarDSCorrections(zone, ds)
+}
+
+// serverConfigCorrections are the corrections of a providers.ServerConfigWriter.
+type serverConfigCorrections struct {
+	provider    string
+	zones       []*models.DomainConfig // The domains that use the provider.
+	corrections []*models.Correction
+	err         error
+}
+
+// generateServerConfigCorrections returns the corrections of the DNS
+// providers that write the configuration of their name servers
+// (providers.ServerConfigWriter), in the order the providers are first
+// used. Each gets all the domains that use it, so that the configuration
+// lists every zone whichever domains are selected. shouldRun tells if
+// a provider is selected.
+func generateServerConfigCorrections(domains []*models.DomainConfig, shouldRun func(name string, dc *models.DomainConfig) bool) []serverConfigCorrections {
+	var names []string
+	writers := map[string]providers.ServerConfigWriter{}
+	zones := map[string][]*models.DomainConfig{}
+	for _, dc := range domains {
+		for _, p := range dc.DNSProviderInstances {
+			writer, ok := p.Driver.(providers.ServerConfigWriter)
+			if !ok {
+				continue
+			}
+			if _, ok := writers[p.Name]; !ok {
+				writers[p.Name] = writer
+				names = append(names, p.Name)
+			}
+			zones[p.Name] = append(zones[p.Name], dc)
+		}
+	}
+
+	var out []serverConfigCorrections
+	for _, name := range names {
+		if !shouldRun(name, zones[name][0]) {
+			continue
+		}
+		corrections, err := writers[name].GetServerConfigCorrections(zones[name])
+		if err != nil {
+			err = fmt.Errorf("error while getting the server configuration of provider=%q: %w", name, err)
+		}
+		out = append(out, serverConfigCorrections{provider: name, zones: zones[name], corrections: corrections, err: err})
+	}
+	return out
 }
 
 func msg(s string) []*models.Correction {
@@ -569,10 +820,17 @@ func msg(s string) []*models.Correction {
 func PInitializeProviders(cfg *models.DNSConfig, providerConfigs map[string]map[string]string, notifyFlag bool) (notify notifications.Notifier, err error) {
 	var notificationCfg map[string]string
 	defer func() {
-		notify = notifications.Init(notificationCfg)
+		var nerr error
+		notify, nerr = notifications.Init(notificationCfg)
+		if err == nil {
+			err = nerr
+		}
 	}()
 	if notifyFlag {
 		notificationCfg = providerConfigs["notifications"]
+		if err = credsfile.ResolveSecrets("notifications", notificationCfg); err != nil {
+			return
+		}
 	}
 	isNonDefault := map[string]bool{}
 	for name, vals := range providerConfigs {
@@ -597,6 +855,9 @@ func PInitializeProviders(cfg *models.DNSConfig, providerConfigs map[string]map[
 	for _, d := range cfg.Domains {
 		if registrars[d.RegistrarName] == nil {
 			rCfg := cfg.RegistrarsByName[d.RegistrarName]
+			if err := credsfile.ResolveSecrets(d.RegistrarName, providerConfigs[d.RegistrarName]); err != nil {
+				return nil, err
+			}
 			r, err := providers.CreateRegistrar(rCfg.Type, providerConfigs[d.RegistrarName])
 			if err != nil {
 				return nil, err
@@ -608,6 +869,9 @@ func PInitializeProviders(cfg *models.DNSConfig, providerConfigs map[string]map[
 		for _, pInst := range d.DNSProviderInstances {
 			if dnsProviders[pInst.Name] == nil {
 				dCfg := cfg.DNSProvidersByName[pInst.Name]
+				if err := credsfile.ResolveSecrets(dCfg.Name, providerConfigs[dCfg.Name]); err != nil {
+					return nil, err
+				}
 				prov, err := providers.CreateDNSProvider(dCfg.Type, providerConfigs[dCfg.Name], dCfg.Metadata)
 				if err != nil {
 					return nil, err
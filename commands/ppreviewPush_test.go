@@ -0,0 +1,105 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+)
+
+type fakeDSRegistrar struct {
+	got models.Records
+}
+
+func (r *fakeDSRegistrar) GetRegistrarCorrections(*models.DomainConfig) ([]*models.Correction, error) {
+	return nil, nil
+}
+
+func (r *fakeDSRegistrar) GetRegistrarDSCorrections(_ *models.DomainConfig, ds models.Records) ([]*models.Correction, error) {
+	r.got = ds
+	return []*models.Correction{{Msg: "update DS"}}, nil
+}
+
+type fakeDSP struct {
+	models.DNSProvider
+}
+
+type fakeSigner struct {
+	models.DNSProvider
+	ds models.Records
+}
+
+func (s fakeSigner) GetDSRecords(*models.DomainConfig) (models.Records, error) {
+	return s.ds, nil
+}
+
+func TestGenerateDSCorrections(t *testing.T) {
+	ds := &models.RecordConfig{Type: "DS"}
+	ds.SetLabel("@", "example.com")
+	if err := ds.SetTargetDSString("1001 13 2 BB01"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		dsps      []*models.DNSProviderInstance
+		wantCalls bool
+		wantDS    int
+	}{
+		{"no lister", []*models.DNSProviderInstance{{Driver: fakeDSP{}}}, false, 0},
+		{"unmanaged", []*models.DNSProviderInstance{{Driver: fakeSigner{}}}, false, 0},
+		{"signed", []*models.DNSProviderInstance{{Driver: fakeDSP{}}, {Driver: fakeSigner{ds: models.Records{ds}}}}, true, 1},
+		{"unsigned", []*models.DNSProviderInstance{{Driver: fakeSigner{ds: models.Records{}}}}, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := &fakeDSRegistrar{}
+			zone := &models.DomainConfig{Name: "example.com", RegistrarInstance: &models.RegistrarInstance{Driver: reg}}
+			corrections, err := generateDSCorrections(zone, tt.dsps)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if called := len(corrections) == 1; called != tt.wantCalls {
+				t.Fatalf("registrar called = %v, want %v", called, tt.wantCalls)
+			}
+			if len(reg.got) != tt.wantDS {
+				t.Errorf("got %d DS records, want %d", len(reg.got), tt.wantDS)
+			}
+		})
+	}
+}
+
+type fakeConfigWriter struct {
+	models.DNSProvider
+	got *[]string
+}
+
+func (w fakeConfigWriter) GetServerConfigCorrections(dcs []*models.DomainConfig) ([]*models.Correction, error) {
+	for _, dc := range dcs {
+		*w.got = append(*w.got, dc.Name)
+	}
+	return []*models.Correction{{Msg: "write config"}}, nil
+}
+
+func TestGenerateServerConfigCorrections(t *testing.T) {
+	var zonesA, zonesB []string
+	a := &models.DNSProviderInstance{ProviderBase: models.ProviderBase{Name: "a"}, Driver: fakeConfigWriter{got: &zonesA}}
+	b := &models.DNSProviderInstance{ProviderBase: models.ProviderBase{Name: "b"}, Driver: fakeConfigWriter{got: &zonesB}}
+	other := &models.DNSProviderInstance{ProviderBase: models.ProviderBase{Name: "other"}, Driver: fakeDSP{}}
+	domains := []*models.DomainConfig{
+		{Name: "example.com", DNSProviderInstances: []*models.DNSProviderInstance{a, other}},
+		{Name: "example.net", DNSProviderInstances: []*models.DNSProviderInstance{b}},
+		{Name: "example.org", DNSProviderInstances: []*models.DNSProviderInstance{a, b}},
+	}
+
+	got := generateServerConfigCorrections(domains, func(name string, _ *models.DomainConfig) bool { return name != "b" })
+	if len(got) != 1 || got[0].provider != "a" || len(got[0].corrections) != 1 || got[0].err != nil {
+		t.Fatalf("got %+v", got)
+	}
+	if strings.Join(zonesA, " ") != "example.com example.org" {
+		t.Errorf("provider a got zones %v", zonesA)
+	}
+	if len(zonesB) != 0 {
+		t.Errorf("provider b was not selected but got zones %v", zonesB)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/StackExchange/dnscontrol/v4/models"
 	"github.com/StackExchange/dnscontrol/v4/pkg/bindserial"
 	"github.com/StackExchange/dnscontrol/v4/pkg/credsfile"
+	"github.com/StackExchange/dnscontrol/v4/pkg/diff2"
 	"github.com/StackExchange/dnscontrol/v4/pkg/nameservers"
 	"github.com/StackExchange/dnscontrol/v4/pkg/normalize"
 	"github.com/StackExchange/dnscontrol/v4/pkg/notifications"
@@ -92,6 +93,11 @@ func (args *PreviewArgs) flags() []cli.Flag {
 		Destination: &bindserial.ForcedValue,
 		Usage:       `Force BIND serial numbers to this value (for reproducibility)`,
 	})
+	flags = append(flags, &cli.BoolFlag{
+		Name:        "allow-protected",
+		Destination: &diff2.AllowProtected,
+		Usage:       `Permit changes to records that match a PROTECT() pattern`,
+	})
 	return flags
 }
 
@@ -131,12 +137,12 @@ func (args *PushArgs) flags() []cli.Flag {
 
 // Preview implements the preview subcommand.
 func Preview(args PreviewArgs) error {
-	return run(args, false, false, printer.DefaultPrinter, nil)
+	return run(args, false, false, printer.DefaultCLI, nil)
 }
 
 // Push implements the push subcommand.
 func Push(args PushArgs) error {
-	return run(args.PreviewArgs, true, args.Interactive, printer.DefaultPrinter, &args.Report)
+	return run(args.PreviewArgs, true, args.Interactive, printer.DefaultCLI, &args.Report)
 }
 
 var obsoleteDiff2FlagUsed = false
@@ -201,8 +207,26 @@ func run(args PreviewArgs, push bool, interactive bool, out printer.CLI, report
 			/// For each DSP...
 			for _, provider := range domain.DNSProviderInstances {
 				if !args.NoPopulate {
-					// preview run: check if zone is already there, if not print a warning
-					if lister, ok := provider.Driver.(providers.ZoneLister); ok && !push {
+					tag := domain.Metadata[models.DomainTag]
+					if creator, ok := provider.Driver.(providers.SplitHorizonZoneCreator); ok && tag != "" {
+						// split horizon: the zone of the tag may differ from the untagged one
+						if !push {
+							exists, err := creator.TaggedZoneExists(domain.Name, tag)
+							if err != nil {
+								out.Errorf("ERROR: %s\n", err.Error())
+								return
+							}
+							if !exists {
+								out.Warnf("Zone '%s' does not exist in the '%s' profile and will be added automatically.\n", uniquename, provider.Name)
+								continue
+							}
+						} else if err := creator.EnsureTaggedZoneExists(domain.Name, tag); err != nil {
+							out.Warnf("Error creating domain: %s\n", err)
+							anyErrors = true
+							continue
+						}
+					} else if lister, ok := provider.Driver.(providers.ZoneLister); ok && !push {
+						// preview run: check if zone is already there, if not print a warning
 						zones, err := lister.ListZones()
 						if err != nil {
 							out.Errorf("ERROR: %s\n", err.Error())
@@ -275,6 +299,11 @@ func run(args PreviewArgs, push bool, interactive bool, out printer.CLI, report
 			}
 
 			corrections, err := domain.RegistrarInstance.Driver.GetRegistrarCorrections(domain)
+			if err == nil {
+				var dsCorrections []*models.Correction
+				dsCorrections, err = generateDSCorrections(domain, providersWithExistingZone)
+				corrections = append(corrections, dsCorrections...)
+			}
 			out.EndProvider(domain.RegistrarName, len(corrections), err)
 			if err != nil {
 				anyErrors = true
@@ -291,6 +320,25 @@ func run(args PreviewArgs, push bool, interactive bool, out printer.CLI, report
 	}
 	wg.Wait() // wait for all anonymous functions to finish
 
+	// Correct the server configurations, which list the zones of a provider...
+	for _, sc := range generateServerConfigCorrections(cfg.Domains, args.shouldRunProvider) {
+		if len(sc.corrections) == 0 && sc.err == nil {
+			continue
+		}
+		out.StartDNSProvider(sc.provider, false)
+		out.EndProvider(sc.provider, len(sc.corrections), sc.err)
+		if sc.err != nil {
+			anyErrors = true
+			continue
+		}
+		totalCorrections += len(sc.corrections)
+		reportItems = append(reportItems, ReportItem{
+			Corrections: len(sc.corrections),
+			Provider:    sc.provider,
+		})
+		anyErrors = printOrRunCorrections("", sc.provider, sc.corrections, out, push, interactive, notifier) || anyErrors
+	}
+
 	if os.Getenv("TEAMCITY_VERSION") != "" {
 		fmt.Fprintf(os.Stderr, "##teamcity[buildStatus status='SUCCESS' text='%d corrections']", totalCorrections)
 	}
@@ -324,10 +372,17 @@ func run(args PreviewArgs, push bool, interactive bool, out printer.CLI, report
 func InitializeProviders(cfg *models.DNSConfig, providerConfigs map[string]map[string]string, notifyFlag bool) (notify notifications.Notifier, err error) {
 	var notificationCfg map[string]string
 	defer func() {
-		notify = notifications.Init(notificationCfg)
+		var nerr error
+		notify, nerr = notifications.Init(notificationCfg)
+		if err == nil {
+			err = nerr
+		}
 	}()
 	if notifyFlag {
 		notificationCfg = providerConfigs["notifications"]
+		if err = credsfile.ResolveSecrets("notifications", notificationCfg); err != nil {
+			return
+		}
 	}
 	isNonDefault := map[string]bool{}
 	for name, vals := range providerConfigs {
@@ -352,6 +407,9 @@ func InitializeProviders(cfg *models.DNSConfig, providerConfigs map[string]map[s
 	for _, d := range cfg.Domains {
 		if registrars[d.RegistrarName] == nil {
 			rCfg := cfg.RegistrarsByName[d.RegistrarName]
+			if err := credsfile.ResolveSecrets(d.RegistrarName, providerConfigs[d.RegistrarName]); err != nil {
+				return nil, err
+			}
 			r, err := providers.CreateRegistrar(rCfg.Type, providerConfigs[d.RegistrarName])
 			if err != nil {
 				return nil, err
@@ -363,6 +421,9 @@ func InitializeProviders(cfg *models.DNSConfig, providerConfigs map[string]map[s
 		for _, pInst := range d.DNSProviderInstances {
 			if dnsProviders[pInst.Name] == nil {
 				dCfg := cfg.DNSProvidersByName[pInst.Name]
+				if err := credsfile.ResolveSecrets(dCfg.Name, providerConfigs[dCfg.Name]); err != nil {
+					return nil, err
+				}
 				prov, err := providers.CreateDNSProvider(dCfg.Type, providerConfigs[dCfg.Name], dCfg.Metadata)
 				if err != nil {
 					return nil, err
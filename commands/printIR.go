@@ -119,13 +119,18 @@ func PrintValidationErrors(errs []error) (fatal bool) {
 	return
 }
 
-// ExecuteDSL executes the dnsconfig.js contents.
+// ExecuteDSL executes the dnsconfig.js contents. Files ending in .yaml
+// or .yml are read as YAML instead.
 func ExecuteDSL(args ExecuteDSLArgs) (*models.DNSConfig, error) {
 	if args.JSFile == "" {
 		return nil, fmt.Errorf("no config specified")
 	}
 
-	dnsConfig, err := js.ExecuteJavaScript(args.JSFile, args.DevMode, stringSliceToMap(args.Variable))
+	execute := js.ExecuteJavaScript
+	if js.IsYAML(args.JSFile) {
+		execute = js.ExecuteYAML
+	}
+	dnsConfig, err := execute(args.JSFile, args.DevMode, stringSliceToMap(args.Variable))
 	if err != nil {
 		return nil, fmt.Errorf("executing %s: %w", args.JSFile, err)
 	}
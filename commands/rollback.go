@@ -0,0 +1,79 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+	"github.com/StackExchange/dnscontrol/v4/pkg/notifications"
+	"github.com/StackExchange/dnscontrol/v4/pkg/printer"
+	"github.com/StackExchange/dnscontrol/v4/pkg/zonerecs"
+)
+
+// rollbackZone restores a zone at a provider to the records in
+// snapshot, which were gathered before the push began. It is used by
+// "ppush --rollback-on-error" after a correction has failed.
+//
+// The reverse diff is computed the usual way: the snapshot becomes the
+// desired state, and the provider generates the corrections needed to
+// get there from the (half-applied) live zone. What was restored is
+// reported to the notifier.
+func rollbackZone(zone *models.DomainConfig, provider *models.DNSProviderInstance, snapshot models.Records, out printer.CLI, notifier notifications.Notifier) {
+	out.Printf("ROLLBACK: Restoring %s at %s to its state before the push\n", zone.GetUniqueName(), provider.Name)
+	msgs, err := restoreSnapshot(zone, provider, snapshot, out, true)
+
+	var report string
+	switch {
+	case err != nil:
+		report = fmt.Sprintf("ROLLBACK FAILED after an error. The zone may be left half-applied. Restored:\n%s", strings.Join(msgs, "\n"))
+		out.Errorf("ROLLBACK of %s at %s failed: %s\n", zone.GetUniqueName(), provider.Name, err)
+	case len(msgs) == 0:
+		report = "ROLLBACK after an error. Nothing needed to be restored."
+	default:
+		report = fmt.Sprintf("ROLLBACK after an error. Restored:\n%s", strings.Join(msgs, "\n"))
+	}
+	notifier.Notify(zone.Name, provider.Name, report, err, false)
+}
+
+// restoreSnapshot runs the corrections that make the zone at the
+// provider match snapshot. It returns the messages of the corrections
+// that were run successfully. If push is false, the corrections are
+// only printed (and all of their messages are returned).
+func restoreSnapshot(zone *models.DomainConfig, provider *models.DNSProviderInstance, snapshot models.Records, out printer.CLI, push bool) ([]string, error) {
+	dc, err := zone.Copy()
+	if err != nil {
+		return nil, err
+	}
+	dc.Records = snapshot
+	// Records created by the failed push must be deleted even if the
+	// domain uses NO_PURGE or PROTECT(). The snapshot includes the
+	// records that IGNORE() matches, which are then desired, not
+	// conflicts.
+	dc.KeepUnknown = false
+	dc.Unmanaged = nil
+	dc.Protected = nil
+
+	_, corrections, err := zonerecs.CorrectZoneRecords(provider.Driver, dc)
+	if err != nil {
+		return nil, err
+	}
+
+	var msgs []string
+	var errs []error
+	for i, c := range corrections {
+		out.PrintCorrection(i, c)
+		if !push {
+			msgs = append(msgs, c.Msg)
+			continue
+		}
+		cerr := c.F()
+		out.EndCorrection(cerr)
+		if cerr != nil {
+			errs = append(errs, cerr)
+			continue
+		}
+		msgs = append(msgs, c.Msg)
+	}
+	return msgs, errors.Join(errs...)
+}
@@ -0,0 +1,138 @@
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+	"github.com/StackExchange/dnscontrol/v4/pkg/diff2"
+	"github.com/StackExchange/dnscontrol/v4/pkg/printer"
+)
+
+// memProvider is a DNS provider that stores a zone in memory.
+type memProvider struct {
+	records models.Records
+	failOn  string // Corrections for this label fail.
+}
+
+func (m *memProvider) GetNameservers(string) ([]*models.Nameserver, error) { return nil, nil }
+
+func (m *memProvider) GetZoneRecords(string, map[string]string) (models.Records, error) {
+	var recs models.Records
+	for _, r := range m.records {
+		c, _ := r.Copy()
+		recs = append(recs, c)
+	}
+	return recs, nil
+}
+
+func (m *memProvider) GetZoneRecordsCorrections(dc *models.DomainConfig, existing models.Records) ([]*models.Correction, error) {
+	changes, err := diff2.ByRecord(existing, dc, nil)
+	if err != nil {
+		return nil, err
+	}
+	var corrections []*models.Correction
+	for _, change := range changes {
+		if change.Type == diff2.REPORT {
+			continue
+		}
+		change := change
+		corrections = append(corrections, &models.Correction{
+			Msg: change.MsgsJoined,
+			F: func() error {
+				if change.Key.NameFQDN == m.failOn {
+					return fmt.Errorf("failed")
+				}
+				for _, o := range change.Old {
+					m.remove(o)
+				}
+				m.records = append(m.records, change.New...)
+				return nil
+			},
+		})
+	}
+	return corrections, nil
+}
+
+func (m *memProvider) remove(old *models.RecordConfig) {
+	for i, r := range m.records {
+		if r.GetLabelFQDN() == old.GetLabelFQDN() && r.Type == old.Type && r.GetTargetCombined() == old.GetTargetCombined() {
+			m.records = append(m.records[:i], m.records[i+1:]...)
+			return
+		}
+	}
+}
+
+func (m *memProvider) String() string {
+	var s []string
+	for _, r := range m.records {
+		s = append(s, fmt.Sprintf("%s %s %s", r.GetLabel(), r.Type, r.GetTargetCombined()))
+	}
+	sort.Strings(s)
+	return strings.Join(s, ", ")
+}
+
+func makeRollbackRec(label, target string) *models.RecordConfig {
+	r := &models.RecordConfig{Type: "A", TTL: 300}
+	r.SetLabel(label, "example.com")
+	r.SetTarget(target)
+	return r
+}
+
+func Test_restoreSnapshot(t *testing.T) {
+	mem := &memProvider{records: models.Records{
+		makeRollbackRec("www", "1.1.1.1"),
+		makeRollbackRec("old", "2.2.2.2"),
+		makeRollbackRec("ign", "6.6.6.6"),
+	}}
+	provider := &models.DNSProviderInstance{Driver: mem}
+	provider.Name = "mem"
+	zone := &models.DomainConfig{
+		Name:        "example.com",
+		KeepUnknown: true, // The rollback must delete records even with NO_PURGE.
+		// The snapshot has the ignored record, which must not be an
+		// IGNORE() conflict, and the rollback must delete the PROTECT()ed
+		// record that the push created.
+		Unmanaged: []*models.UnmanagedConfig{{LabelPattern: "ign"}},
+		Protected: []*models.UnmanagedConfig{{LabelPattern: "new"}},
+		Records: models.Records{
+			makeRollbackRec("www", "3.3.3.3"),
+			makeRollbackRec("new", "4.4.4.4"),
+			makeRollbackRec("zzz", "5.5.5.5"),
+		},
+	}
+	zone.UpdateSplitHorizonNames()
+	before := mem.String()
+	snapshot, _ := mem.GetZoneRecords("example.com", nil)
+
+	// Push, with one correction failing.
+	mem.failOn = "zzz.example.com"
+	corrections, err := mem.GetZoneRecordsCorrections(zone, snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed := 0
+	for _, c := range corrections {
+		if c.F() != nil {
+			failed++
+		}
+	}
+	if failed != 1 || mem.String() == before {
+		t.Fatalf("push didn't half-apply: failed=%d zone=%s", failed, mem)
+	}
+
+	mem.failOn = ""
+	out := &printer.ConsolePrinter{Writer: &strings.Builder{}}
+	msgs, err := restoreSnapshot(zone, provider, snapshot, out, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) == 0 {
+		t.Error("restoreSnapshot() returned no messages")
+	}
+	if got := mem.String(); got != before {
+		t.Errorf("after rollback zone = %s, want %s", got, before)
+	}
+}
@@ -0,0 +1,331 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+	"github.com/StackExchange/dnscontrol/v4/pkg/credsfile"
+	"github.com/StackExchange/dnscontrol/v4/pkg/normalize"
+	"github.com/StackExchange/dnscontrol/v4/pkg/prettyzone"
+	"github.com/StackExchange/dnscontrol/v4/pkg/printer"
+	"github.com/StackExchange/dnscontrol/v4/providers"
+	"github.com/StackExchange/dnscontrol/v4/providers/bind"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/exp/slices"
+)
+
+var _ = cmd(catMain, func() *cli.Command {
+	var args SnapshotArgs
+	return &cli.Command{
+		Name:  "snapshot",
+		Usage: "Save every zone at every provider (for backups)",
+		Action: func(ctx *cli.Context) error {
+			return exit(Snapshot(args))
+		},
+		Flags: args.flags(),
+	}
+}())
+
+var _ = cmd(catMain, func() *cli.Command {
+	var args RestoreArgs
+	return &cli.Command{
+		Name:      "restore",
+		Usage:     "Restore zones from a snapshot. Preview unless --push is given",
+		UsageText: "dnscontrol restore [command options] snapshotdir",
+		Action: func(ctx *cli.Context) error {
+			if ctx.NArg() != 1 {
+				return cli.Exit("Arguments should be: snapshotdir (Ex: snapshots/20240101T000000Z)", 1)
+			}
+			args.SnapshotDir = ctx.Args().Get(0)
+			return exit(Restore(args))
+		},
+		Flags: args.flags(),
+	}
+}())
+
+// snapshotManifestFile is the name of the manifest within a snapshot directory.
+const snapshotManifestFile = "manifest.json"
+
+// SnapshotManifest describes the contents of a snapshot directory.
+type SnapshotManifest struct {
+	Time  time.Time       `json:"time"`
+	Zones []*SnapshotZone `json:"zones"`
+}
+
+// SnapshotZone describes one zone (at one provider) in a snapshot.
+type SnapshotZone struct {
+	Domain       string            `json:"domain"` // The unique name (includes the tag, if any).
+	Name         string            `json:"name"`   // The zone name.
+	Provider     string            `json:"provider"`
+	ProviderType string            `json:"provider_type"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+	File         string            `json:"file,omitempty"`         // The zone file, for people. Relative to the snapshot directory.
+	RecordsFile  string            `json:"records_file,omitempty"` // The records as JSON, which restore reads. Relative to the snapshot directory.
+	Records      int               `json:"records"`
+	Error        string            `json:"error,omitempty"` // The zone could not be saved.
+}
+
+// SnapshotArgs contains all data/flags needed to run snapshot, independently of CLI.
+type SnapshotArgs struct {
+	GetDNSConfigArgs
+	GetCredentialsArgs
+	FilterArgs
+	Dir string
+}
+
+func (args *SnapshotArgs) flags() []cli.Flag {
+	flags := args.GetDNSConfigArgs.flags()
+	flags = append(flags, args.GetCredentialsArgs.flags()...)
+	flags = append(flags, args.FilterArgs.flags()...)
+	flags = append(flags, &cli.StringFlag{
+		Name:        "dir",
+		Destination: &args.Dir,
+		Value:       "snapshots",
+		Usage:       `Directory to create the timestamped snapshot in`,
+	})
+	return flags
+}
+
+// Snapshot implements the snapshot subcommand.
+func Snapshot(args SnapshotArgs) error {
+	out := printer.DefaultCLI
+
+	cfg, err := GetDNSConfig(args.GetDNSConfigArgs)
+	if err != nil {
+		return err
+	}
+	providerConfigs, err := credsfile.LoadProviderConfigs(args.CredsFile)
+	if err != nil {
+		return err
+	}
+	if _, err := PInitializeProviders(cfg, providerConfigs, false); err != nil {
+		return err
+	}
+	errs := normalize.ValidateAndNormalizeConfig(cfg)
+	if PrintValidationErrors(errs) {
+		return fmt.Errorf("exiting due to validation errors")
+	}
+
+	manifest := &SnapshotManifest{Time: time.Now().UTC()}
+	dir := filepath.Join(args.Dir, manifest.Time.Format("20060102T150405Z"))
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, zone := range whichZonesToProcess(cfg.Domains, args.Domains) {
+		out.StartDomain(zone.GetUniqueName())
+		for _, provider := range whichProvidersToProcess(zone.DNSProviderInstances, args.Providers) {
+			out.StartDNSProvider(provider.Name, false)
+			sz := &SnapshotZone{
+				Domain:       zone.GetUniqueName(),
+				Name:         zone.Name,
+				Provider:     provider.Name,
+				ProviderType: provider.ProviderType,
+				Metadata:     zone.Metadata,
+			}
+			if err := snapshotZone(dir, zone, provider, sz); err != nil {
+				sz.Error = err.Error()
+				out.Errorf("%s at %s: %s\n", sz.Domain, sz.Provider, err)
+				failed++
+			} else {
+				out.Printf("Saved %d records to %s\n", sz.Records, filepath.Join(dir, sz.File))
+			}
+			manifest.Zones = append(manifest.Zones, sz)
+		}
+	}
+
+	b, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, snapshotManifestFile), b, 0o640); err != nil {
+		return err
+	}
+	out.Printf("Snapshot written to %s\n", dir)
+
+	if failed != 0 {
+		return fmt.Errorf("%d zone(s) could not be saved", failed)
+	}
+	return nil
+}
+
+// snapshotZone saves the records of one zone at one provider. A zone
+// file can't hold provider-specific record types (they become comments)
+// or settings such as Route53 routing policies, so the records are
+// saved as JSON too, and restored from that.
+func snapshotZone(dir string, zone *models.DomainConfig, provider *models.DNSProviderInstance, sz *SnapshotZone) error {
+	recs, err := provider.Driver.GetZoneRecords(zone.Name, zone.Metadata)
+	if err != nil {
+		return err
+	}
+	sz.Records = len(recs)
+
+	if err := os.MkdirAll(filepath.Join(dir, provider.Name), 0o750); err != nil {
+		return err
+	}
+	sz.RecordsFile = filepath.Join(provider.Name, sz.Domain+".json")
+	b, err := json.MarshalIndent(recs, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, sz.RecordsFile), b, 0o640); err != nil {
+		return err
+	}
+
+	sz.File = filepath.Join(provider.Name, sz.Domain+".zone")
+	f, err := os.Create(filepath.Join(dir, sz.File))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	comments := []string{
+		fmt.Sprintf("snapshot of %s at %s (%s)", sz.Domain, sz.Provider, sz.ProviderType),
+	}
+	if err := prettyzone.WriteZoneFileRC(f, recs, zone.Name, models.DefaultTTL, comments); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// RestoreArgs contains all data/flags needed to run restore, independently of CLI.
+type RestoreArgs struct {
+	GetCredentialsArgs
+	SnapshotDir string
+	Domains     string
+	Providers   string
+	Push        bool
+}
+
+func (args *RestoreArgs) flags() []cli.Flag {
+	flags := args.GetCredentialsArgs.flags()
+	flags = append(flags, &cli.StringFlag{
+		Name:        "domains",
+		Destination: &args.Domains,
+		Usage:       `Comma separated list of domain names to restore (default: all in the snapshot)`,
+	})
+	flags = append(flags, &cli.StringFlag{
+		Name:        "providers",
+		Destination: &args.Providers,
+		Usage:       `Comma separated list of providers to restore to (default: all in the snapshot)`,
+	})
+	flags = append(flags, &cli.BoolFlag{
+		Name:        "push",
+		Destination: &args.Push,
+		Usage:       `Make the changes. Without this, the changes are only previewed`,
+	})
+	return flags
+}
+
+// Restore implements the restore subcommand.
+func Restore(args RestoreArgs) error {
+	out := printer.DefaultCLI
+
+	b, err := os.ReadFile(filepath.Join(args.SnapshotDir, snapshotManifestFile))
+	if err != nil {
+		return err
+	}
+	manifest := &SnapshotManifest{}
+	if err := json.Unmarshal(b, manifest); err != nil {
+		return fmt.Errorf("could not parse %s: %w", snapshotManifestFile, err)
+	}
+	providerConfigs, err := credsfile.LoadProviderConfigs(args.CredsFile)
+	if err != nil {
+		return err
+	}
+
+	var totalCorrections, failed int
+	instances := map[string]*models.DNSProviderInstance{}
+	for _, sz := range manifest.Zones {
+		if args.Domains != "" && !domainInList(sz.Domain, strings.Split(args.Domains, ",")) {
+			continue
+		}
+		if args.Providers != "" && !slices.Contains(strings.Split(args.Providers, ","), sz.Provider) {
+			continue
+		}
+		out.StartDomain(sz.Domain)
+		out.StartDNSProvider(sz.Provider, false)
+		if sz.Error != "" {
+			out.Warnf("Not in the snapshot: %s\n", sz.Error)
+			continue
+		}
+
+		provider, ok := instances[sz.Provider]
+		if !ok {
+			if err := credsfile.ResolveSecrets(sz.Provider, providerConfigs[sz.Provider]); err != nil {
+				out.Errorf("%s: %s\n", sz.Provider, err)
+				failed++
+				continue
+			}
+			driver, err := providers.CreateDNSProvider(sz.ProviderType, providerConfigs[sz.Provider], nil)
+			if err != nil {
+				out.Errorf("%s: %s\n", sz.Provider, err)
+				failed++
+				continue
+			}
+			provider = &models.DNSProviderInstance{Driver: driver}
+			provider.Name = sz.Provider
+			provider.ProviderType = sz.ProviderType
+			instances[sz.Provider] = provider
+		}
+
+		msgs, err := restoreSnapshotZone(args.SnapshotDir, sz, provider, out, args.Push)
+		totalCorrections += len(msgs)
+		if err != nil {
+			out.Errorf("%s at %s: %s\n", sz.Domain, sz.Provider, err)
+			failed++
+		}
+	}
+
+	out.Printf("Done. %d corrections.\n", totalCorrections)
+	if failed != 0 {
+		return fmt.Errorf("%d zone(s) could not be restored", failed)
+	}
+	return nil
+}
+
+// restoreSnapshotZone reads one zone from a snapshot and makes (or
+// previews) the corrections that restore it.
+func restoreSnapshotZone(dir string, sz *SnapshotZone, provider *models.DNSProviderInstance, out printer.CLI, push bool) ([]string, error) {
+	recs, err := readSnapshotRecords(dir, sz)
+	if err != nil {
+		return nil, err
+	}
+	zone := &models.DomainConfig{
+		Name:     sz.Name,
+		Metadata: sz.Metadata,
+	}
+	zone.UpdateSplitHorizonNames()
+	return restoreSnapshot(zone, provider, recs, out, push)
+}
+
+// readSnapshotRecords returns the records of one zone of a snapshot.
+// Snapshots that predate the JSON records only have the zone file.
+func readSnapshotRecords(dir string, sz *SnapshotZone) (models.Records, error) {
+	if sz.RecordsFile == "" {
+		content, err := os.ReadFile(filepath.Join(dir, sz.File))
+		if err != nil {
+			return nil, err
+		}
+		return bind.ParseZoneContents(string(content), sz.Name, sz.File)
+	}
+
+	b, err := os.ReadFile(filepath.Join(dir, sz.RecordsFile))
+	if err != nil {
+		return nil, err
+	}
+	var recs models.Records
+	if err := json.Unmarshal(b, &recs); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", sz.RecordsFile, err)
+	}
+	for _, rec := range recs {
+		// The FQDN isn't saved.
+		rec.SetLabel(rec.Name, sz.Name)
+	}
+	return recs, nil
+}
@@ -0,0 +1,105 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+	"github.com/StackExchange/dnscontrol/v4/pkg/printer"
+)
+
+func Test_snapshotRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	mem := &memProvider{records: models.Records{
+		makeRollbackRec("www", "1.1.1.1"),
+		makeRollbackRec("old", "2.2.2.2"),
+	}}
+	provider := &models.DNSProviderInstance{Driver: mem}
+	provider.Name = "mem"
+	provider.ProviderType = "MEM"
+	zone := &models.DomainConfig{Name: "example.com"}
+	zone.UpdateSplitHorizonNames()
+
+	sz := &SnapshotZone{Domain: "example.com", Name: "example.com", Provider: "mem", ProviderType: "MEM"}
+	if err := snapshotZone(dir, zone, provider, sz); err != nil {
+		t.Fatal(err)
+	}
+	if sz.Records != 2 || sz.File == "" {
+		t.Fatalf("snapshotZone() = %+v", sz)
+	}
+	before := mem.String()
+
+	// Someone changes the zone...
+	mem.records = models.Records{makeRollbackRec("www", "3.3.3.3"), makeRollbackRec("new", "4.4.4.4")}
+
+	out := &printer.ConsolePrinter{Writer: &strings.Builder{}}
+
+	// A preview doesn't change anything.
+	msgs, err := restoreSnapshotZone(dir, sz, provider, out, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) == 0 || mem.String() == before {
+		t.Fatalf("preview: msgs=%q zone=%s", msgs, mem)
+	}
+
+	if _, err := restoreSnapshotZone(dir, sz, provider, out, true); err != nil {
+		t.Fatal(err)
+	}
+	if got := mem.String(); got != before {
+		t.Errorf("after restore zone = %s, want %s", got, before)
+	}
+}
+
+func Test_snapshotProviderSpecific(t *testing.T) {
+	dir := t.TempDir()
+	alias := &models.RecordConfig{Type: "R53_ALIAS", TTL: 300, R53Alias: map[string]string{"type": "A", "zone_id": "Z2FDTNDATAQYW2", "evaluate_target_health": "false"}}
+	alias.SetLabel("@", "example.com")
+	alias.SetTarget("d111111abcdef8.cloudfront.net.")
+	weighted := func(target, id, weight string) *models.RecordConfig {
+		r := makeRollbackRec("www", target)
+		r.R53Routing = map[string]string{models.R53RoutingSetIdentifier: id, models.R53RoutingWeight: weight}
+		return r
+	}
+	mem := &memProvider{records: models.Records{
+		alias,
+		weighted("1.1.1.1", "blue", "90"),
+		weighted("2.2.2.2", "green", "10"),
+	}}
+	provider := &models.DNSProviderInstance{Driver: mem}
+	provider.Name = "mem"
+	provider.ProviderType = "MEM"
+	zone := &models.DomainConfig{Name: "example.com"}
+	zone.UpdateSplitHorizonNames()
+
+	sz := &SnapshotZone{Domain: "example.com", Name: "example.com", Provider: "mem", ProviderType: "MEM"}
+	if err := snapshotZone(dir, zone, provider, sz); err != nil {
+		t.Fatal(err)
+	}
+	if sz.Records != 3 || sz.RecordsFile == "" {
+		t.Fatalf("snapshotZone() = %+v", sz)
+	}
+
+	// The zone is lost...
+	mem.records = nil
+
+	out := &printer.ConsolePrinter{Writer: &strings.Builder{}}
+	if _, err := restoreSnapshotZone(dir, sz, provider, out, true); err != nil {
+		t.Fatal(err)
+	}
+	if len(mem.records) != 3 {
+		t.Fatalf("after restore zone = %s, want 3 records", mem)
+	}
+	got := map[string]*models.RecordConfig{}
+	for _, r := range mem.records {
+		got[r.Type+" "+r.GetTargetField()] = r
+	}
+	if r := got["R53_ALIAS d111111abcdef8.cloudfront.net."]; r == nil || r.R53Alias["zone_id"] != "Z2FDTNDATAQYW2" || r.GetLabelFQDN() != "example.com" {
+		t.Errorf("R53_ALIAS not restored: %s", mem)
+	}
+	for target, weight := range map[string]string{"1.1.1.1": "90", "2.2.2.2": "10"} {
+		if r := got["A "+target]; r == nil || r.R53Routing[models.R53RoutingWeight] != weight {
+			t.Errorf("weighted A %s not restored with weight %s: %+v", target, weight, r)
+		}
+	}
+}
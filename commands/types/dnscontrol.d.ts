@@ -111,7 +111,7 @@ interface ResponseHeaders {
 
 declare function require(name: `${string}.json`): any;
 declare function require(name: `${string}.json5`): any;
-declare function require(name: string): true;
+declare function require(name: string): any;
 
 /**
  * Issuer critical flag. CA that does not understand this tag will refuse to issue certificate for this domain.
@@ -1861,6 +1861,61 @@ declare function LOC_BUILDER_STR(opts: { label?: string; str: string; alt?: numb
  */
 declare function M365_BUILDER(opts: { label?: string; mx?: boolean; autodiscover?: boolean; dkim?: boolean; skypeForBusiness?: boolean; mdm?: boolean; domainGUID?: string; initialDomain?: string }): DomainModifier;
 
+/**
+ * `MAX_CHANGES` limits the "blast radius" of a push. If more than `max`
+ * records in the domain would be created, changed, or deleted, `ppush`
+ * refuses to make any changes to the domain (at that DNS provider) and
+ * exits with an error. `ppreview` prints a warning. Writing a name server
+ * configuration that lists the domain, such as the BIND provider's
+ * `server_config`, counts as a change.
+ *
+ * This protects against mistakes like a typo in a
+ * [`require_glob()`](../top-level-functions/require_glob.md) pattern, which
+ * can remove most of the records from `dnsconfig.js` at once.
+ *
+ * Use `ppush --force` to make the changes anyway. `ppush --max-changes N`
+ * overrides the value for all domains.
+ *
+ * To set a limit for all domains, use [`DEFAULTS`](../top-level-functions/DEFAULTS.md).
+ * See also [`MAX_DELETE_PERCENT`](MAX_DELETE_PERCENT.md).
+ *
+ * ```javascript
+ * DEFAULTS(MAX_CHANGES(50));
+ *
+ * D("example.com", REG_MY_PROVIDER, DnsProvider(DSP_MY_PROVIDER),
+ *   MAX_CHANGES(10),
+ *   A("@", "1.2.3.4"),
+ * END);
+ * ```
+ *
+ * @see https://docs.dnscontrol.org/language-reference/domain-modifiers/max_changes
+ */
+declare function MAX_CHANGES(max: number): DomainModifier;
+
+/**
+ * `MAX_DELETE_PERCENT` limits how much of a domain a push may delete. If more
+ * than `percent` percent of the records that currently exist in the domain
+ * would be deleted, `ppush` refuses to make any changes to the domain (at
+ * that DNS provider) and exits with an error. `ppreview` prints a warning.
+ *
+ * Use `ppush --force` to make the changes anyway. `ppush --max-delete-percent X`
+ * overrides the value for all domains.
+ *
+ * To set a limit for all domains, use [`DEFAULTS`](../top-level-functions/DEFAULTS.md).
+ * See also [`MAX_CHANGES`](MAX_CHANGES.md).
+ *
+ * ```javascript
+ * DEFAULTS(MAX_DELETE_PERCENT(25));
+ *
+ * D("example.com", REG_MY_PROVIDER, DnsProvider(DSP_MY_PROVIDER),
+ *   A("@", "1.2.3.4"),
+ * END);
+ * ```
+ *
+ * @see https://docs.dnscontrol.org/language-reference/domain-modifiers/max_delete_percent
+ */
+declare function MAX_DELETE_PERCENT(percent: number): DomainModifier;
+
 /**
  * MX adds an MX record to the domain.
  *
@@ -2354,6 +2409,60 @@ declare function NewRegistrar(name: string, type?: string, meta?: object): strin
  */
 declare function PANIC(message: string): never;
 
+/**
+ * `PROTECT()` guards records against accidental deletion or modification.
+ * If a change would delete or modify an existing record that matches the
+ * parameters, the domain is not changed and an error is reported instead.
+ *
+ * Unlike [`IGNORE()`](IGNORE.md), protected records are still managed by
+ * DNSControl. They are created if they are missing, and they must be
+ * listed in `dnsconfig.js` like any other record.
+ *
+ * Use case: A zone has MX records that, if removed by mistake, would stop
+ * mail delivery. `PROTECT("@", "MX")` means a typo or a missing `INCLUDE()`
+ * can't silently delete them.
+ *
+ * To intentionally change a protected record, run `preview`/`push` with
+ * `--allow-protected`.
+ *
+ * ## Syntax
+ *
+ * The parameters are the same as for `IGNORE()`:
+ *
+ * ```javascript
+ * D("example.com", REG_MY_PROVIDER, DnsProvider(DSP_MY_PROVIDER),
+ *   PROTECT(labelSpec, typeSpec, targetSpec),
+ *   PROTECT(labelSpec, typeSpec),
+ *   PROTECT(labelSpec),
+ * END);
+ * ```
+ *
+ * * `labelSpec` is a glob that matches the DNS label. `"*"` matches all labels.
+ * * `typeSpec` is a comma-separated list of DNS types. `"*"` matches any DNS type.
+ * * `targetSpec` is a glob that matches the DNS target. `"*"` matches all targets.
+ *
+ * `typeSpec` and `targetSpec` default to `"*"` if they are omitted.
+ *
+ * ## Examples
+ *
+ * ```javascript
+ * D("example.com", REG_MY_PROVIDER, DnsProvider(DSP_MY_PROVIDER),
+ *   PROTECT("@", "MX"),            // Protect the apex MX records.
+ *   PROTECT("*", "NS"),            // Protect all NS records.
+ *   PROTECT("www", "A", "10.*"),   // Protect www's A records that point to 10.*
+ *   MX("@", 10, "mx1.example.com."),
+ *   MX("@", 20, "mx2.example.com."),
+ *   A("www", "10.1.1.1"),
+ * END);
+ * ```
+ *
+ * Changing only the TTL of a protected record counts as a modification.
+ * Adding new records to a label or type that is protected is permitted.
+ *
+ * @see https://docs.dnscontrol.org/language-reference/domain-modifiers/protect
+ */
+declare function PROTECT(labelSpec: string, typeSpec?: string, targetSpec?: string): DomainModifier;
+
 /**
  * PTR adds a PTR record to the domain.
  *
@@ -2552,6 +2661,111 @@ declare function R53_ALIAS(name: string, target: string, zone_idModifier: Domain
  */
 declare function R53_EVALUATE_TARGET_HEALTH(enabled: boolean): RecordModifier;
 
+/**
+ * `R53_FAILOVER` puts a record in a Route53 record set with the failover routing policy. Route53 answers with the `PRIMARY` set while it is healthy and with the `SECONDARY` set otherwise.
+ *
+ * The set identifier names the set. All records with the same name, type and set identifier form one set. The health of the primary set is given by its [`R53_HEALTH_CHECK_ID`](R53_HEALTH_CHECK_ID.md) or, for an [`R53_ALIAS`](../domain-modifiers/R53_ALIAS.md), by [`R53_EVALUATE_TARGET_HEALTH`](R53_EVALUATE_TARGET_HEALTH.md).
+ *
+ * ```javascript
+ * D("example.com", REG_MY_PROVIDER, DnsProvider("ROUTE53"),
+ *   A("app", "1.2.3.4", R53_FAILOVER("primary", "PRIMARY"), R53_HEALTH_CHECK_ID("abcdef11-2222-3333-4444-555555fedcba")),
+ *   R53_ALIAS("app", "A", "standby.example.com.", R53_FAILOVER("secondary", "SECONDARY")),
+ *   A("standby", "5.6.7.8"),
+ * END);
+ * ```
+ *
+ * @see https://docs.dnscontrol.org/language-reference/record-modifiers/service-provider-specific/amazon-route-53/r53_failover
+ */
+declare function R53_FAILOVER(set_identifier: string, role: "PRIMARY" | "SECONDARY"): RecordModifier;
+
+/**
+ * `R53_GEO` puts a record in a Route53 record set with the geolocation routing policy. Route53 answers with the set whose location is the most specific match for the client.
+ *
+ * The set identifier names the set. All records with the same name, type and set identifier form one set. The location is one of:
+ *
+ * * `{continent: "EU"}`: a continent code (`AF`, `AN`, `AS`, `EU`, `NA`, `OC` or `SA`).
+ * * `{country: "DE"}`: a two-letter country code.
+ * * `{country: "US", subdivision: "CA"}`: a subdivision of a country, such as a US state.
+ * * `{country: "*"}`: the default, for clients that match no other set.
+ *
+ * ```javascript
+ * D("example.com", REG_MY_PROVIDER, DnsProvider("ROUTE53"),
+ *   A("www", "10.1.0.1", R53_GEO("europe", {continent: "EU"})),
+ *   A("www", "10.2.0.1", R53_GEO("california", {country: "US", subdivision: "CA"})),
+ *   A("www", "10.3.0.1", R53_GEO("default", {country: "*"})),
+ * END);
+ * ```
+ *
+ * @see https://docs.dnscontrol.org/language-reference/record-modifiers/service-provider-specific/amazon-route-53/r53_geo
+ */
+declare function R53_GEO(set_identifier: string, location: { continent?: string; country?: string; subdivision?: string }): RecordModifier;
+
+/**
+ * `R53_HEALTH_CHECK_ID` associates a Route53 health check with the record set of a record. Route53 does not answer with a set whose health check fails, unless all of them fail. It is usually combined with a routing policy such as [`R53_FAILOVER`](R53_FAILOVER.md), [`R53_WEIGHT`](R53_WEIGHT.md) or [`R53_MULTIVALUE`](R53_MULTIVALUE.md).
+ *
+ * DNSControl does not manage health checks. Create the health check in AWS and use its id.
+ *
+ * ```javascript
+ * D("example.com", REG_MY_PROVIDER, DnsProvider("ROUTE53"),
+ *   A("app", "1.2.3.4", R53_FAILOVER("primary", "PRIMARY"), R53_HEALTH_CHECK_ID("abcdef11-2222-3333-4444-555555fedcba")),
+ *   A("app", "5.6.7.8", R53_FAILOVER("secondary", "SECONDARY")),
+ * END);
+ * ```
+ *
+ * @see https://docs.dnscontrol.org/language-reference/record-modifiers/service-provider-specific/amazon-route-53/r53_health_check_id
+ */
+declare function R53_HEALTH_CHECK_ID(health_check_id: string): RecordModifier;
+
+/**
+ * `R53_LATENCY_REGION` puts a record in a Route53 record set with the latency routing policy. Route53 answers with the set whose AWS region has the lowest latency for the client.
+ *
+ * The set identifier names the set. All records with the same name, type and set identifier form one set. The region is an AWS region such as `us-east-1`.
+ *
+ * ```javascript
+ * D("example.com", REG_MY_PROVIDER, DnsProvider("ROUTE53"),
+ *   CNAME("api", "api-us.example.com.", R53_LATENCY_REGION("us", "us-east-1")),
+ *   CNAME("api", "api-eu.example.com.", R53_LATENCY_REGION("eu", "eu-west-1")),
+ * END);
+ * ```
+ *
+ * @see https://docs.dnscontrol.org/language-reference/record-modifiers/service-provider-specific/amazon-route-53/r53_latency_region
+ */
+declare function R53_LATENCY_REGION(set_identifier: string, region: string): RecordModifier;
+
+/**
+ * `R53_MULTIVALUE` puts a record in a Route53 record set with the multivalue answer routing policy. Route53 answers with up to eight healthy sets at the same name and type, chosen at random.
+ *
+ * The set identifier names the set. Usually each set has one record and its own [`R53_HEALTH_CHECK_ID`](R53_HEALTH_CHECK_ID.md). Route53 does not permit multivalue answers for [`R53_ALIAS`](../domain-modifiers/R53_ALIAS.md) records.
+ *
+ * ```javascript
+ * D("example.com", REG_MY_PROVIDER, DnsProvider("ROUTE53"),
+ *   A("www", "1.2.3.4", R53_MULTIVALUE("one"), R53_HEALTH_CHECK_ID("abcdef11-2222-3333-4444-555555fedcba")),
+ *   A("www", "1.2.3.5", R53_MULTIVALUE("two"), R53_HEALTH_CHECK_ID("abcdef22-2222-3333-4444-555555fedcba")),
+ * END);
+ * ```
+ *
+ * @see https://docs.dnscontrol.org/language-reference/record-modifiers/service-provider-specific/amazon-route-53/r53_multivalue
+ */
+declare function R53_MULTIVALUE(set_identifier: string): RecordModifier;
+
+/**
+ * `R53_WEIGHT` puts a record in a Route53 record set with the weighted routing policy. Route53 answers with one of the sets at the same name and type, chosen in proportion to their weights.
+ *
+ * The set identifier names the set. All records with the same name, type and set identifier form one set and must have the same weight. The weight is a number from 0 to 255. A set with weight 0 is only used if all sets have weight 0.
+ *
+ * ```javascript
+ * D("example.com", REG_MY_PROVIDER, DnsProvider("ROUTE53"),
+ *   A("www", "1.2.3.4", R53_WEIGHT("blue", 90)), // 90% of the queries
+ *   A("www", "1.2.3.5", R53_WEIGHT("green", 10)), // 10% of the queries
+ * END);
+ * ```
+ *
+ * See also [`R53_HEALTH_CHECK_ID`](R53_HEALTH_CHECK_ID.md) to stop answering with sets that are unhealthy.
+ *
+ * @see https://docs.dnscontrol.org/language-reference/record-modifiers/service-provider-specific/amazon-route-53/r53_weight
+ */
+declare function R53_WEIGHT(set_identifier: string, weight: number): RecordModifier;
+
 /**
  * `R53_ZONE` lets you specify the AWS Zone ID for an entire domain ([`D()`](../top-level-functions/D.md)) or a specific [`R53_ALIAS()`](../domain-modifiers/R53_ALIAS.md) record.
  *
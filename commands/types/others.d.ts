@@ -1,6 +1,6 @@
 declare function require(name: `${string}.json`): any;
 declare function require(name: `${string}.json5`): any;
-declare function require(name: string): true;
+declare function require(name: string): any;
 
 /**
  * Issuer critical flag. CA that does not understand this tag will refuse to issue certificate for this domain.
@@ -59,6 +59,9 @@ func getProvider(t *testing.T) (providers.DNSServiceProvider, string, map[string
 		if *providerToRun != name {
 			continue
 		}
+		if err := credsfile.ResolveSecrets(name, cfg); err != nil {
+			t.Fatalf("Error resolving secrets: %s", err)
+		}
 
 		var metadata json.RawMessage
 		// CLOUDFLAREAPI tests related to CLOUDFLAREAPI_SINGLE_REDIRECT/CF_REDIRECT/CF_TEMP_REDIRECT
@@ -634,6 +637,16 @@ func r53alias(name, aliasType, target, evalTargetHealth string) *models.RecordCo
 	return r
 }
 
+// r53routing gives r the Route53 routing policy kv, a list of keys of
+// RecordConfig.R53Routing and their values.
+func r53routing(r *models.RecordConfig, kv ...string) *models.RecordConfig {
+	r.R53Routing = map[string]string{}
+	for i := 0; i+1 < len(kv); i += 2 {
+		r.R53Routing[kv[i]] = kv[i+1]
+	}
+	return r
+}
+
 func soa(name string, ns, mbox string, serial, refresh, retry, expire, minttl uint32) *models.RecordConfig {
 	r := makeRec(name, "", "SOA")
 	r.SetTargetSOA(ns, mbox, serial, refresh, retry, expire, minttl)
@@ -1856,6 +1869,37 @@ func makeTests() []*TestGroup {
 			),
 		),
 
+		testgroup("R53_ROUTING",
+			only("ROUTE53"),
+			tc("create weighted sets",
+				r53routing(a("www", "1.2.3.4"), models.R53RoutingSetIdentifier, "blue", models.R53RoutingWeight, "90"),
+				r53routing(a("www", "1.2.3.5"), models.R53RoutingSetIdentifier, "green", models.R53RoutingWeight, "10"),
+			),
+			tc("change a weight",
+				r53routing(a("www", "1.2.3.4"), models.R53RoutingSetIdentifier, "blue", models.R53RoutingWeight, "50"),
+				r53routing(a("www", "1.2.3.5"), models.R53RoutingSetIdentifier, "green", models.R53RoutingWeight, "10"),
+			),
+			tc("change a target",
+				r53routing(a("www", "1.2.3.4"), models.R53RoutingSetIdentifier, "blue", models.R53RoutingWeight, "50"),
+				r53routing(a("www", "1.2.3.6"), models.R53RoutingSetIdentifier, "green", models.R53RoutingWeight, "10"),
+			),
+			tc("replace with failover",
+				r53routing(a("www", "1.2.3.4"), models.R53RoutingSetIdentifier, "primary", models.R53RoutingFailover, "PRIMARY"),
+				r53routing(r53alias("www", "A", "backup.**current-domain**", "false"), models.R53RoutingSetIdentifier, "secondary", models.R53RoutingFailover, "SECONDARY"),
+				a("backup", "1.2.3.5"),
+			),
+			tc("geolocation and latency",
+				r53routing(a("geo", "1.2.3.4"), models.R53RoutingSetIdentifier, "eu", models.R53RoutingGeoContinent, "EU"),
+				r53routing(a("geo", "1.2.3.5"), models.R53RoutingSetIdentifier, "default", models.R53RoutingGeoCountry, "*"),
+				r53routing(cname("lat", "us.example.com."), models.R53RoutingSetIdentifier, "us", models.R53RoutingRegion, "us-east-1"),
+				r53routing(cname("lat", "eu.example.com."), models.R53RoutingSetIdentifier, "eu", models.R53RoutingRegion, "eu-west-1"),
+			),
+			tc("multivalue",
+				r53routing(a("mv", "1.2.3.4"), models.R53RoutingSetIdentifier, "one", models.R53RoutingMultiValue, "true"),
+				r53routing(a("mv", "1.2.3.5"), models.R53RoutingSetIdentifier, "two", models.R53RoutingMultiValue, "true"),
+			),
+		),
+
 		// CLOUDFLAREAPI features
 
 		// CLOUDFLAREAPI: Redirects:
@@ -9,4 +9,6 @@ type RawRecordConfig struct {
 	Args  []any            `json:"args,omitempty"`
 	Metas []map[string]any `json:"metas,omitempty"`
 	TTL   uint32           `json:"ttl,omitempty"`
+
+	Source *SourceLocation `json:"source,omitempty"`
 }
@@ -98,6 +98,9 @@ type RecordConfig struct {
 	Metadata  map[string]string `json:"meta,omitempty"`
 	Original  interface{}       `json:"-"` // Store pointer to provider-specific record object. Used in diffing.
 
+	// Source is where dnsconfig.js created the record, if known.
+	Source *SourceLocation `json:"source,omitempty"`
+
 	// If you add a field to this struct, also add it to the list in the UnmarshalJSON function.
 	MxPreference     uint16            `json:"mxpreference,omitempty"`
 	SrvPriority      uint16            `json:"srvpriority,omitempty"`
@@ -139,6 +142,7 @@ type RecordConfig struct {
 	TlsaSelector     uint8             `json:"tlsaselector,omitempty"`
 	TlsaMatchingType uint8             `json:"tlsamatchingtype,omitempty"`
 	R53Alias         map[string]string `json:"r53_alias,omitempty"`
+	R53Routing       map[string]string `json:"r53_routing,omitempty"` // Route53 routing policy. See R53Routing* for the keys.
 	AzureAlias       map[string]string `json:"azure_alias,omitempty"`
 	UnknownTypeName  string            `json:"unknown_type_name,omitempty"`
 
@@ -147,6 +151,21 @@ type RecordConfig struct {
 	CloudflareRedirect *CloudflareSingleRedirectConfig `json:"cloudflareapi_redirect,omitempty"`
 }
 
+// Keys of RecordConfig.R53Routing. A record with a routing policy has a
+// set identifier and the keys of one policy. Any record may have a
+// health check.
+const (
+	R53RoutingSetIdentifier  = "set_identifier"
+	R53RoutingWeight         = "weight"          // Weighted: 0-255.
+	R53RoutingRegion         = "region"          // Latency: an AWS region.
+	R53RoutingFailover       = "failover"        // Failover: PRIMARY or SECONDARY.
+	R53RoutingGeoContinent   = "geo_continent"   // Geolocation: a continent code.
+	R53RoutingGeoCountry     = "geo_country"     // Geolocation: a country code, or "*" for the default.
+	R53RoutingGeoSubdivision = "geo_subdivision" // Geolocation: a subdivision of geo_country.
+	R53RoutingMultiValue     = "multivalue"      // Multivalue answer: "true".
+	R53RoutingHealthCheckID  = "health_check_id"
+)
+
 // CloudflareSingleRedirectConfig contains info about a Cloudflare Single Redirect.
 //
 //	When these are used, .target is set to a human-readable version (only to be used for display purposes).
@@ -168,6 +187,26 @@ type CloudflareSingleRedirectConfig struct {
 	SRDisplay        string `json:"sr_display,omitempty"` // How is this displayed to the user (SetTarget) for CF_SINGLE_REDIRECT
 }
 
+// SourceLocation is a line of dnsconfig.js, or of a file it requires.
+type SourceLocation struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+}
+
+// String returns the location as "file:line".
+func (sl *SourceLocation) String() string {
+	return fmt.Sprintf("%s:%d", sl.File, sl.Line)
+}
+
+// SourceError returns err prefixed with the location in dnsconfig.js
+// where rc was created. If that isn't known, err is returned unchanged.
+func (rc *RecordConfig) SourceError(err error) error {
+	if rc.Source == nil || err == nil {
+		return err
+	}
+	return fmt.Errorf("%s: %w", rc.Source, err)
+}
+
 // MarshalJSON marshals RecordConfig.
 func (rc *RecordConfig) MarshalJSON() ([]byte, error) {
 	recj := &struct {
@@ -198,6 +237,7 @@ func (rc *RecordConfig) UnmarshalJSON(b []byte) error {
 		Metadata  map[string]string `json:"meta,omitempty"`
 		Original  interface{}       `json:"-"` // Store pointer to provider-specific record object. Used in diffing.
 		Args      []any             `json:"args,omitempty"`
+		Source    *SourceLocation   `json:"source,omitempty"`
 
 		MxPreference     uint16            `json:"mxpreference,omitempty"`
 		SrvPriority      uint16            `json:"srvpriority,omitempty"`
@@ -239,9 +279,12 @@ func (rc *RecordConfig) UnmarshalJSON(b []byte) error {
 		TlsaSelector     uint8             `json:"tlsaselector,omitempty"`
 		TlsaMatchingType uint8             `json:"tlsamatchingtype,omitempty"`
 		R53Alias         map[string]string `json:"r53_alias,omitempty"`
+		R53Routing       map[string]string `json:"r53_routing,omitempty"`
 		AzureAlias       map[string]string `json:"azure_alias,omitempty"`
 		UnknownTypeName  string            `json:"unknown_type_name,omitempty"`
 
+		CloudflareRedirect *CloudflareSingleRedirectConfig `json:"cloudflareapi_redirect,omitempty"`
+
 		EnsureAbsent bool `json:"ensure_absent,omitempty"` // Override NO_PURGE and delete this record
 
 		// NB(tlim): If anyone can figure out how to do this without listing all
@@ -523,6 +566,11 @@ func (rc *RecordConfig) Key() RecordKey {
 			t = fmt.Sprintf("%s_%s", t, v)
 		}
 	}
+	if id := rc.R53Routing[R53RoutingSetIdentifier]; id != "" {
+		// Route53 record sets with a routing policy are identified by
+		// their set identifier, so each is a separate record set.
+		t = fmt.Sprintf("%s/%s", t, id)
+	}
 	return RecordKey{rc.NameFQDN, t}
 }
 
@@ -3,6 +3,12 @@
 // their environment variable equivalents. To reference an environment variable in your json file, simply use values in this format:
 //
 //	"key"="$ENV_VAR_NAME"
+//
+// Values may also reference a secret backend:
+//
+//	"key"="file:/path/to/file"
+//	"key"="vault:secret/path#key"
+//	"key"="exec:command arg1 arg2"
 package credsfile
 
 import (
@@ -19,7 +25,8 @@ import (
 	"github.com/google/shlex"
 )
 
-// LoadProviderConfigs will open or execute the specified file name, and parse its contents. It will replace environment variables it finds if any value matches $[A-Za-z_-0-9]+
+// LoadProviderConfigs will open or execute the specified file name, and parse its contents. It will replace environment variables it finds if any value matches $[A-Za-z_-0-9]+.
+// Values that reference a secret backend (file:, vault:, exec:) are resolved by ResolveSecrets.
 func LoadProviderConfigs(fname string) (map[string]map[string]string, error) {
 	var results = map[string]map[string]string{}
 
@@ -47,6 +54,7 @@ func LoadProviderConfigs(fname string) (map[string]map[string]string, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed parsing provider credentials file %v: %v", fname, err)
 	}
+	findSecretRefs(results)
 	if err = replaceEnvVars(results); err != nil {
 		return nil, err
 	}
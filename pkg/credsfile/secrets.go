@@ -0,0 +1,170 @@
+package credsfile
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/google/shlex"
+	"github.com/hashicorp/vault/api"
+)
+
+// secretResolvers maps a value prefix to the function that resolves
+// the remainder of the value. For example, "file:/run/secrets/token"
+// is resolved by calling secretResolvers["file:"]("/run/secrets/token").
+var secretResolvers = map[string]func(ref string) (string, error){
+	"file:":  resolveFile,
+	"vault:": resolveVault,
+	"exec:":  resolveExec,
+}
+
+// secretRefs are the values of the creds file that was loaded last
+// that reference a secret backend, by entry name and key. They are
+// resolved by ResolveSecrets, only for the entries that are used.
+var secretRefs struct {
+	sync.Mutex
+	m map[string]map[string]string
+}
+
+// findSecretRefs records the values of m that reference a secret
+// backend. It must be called before replaceEnvVars: the value of an
+// environment variable is never a reference.
+func findSecretRefs(m map[string]map[string]string) {
+	refs := map[string]map[string]string{}
+	for name, keys := range m {
+		for k, v := range keys {
+			if secretPrefix(v) == "" {
+				continue
+			}
+			if refs[name] == nil {
+				refs[name] = map[string]string{}
+			}
+			refs[name][k] = v
+		}
+	}
+	secretRefs.Lock()
+	secretRefs.m = refs
+	secretRefs.Unlock()
+}
+
+// secretPrefix returns the prefix of v that selects a secret backend,
+// or "".
+func secretPrefix(v string) string {
+	for prefix := range secretResolvers {
+		if strings.HasPrefix(v, prefix) {
+			return prefix
+		}
+	}
+	return ""
+}
+
+// ResolveSecrets replaces the values of cfg, the creds.json entry name,
+// that reference a secret backend with the secret itself. Each value is
+// resolved individually; the secrets are never assembled into a file.
+// Call it before the entry is used; entries that aren't used are never
+// resolved. Calling it again for the same entry does nothing.
+func ResolveSecrets(name string, cfg map[string]string) error {
+	secretRefs.Lock()
+	defer secretRefs.Unlock()
+	for k, v := range secretRefs.m[name] {
+		if cfg[k] != v {
+			continue // Resolved already, or not the entry that was loaded.
+		}
+		prefix := secretPrefix(v)
+		secret, err := secretResolvers[prefix](strings.TrimPrefix(v, prefix))
+		if err != nil {
+			// Don't include the value. It may be sensitive.
+			return fmt.Errorf("creds.json entry %q key %q: %s: %w", name, k, prefix[:len(prefix)-1], err)
+		}
+		cfg[k] = secret
+		delete(secretRefs.m[name], k)
+	}
+	return nil
+}
+
+// resolveFile returns the contents of a file, less any trailing newlines.
+func resolveFile(path string) (string, error) {
+	dat, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(dat), "\r\n"), nil
+}
+
+// resolveExec runs a command and returns its output, less any
+// trailing newlines. The command is not run by a shell.
+func resolveExec(command string) (string, error) {
+	cmd, err := shlex.Split(command)
+	if err != nil {
+		return "", err
+	}
+	if len(cmd) == 0 {
+		return "", fmt.Errorf("no command")
+	}
+	c := exec.Command(cmd[0], cmd[1:]...)
+	c.Stderr = os.Stderr
+	out, err := c.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
+
+var vaultClient struct {
+	sync.Mutex
+	logical *api.Logical
+	cache   map[string]map[string]interface{} // Secrets already read, by path.
+}
+
+// resolveVault reads a key from a HashiCorp Vault secret. The reference
+// is "path#key". The Vault server and token are taken from the usual
+// environment variables (VAULT_ADDR, VAULT_TOKEN, etc.).
+//
+// Both KV version 1 and version 2 secrets are supported. For version 2
+// the path must include "data/", for example "secret/data/dnscontrol#apikey".
+func resolveVault(ref string) (string, error) {
+	path, key, ok := strings.Cut(ref, "#")
+	if !ok || path == "" || key == "" {
+		return "", fmt.Errorf("reference must be in the format path#key")
+	}
+
+	vaultClient.Lock()
+	defer vaultClient.Unlock()
+
+	data, ok := vaultClient.cache[path]
+	if !ok {
+		if vaultClient.logical == nil {
+			client, err := api.NewClient(api.DefaultConfig())
+			if err != nil {
+				return "", err
+			}
+			vaultClient.logical = client.Logical()
+			vaultClient.cache = map[string]map[string]interface{}{}
+		}
+		secret, err := vaultClient.logical.Read(path)
+		if err != nil {
+			return "", err
+		}
+		if secret == nil {
+			return "", fmt.Errorf("no secret at %q", path)
+		}
+		data = secret.Data
+		// KV version 2 nests the key/value pairs in "data".
+		if inner, ok := data["data"].(map[string]interface{}); ok {
+			data = inner
+		}
+		vaultClient.cache[path] = data
+	}
+
+	v, ok := data[key]
+	if !ok {
+		return "", fmt.Errorf("key %q not found at %q", key, path)
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("key %q at %q is not a string", key, path)
+	}
+	return s, nil
+}
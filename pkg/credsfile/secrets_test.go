@@ -0,0 +1,82 @@
+package credsfile
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func Test_ResolveSecrets(t *testing.T) {
+	dir := t.TempDir()
+	fname := filepath.Join(dir, "token")
+	if err := os.WriteFile(fname, []byte("s3cr3t\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CREDS_TEST_ENV", "exec:false")
+
+	m := map[string]map[string]string{
+		"prov": {
+			"TYPE":  "CLOUDFLAREAPI",
+			"file":  "file:" + fname,
+			"plain": "not a reference",
+			"env":   "$CREDS_TEST_ENV",
+		},
+		"unused": {"apikey": "file:/does/not/exist"},
+	}
+	if runtime.GOOS != "windows" {
+		m["prov"]["exec"] = "exec:echo 'hello world'"
+	}
+	findSecretRefs(m)
+	if err := replaceEnvVars(m); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the entry that is used is resolved.
+	if err := ResolveSecrets("prov", m["prov"]); err != nil {
+		t.Fatal(err)
+	}
+	if got := m["prov"]["file"]; got != "s3cr3t" {
+		t.Errorf("file: got %q, want %q", got, "s3cr3t")
+	}
+	if got, ok := m["prov"]["exec"]; ok && got != "hello world" {
+		t.Errorf("exec: got %q, want %q", got, "hello world")
+	}
+	if got := m["prov"]["plain"]; got != "not a reference" {
+		t.Errorf("plain: got %q", got)
+	}
+	// The value of an environment variable is not a reference.
+	if got := m["prov"]["env"]; got != "exec:false" {
+		t.Errorf("env: got %q, want %q", got, "exec:false")
+	}
+
+	// Resolving again changes nothing, even if the file is gone.
+	if err := os.Remove(fname); err != nil {
+		t.Fatal(err)
+	}
+	if err := ResolveSecrets("prov", m["prov"]); err != nil {
+		t.Errorf("second ResolveSecrets(): %v", err)
+	}
+	if got := m["prov"]["file"]; got != "s3cr3t" {
+		t.Errorf("file after the second ResolveSecrets(): got %q", got)
+	}
+}
+
+func Test_ResolveSecretsErrors(t *testing.T) {
+	m := map[string]map[string]string{
+		"prov": {"apikey": "file:/does/not/exist"},
+	}
+	findSecretRefs(m)
+	err := ResolveSecrets("prov", m["prov"])
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if !strings.Contains(err.Error(), `"prov"`) || !strings.Contains(err.Error(), `"apikey"`) {
+		t.Errorf("error doesn't say which entry failed: %v", err)
+	}
+
+	if _, err := resolveVault("secret/no-key"); err == nil {
+		t.Error("resolveVault() accepted a reference without #key")
+	}
+}
@@ -177,7 +177,7 @@ func findTTLChanges(existing, desired []targetConfig) ([]targetConfig, []targetC
 		}
 
 		if ecomp == dcomp && er.TTL != dr.TTL {
-			m := color.YellowString("± MODIFY-TTL %s %s %s", dr.NameFQDN, dr.Type, humanDiff(existing[ei], desired[di]))
+			m := color.YellowString("± MODIFY-TTL %s %s %s%s", dr.NameFQDN, dr.Type, humanDiff(existing[ei], desired[di]), sourceNote(dr))
 			v := mkChange(dr.NameFQDN, dr.Type, []string{m},
 				models.Records{er},
 				models.Records{dr},
@@ -242,6 +242,15 @@ func humanDiff(a, b targetConfig) string {
 	return fmt.Sprintf("%s ttl=(%d->%d)", a.comparableNoTTL, a.rec.TTL, b.rec.TTL)
 }
 
+// sourceNote returns " (from file:line)" if it is known where rec was
+// created in dnsconfig.js, or "".
+func sourceNote(rec *models.RecordConfig) string {
+	if rec.Source == nil {
+		return ""
+	}
+	return fmt.Sprintf(" (from %s)", rec.Source)
+}
+
 func diffTargets(existing, desired []targetConfig) ChangeList {
 
 	//fmt.Printf("DEBUG: diffTargets(\nexisting=%v\ndesired=%v\nDEBUG.\n", existing, desired)
@@ -273,7 +282,7 @@ func diffTargets(existing, desired []targetConfig) ChangeList {
 		er := existing[i].rec
 		dr := desired[i].rec
 
-		m := color.YellowString("± MODIFY %s %s %s", dr.NameFQDN, dr.Type, humanDiff(existing[i], desired[i]))
+		m := color.YellowString("± MODIFY %s %s %s%s", dr.NameFQDN, dr.Type, humanDiff(existing[i], desired[i]), sourceNote(dr))
 
 		mkc := mkChange(dr.NameFQDN, dr.Type, []string{m}, models.Records{er}, models.Records{dr})
 		if len(existing) == 1 && len(desired) == 1 {
@@ -296,7 +305,7 @@ func diffTargets(existing, desired []targetConfig) ChangeList {
 	// any left-over desired are creates
 	for i := mi; i < len(desired); i++ {
 		dr := desired[i].rec
-		m := color.GreenString("+ CREATE %s %s %s", dr.NameFQDN, dr.Type, desired[i].comparableFull)
+		m := color.GreenString("+ CREATE %s %s %s%s", dr.NameFQDN, dr.Type, desired[i].comparableFull, sourceNote(dr))
 		instructions = append(instructions, mkAdd(dr.NameFQDN, dr.Type, []string{m}, models.Records{dr}))
 	}
 
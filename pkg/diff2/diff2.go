@@ -241,6 +241,11 @@ func byHelper(fn func(cc *CompareConfig) ChangeList, existing models.Records, dc
 	// Analyze and generate the instructions:
 	instructions := fn(cc)
 
+	// Refuse to touch PROTECT()ed records:
+	if err := checkProtected(dc.Protected, instructions); err != nil {
+		return nil, err
+	}
+
 	// If we have msgs, create a change to output them:
 	if len(msgs) != 0 {
 		chg := Change{
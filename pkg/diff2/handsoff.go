@@ -149,6 +149,17 @@ func handsoff(
 	return desired, msgs, nil
 }
 
+// Unmanaged returns the existing records that will not be deleted
+// even though they are not desired: ignored are the records that match
+// an IGNORE*() pattern; unpurged are the records kept by NO_PURGE.
+func Unmanaged(existing models.Records, dc *models.DomainConfig) (ignored, unpurged models.Records, err error) {
+	if err := compileUnmanagedConfigs(dc.Unmanaged); err != nil {
+		return nil, nil, err
+	}
+	ignored, unpurged = processIgnoreAndNoPurge(dc.Name, existing, dc.Records, dc.EnsureAbsent, dc.Unmanaged, dc.KeepUnknown)
+	return ignored, unpurged, nil
+}
+
 // reportSkips reports records being skipped, if !full only the first
 // printer.MaxReport are output.
 func reportSkips(recs models.Records, full bool) []string {
@@ -0,0 +1,61 @@
+package diff2
+
+// This file implements PROTECT(), which makes it an error to delete or
+// modify certain records. Unlike IGNORE*(), protected records are
+// still managed: they are created if missing, and they may be changed
+// if --allow-protected is given.
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+)
+
+// AllowProtected permits changes to records that match a PROTECT()
+// pattern. It is set by the --allow-protected flag.
+var AllowProtected bool
+
+// checkProtected returns an error if the instructions would delete or
+// modify a record that matches a PROTECT() pattern.
+func checkProtected(protected []*models.UnmanagedConfig, instructions ChangeList) error {
+	if len(protected) == 0 || AllowProtected {
+		return nil
+	}
+	if err := compileUnmanagedConfigs(protected); err != nil {
+		return err
+	}
+
+	var touched models.Records
+	for _, inst := range instructions {
+		if inst.Type != CHANGE && inst.Type != DELETE {
+			continue
+		}
+		for _, old := range inst.Old {
+			if matchAny(protected, old) && !survives(old, inst.New) {
+				touched = append(touched, old)
+			}
+		}
+	}
+	if len(touched) == 0 {
+		return nil
+	}
+
+	msgs := []string{fmt.Sprintf("%d PROTECT()ed records would be deleted or modified:", len(touched))}
+	for _, r := range touched {
+		msgs = append(msgs, fmt.Sprintf("    %s %s %s ttl=%d", r.GetLabelFQDN(), r.Type, r.GetTargetCombined(), r.TTL))
+	}
+	return fmt.Errorf("%s\nERROR: Use --allow-protected to permit this", strings.Join(msgs, "\n"))
+}
+
+// survives reports whether old is in recs unchanged. Changes that
+// update a whole RecordSet or label list records that aren't changing
+// in both Old and New.
+func survives(old *models.RecordConfig, recs models.Records) bool {
+	for _, r := range recs {
+		if r.Type == old.Type && r.TTL == old.TTL && r.ToComparableNoTTL() == old.ToComparableNoTTL() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package diff2
+
+import (
+	"testing"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+)
+
+func Test_checkProtected(t *testing.T) {
+	mx := makeRec("@", "MX", "10 mx1.f.com.")
+	mx2 := makeRec("@", "MX", "10 mx2.f.com.")
+	www := makeRec("www", "A", "1.2.3.4")
+	www2 := makeRec("www", "A", "5.6.7.8")
+	wwwTTL := makeRecTTL("www", "A", "1.2.3.4", 600)
+
+	protectMX := []*models.UnmanagedConfig{{LabelPattern: "@", RTypePattern: "MX"}}
+	protectWWW := []*models.UnmanagedConfig{{LabelPattern: "www", TargetPattern: "1.2.3.*"}}
+
+	tests := []struct {
+		name      string
+		protected []*models.UnmanagedConfig
+		allow     bool
+		inst      ChangeList
+		wantErr   bool
+	}{
+		{
+			name:      "none",
+			protected: nil,
+			inst:      ChangeList{{Type: DELETE, Old: models.Records{mx}}},
+		},
+		{
+			name:      "create",
+			protected: protectMX,
+			inst:      ChangeList{{Type: CREATE, New: models.Records{mx}}},
+		},
+		{
+			name:      "delete",
+			protected: protectMX,
+			inst:      ChangeList{{Type: DELETE, Old: models.Records{mx}}},
+			wantErr:   true,
+		},
+		{
+			name:      "change",
+			protected: protectMX,
+			inst:      ChangeList{{Type: CHANGE, Old: models.Records{mx}, New: models.Records{mx2}}},
+			wantErr:   true,
+		},
+		{
+			name:      "allowed",
+			protected: protectMX,
+			allow:     true,
+			inst:      ChangeList{{Type: DELETE, Old: models.Records{mx}}},
+		},
+		{
+			name:      "otherType",
+			protected: protectMX,
+			inst:      ChangeList{{Type: DELETE, Old: models.Records{www}}},
+		},
+		{
+			name:      "unchangedInSet",
+			protected: protectWWW,
+			inst:      ChangeList{{Type: CHANGE, Old: models.Records{www}, New: models.Records{www, www2}}},
+		},
+		{
+			name:      "ttl",
+			protected: protectWWW,
+			inst:      ChangeList{{Type: CHANGE, Old: models.Records{www}, New: models.Records{wwwTTL}}},
+			wantErr:   true,
+		},
+		{
+			name:      "targetMismatch",
+			protected: protectWWW,
+			inst:      ChangeList{{Type: DELETE, Old: models.Records{www2}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			AllowProtected = tt.allow
+			defer func() { AllowProtected = false }()
+			err := checkProtected(tt.protected, tt.inst)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkProtected() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,532 @@
+package js
+
+// This file implements the semantic formatter used by "dnscontrol fmt
+// --semantic". Unlike a pretty-printer it edits the source text, so
+// comments and anything else it doesn't change are left alone:
+//
+//   - Runs of records in D() and D_EXTEND() are sorted by label and type.
+//   - Single-quoted strings become double-quoted.
+//   - Durations in TTL() and DefaultTTL() become seconds: TTL("1h") becomes TTL(3600).
+//   - Duplicate modifiers are removed.
+//
+// Only records are reordered, and only past other records, so the
+// result of running the file is the same except for the order of the
+// records. Records whose order matters (see orderedRecords) stay where
+// they are.
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/StackExchange/dnscontrol/v4/pkg/prettyzone"
+	"github.com/dop251/goja"
+	"github.com/dop251/goja/ast"
+)
+
+// helperNames lists the functions and constants defined in helpers.js.
+// records is the subset that creates records.
+var helperNames = sync.OnceValues(func() (all, records map[string]bool) {
+	all = map[string]bool{}
+	records = map[string]bool{}
+	prg, err := goja.Parse("helpers.js", GetHelpers(false))
+	if err != nil {
+		panic(err) // helpers.js is embedded, so this can't happen.
+	}
+	for _, st := range prg.Body {
+		switch st := st.(type) {
+		case *ast.FunctionDeclaration:
+			all[st.Function.Name.Name.String()] = true
+		case *ast.VariableStatement:
+			for _, b := range st.List {
+				id, ok := b.Target.(*ast.Identifier)
+				if !ok {
+					continue
+				}
+				name := id.Name.String()
+				all[name] = true
+				if call, ok := b.Initializer.(*ast.CallExpression); ok {
+					if fn := calleeName(call); fn == "recordBuilder" || fn == "rawrecordBuilder" {
+						records[name] = true
+					}
+				}
+			}
+		}
+	}
+	return all, records
+})
+
+// inspectAST calls f for each ast.Node in the tree rooted at v. If f
+// returns false, the children of the node are skipped. goja has no AST
+// walker, so this uses reflection. Nodes may be visited more than once.
+func inspectAST(v reflect.Value, f func(ast.Node) bool) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return
+		}
+		if n, ok := v.Interface().(ast.Node); ok && v.Kind() == reflect.Ptr {
+			if !f(n) {
+				return
+			}
+		}
+		inspectAST(v.Elem(), f)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				inspectAST(v.Field(i), f)
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			inspectAST(v.Index(i), f)
+		}
+	}
+}
+
+// calleeName returns the name of the function called by call, or "" if
+// it isn't a plain identifier.
+func calleeName(call *ast.CallExpression) string {
+	if id, ok := call.Callee.(*ast.Identifier); ok {
+		return id.Name.String()
+	}
+	return ""
+}
+
+// formatter holds a parsed file.
+type formatter struct {
+	src  string
+	base int // Subtract from a file.Idx to get an offset in src.
+	prg  *ast.Program
+}
+
+func parseForFormat(name, src string) (*formatter, error) {
+	prg, err := goja.Parse(name, src)
+	if err != nil {
+		return nil, err
+	}
+	return &formatter{src: src, base: prg.File.Base(), prg: prg}, nil
+}
+
+func (f *formatter) start(n ast.Node) int { return int(n.Idx0()) - f.base }
+func (f *formatter) end(n ast.Node) int   { return int(n.Idx1()) - f.base }
+func (f *formatter) text(n ast.Node) string {
+	return f.src[f.start(n):f.end(n)]
+}
+
+// edit replaces src[start:end] with text.
+type edit struct {
+	start, end int
+	text       string
+}
+
+// apply returns src with the edits applied. Edits must not overlap.
+func apply(src string, edits []edit) string {
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start < edits[j].start })
+	var b strings.Builder
+	pos := 0
+	for _, e := range edits {
+		b.WriteString(src[pos:e.start])
+		b.WriteString(e.text)
+		pos = e.end
+	}
+	b.WriteString(src[pos:])
+	return b.String()
+}
+
+// FormatSemantic sorts the records in dnsconfig.js and normalizes the
+// way it is written. name is used in error messages.
+func FormatSemantic(name string, src []byte) ([]byte, error) {
+	f, err := parseForFormat(name, string(src))
+	if err != nil {
+		return nil, err
+	}
+	// The literals are changed first so that duplicates that differ
+	// only in spelling are found.
+	f, err = parseForFormat(name, apply(f.src, f.literalEdits()))
+	if err != nil {
+		return nil, err
+	}
+	return []byte(apply(f.src, f.domainEdits())), nil
+}
+
+var durationRe = regexp.MustCompile(`^(\d+)([smhdwny]?)$`)
+
+// durationUnits must match stringToDuration() in helpers.js.
+var durationUnits = map[string]int{"": 1, "s": 1, "m": 60, "h": 3600, "d": 86400, "w": 7 * 86400, "n": 30 * 86400, "y": 365 * 86400}
+
+// literalEdits returns the edits that normalize string literals.
+func (f *formatter) literalEdits() []edit {
+	edits := map[int]edit{} // Indexed by start, since nodes may be visited twice.
+	inspectAST(reflect.ValueOf(f.prg), func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.CallExpression:
+			if fn := calleeName(n); (fn == "TTL" || fn == "DefaultTTL") && len(n.ArgumentList) == 1 {
+				if s, ok := n.ArgumentList[0].(*ast.StringLiteral); ok {
+					if m := durationRe.FindStringSubmatch(s.Value.String()); m != nil {
+						v, err := strconv.Atoi(m[1])
+						if err == nil {
+							edits[f.start(s)] = edit{f.start(s), f.end(s), strconv.Itoa(v * durationUnits[m[2]])}
+							return false
+						}
+					}
+				}
+			}
+		case *ast.StringLiteral:
+			if strings.HasPrefix(n.Literal, "'") {
+				edits[f.start(n)] = edit{f.start(n), f.end(n), quote(n.Value.String())}
+			}
+		}
+		return true
+	})
+	var result []edit
+	for _, e := range edits {
+		result = append(result, e)
+	}
+	return result
+}
+
+// quote returns s as a double-quoted JavaScript string.
+func quote(s string) string {
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// domainEdits returns the edits that sort the records and remove the
+// duplicate modifiers in each D() and D_EXTEND().
+func (f *formatter) domainEdits() []edit {
+	edits := map[int]edit{}
+	inspectAST(reflect.ValueOf(f.prg), func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpression)
+		if !ok {
+			return true
+		}
+		switch calleeName(call) {
+		case "D":
+			if e, ok := f.formatDomain(call, 2); ok {
+				edits[e.start] = e
+			}
+			return false
+		case "D_EXTEND":
+			if e, ok := f.formatDomain(call, 1); ok {
+				edits[e.start] = e
+			}
+			return false
+		}
+		return true
+	})
+	var result []edit
+	for _, e := range edits {
+		result = append(result, e)
+	}
+	return result
+}
+
+// argument is one argument of a call, with the comments around it.
+type argument struct {
+	node  ast.Expression
+	lead  string // Comments and white space before the argument.
+	text  string // The argument.
+	trail string // A comment after the comma, on the same line.
+	drop  bool   // A duplicate to be removed.
+}
+
+// splitArguments returns the arguments of call. The last result is the
+// text after the last argument, up to and including the closing
+// parenthesis, and whether it started with a comma.
+func (f *formatter) splitArguments(call *ast.CallExpression) ([]*argument, string, bool) {
+	args := make([]*argument, len(call.ArgumentList))
+	pos := int(call.LeftParenthesis) - f.base + 1
+	for i, n := range call.ArgumentList {
+		args[i] = &argument{node: n, lead: f.src[pos:f.start(n)], text: f.text(n)}
+		pos = f.end(n)
+		next := int(call.RightParenthesis) - f.base
+		if i+1 < len(call.ArgumentList) {
+			next = f.start(call.ArgumentList[i+1])
+		}
+		gap := f.src[pos:next]
+		comma := findComma(gap)
+		if comma < 0 {
+			// Only the last argument may lack a comma.
+			return args, f.src[pos:f.end(call)], false
+		}
+		// Comments before the comma stay with the argument.
+		if pre := gap[:comma]; strings.TrimSpace(pre) != "" {
+			args[i].text += pre
+		}
+		pos += comma + 1
+		if nl := strings.IndexByte(gap[comma:], '\n'); nl >= 0 && isSpaceOrComment(gap[comma+1:comma+nl]) {
+			args[i].trail = strings.TrimSpace(gap[comma+1 : comma+nl])
+			pos += nl - 1
+		}
+	}
+	return args, f.src[pos:f.end(call)], true
+}
+
+// findComma returns the index of the first comma in s that isn't in a
+// comment, or -1.
+func findComma(s string) int {
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == ',':
+			return i
+		case strings.HasPrefix(s[i:], "//"):
+			nl := strings.IndexByte(s[i:], '\n')
+			if nl < 0 {
+				return -1
+			}
+			i += nl
+		case strings.HasPrefix(s[i:], "/*"):
+			end := strings.Index(s[i+2:], "*/")
+			if end < 0 {
+				return -1
+			}
+			i += end + 3
+		}
+	}
+	return -1
+}
+
+// isSpaceOrComment reports whether s, which is part of a line, has
+// nothing but white space and comments.
+func isSpaceOrComment(s string) bool {
+	s = strings.TrimSpace(s)
+	for s != "" {
+		switch {
+		case strings.HasPrefix(s, "//"):
+			return true
+		case strings.HasPrefix(s, "/*"):
+			end := strings.Index(s, "*/")
+			if end < 0 {
+				return false
+			}
+			s = strings.TrimSpace(s[end+2:])
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// formatDomain returns the edit that rewrites the arguments of call,
+// which is D() or D_EXTEND(). The first skip arguments are left alone.
+func (f *formatter) formatDomain(call *ast.CallExpression, skip int) (edit, bool) {
+	args, tail, trailingComma := f.splitArguments(call)
+	if len(args) <= skip {
+		return edit{}, false
+	}
+
+	// Remove duplicate modifiers, of the domain and of each record.
+	f.markDuplicates(args[skip:])
+	args, tail = removeDropped(args, tail)
+	for _, a := range args[skip:] {
+		if rec, ok := f.recordCall(a.node); ok {
+			a.text = f.formatRecord(rec)
+		}
+	}
+
+	// Sort each run of records.
+	for i := skip; i < len(args); i++ {
+		j := i
+		for j < len(args) && f.isSortableRecord(args[j].node) {
+			j++
+		}
+		run := args[i:j]
+		sort.SliceStable(run, func(a, b int) bool {
+			return f.recordLess(run[a].node, run[b].node)
+		})
+		i = j
+	}
+
+	return edit{f.start(call), f.end(call), f.rebuild(call, args, tail, trailingComma)}, true
+}
+
+// removeDropped returns args without the arguments marked as dropped.
+// Their comments are kept.
+func removeDropped(args []*argument, tail string) ([]*argument, string) {
+	var kept []*argument
+	comments := ""
+	for _, a := range args {
+		if !a.drop {
+			a.lead = comments + a.lead
+			comments = ""
+			kept = append(kept, a)
+			continue
+		}
+		if strings.Contains(a.lead, "/") {
+			comments += strings.TrimRight(a.lead, " \t\n")
+		}
+		if a.trail != "" {
+			comments += " " + a.trail
+		}
+		if endsWithLineComment(comments) {
+			comments += "\n"
+		}
+	}
+	return kept, comments + tail
+}
+
+// rebuild returns the text of call with the arguments args.
+func (f *formatter) rebuild(call *ast.CallExpression, args []*argument, tail string, trailingComma bool) string {
+	var b strings.Builder
+	b.WriteString(f.src[f.start(call) : int(call.LeftParenthesis)-f.base+1])
+	trail := ""
+	for i, a := range args {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		writeTrail(&b, trail, a.lead)
+		b.WriteString(a.lead)
+		b.WriteString(a.text)
+		trail = a.trail
+	}
+	if trailingComma {
+		b.WriteString(",")
+	}
+	writeTrail(&b, trail, tail)
+	b.WriteString(tail)
+	return b.String()
+}
+
+// writeTrail writes the comment trail, followed by a newline if it is a
+// line comment and next doesn't start on a new line.
+func writeTrail(b *strings.Builder, trail, next string) {
+	if trail == "" {
+		return
+	}
+	b.WriteString(" " + trail)
+	if endsWithLineComment(trail) && !strings.HasPrefix(strings.TrimLeft(next, " \t"), "\n") {
+		b.WriteString("\n")
+	}
+}
+
+// endsWithLineComment reports whether the last line of s, which has
+// nothing but white space and comments, has a // comment.
+func endsWithLineComment(s string) bool {
+	return strings.Contains(s[strings.LastIndexByte(s, '\n')+1:], "//")
+}
+
+// recordCall returns n if it creates a record.
+func (f *formatter) recordCall(n ast.Expression) (*ast.CallExpression, bool) {
+	call, ok := n.(*ast.CallExpression)
+	if !ok {
+		return nil, false
+	}
+	_, records := helperNames()
+	return call, records[calleeName(call)]
+}
+
+// orderedRecords lists the records whose order is significant: Cloudflare
+// gives page rules, redirects and worker routes their priority in the
+// order they are defined.
+var orderedRecords = map[string]bool{
+	"CF_REDIRECT":        true,
+	"CF_SINGLE_REDIRECT": true,
+	"CF_TEMP_REDIRECT":   true,
+	"CF_WORKER_ROUTE":    true,
+}
+
+// isSortableRecord reports whether n creates a record whose label is a
+// string literal and whose order doesn't matter.
+func (f *formatter) isSortableRecord(n ast.Expression) bool {
+	call, ok := f.recordCall(n)
+	if !ok || len(call.ArgumentList) == 0 || orderedRecords[calleeName(call)] {
+		return false
+	}
+	_, ok = call.ArgumentList[0].(*ast.StringLiteral)
+	return ok
+}
+
+func (f *formatter) recordLess(a, b ast.Expression) bool {
+	ca, cb := a.(*ast.CallExpression), b.(*ast.CallExpression)
+	la := ca.ArgumentList[0].(*ast.StringLiteral).Value.String()
+	lb := cb.ArgumentList[0].(*ast.StringLiteral).Value.String()
+	if la != lb {
+		return prettyzone.LabelLess(la, lb)
+	}
+	return prettyzone.RrtypeLess(calleeName(ca), calleeName(cb))
+}
+
+// formatRecord returns the text of the record created by call, without
+// duplicate modifiers.
+func (f *formatter) formatRecord(call *ast.CallExpression) string {
+	args, tail, trailingComma := f.splitArguments(call)
+	if len(args) < 2 || !f.markDuplicates(args[1:]) {
+		return f.text(call)
+	}
+	args, tail = removeDropped(args, tail)
+	return f.rebuild(call, args, tail, trailingComma)
+}
+
+// lastWins lists the modifiers of which only the last one counts. A
+// repeat of one of these can only be removed if nothing in the same
+// group came in between.
+var lastWins = map[string]string{
+	"DefaultTTL":           "ttl",
+	"TTL":                  "ttl",
+	"PURGE":                "purge",
+	"NO_PURGE":             "purge",
+	"AUTODNSSEC_ON":        "dnssec",
+	"AUTODNSSEC_OFF":       "dnssec",
+	"CF_PROXY_ON":          "cfproxy",
+	"CF_PROXY_OFF":         "cfproxy",
+	"CF_PROXY_FULL":        "cfproxy",
+	"CF_PROXY_DEFAULT_ON":  "cfproxydefault",
+	"CF_PROXY_DEFAULT_OFF": "cfproxydefault",
+}
+
+// markDuplicates marks the modifiers in args that have no effect
+// because they repeat an earlier one. Only modifiers defined in
+// helpers.js and object literals are considered. It reports whether
+// any were marked.
+func (f *formatter) markDuplicates(args []*argument) bool {
+	all, records := helperNames()
+	seen := map[string]bool{}
+	last := map[string]string{} // The last text in each lastWins group.
+	found := false
+	for _, a := range args {
+		var name, group string
+		switch n := a.node.(type) {
+		case *ast.Identifier:
+			name = n.Name.String()
+		case *ast.CallExpression:
+			name = calleeName(n)
+			if name == "DnsProvider" && len(n.ArgumentList) != 0 {
+				// The last DnsProvider() for a provider counts.
+				group = "DnsProvider " + f.text(n.ArgumentList[0])
+			}
+		case *ast.ObjectLiteral:
+			// Metadata: the last value of a key counts.
+			group = "{}"
+		default:
+			continue
+		}
+		if group == "" {
+			if !all[name] || records[name] {
+				continue
+			}
+			group = lastWins[name]
+		}
+
+		text := strings.Join(strings.Fields(a.text), " ")
+		if group != "" {
+			if last[group] == text {
+				a.drop, found = true, true
+			}
+			last[group] = text
+			continue
+		}
+		if seen[text] {
+			a.drop, found = true, true
+		}
+		seen[text] = true
+	}
+	return found
+}
@@ -0,0 +1,122 @@
+package js
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFormatSemantic(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "sort",
+			in: `D("example.com", REG,
+    A("www", "1.2.3.4"),
+    MX("@", 10, "mx"),
+    A("@", "1.2.3.4"),
+    CNAME("*", "www")
+);`,
+			want: `D("example.com", REG,
+    A("@", "1.2.3.4"),
+    MX("@", 10, "mx"),
+    CNAME("*", "www"),
+    A("www", "1.2.3.4")
+);`,
+		},
+		{
+			name: "modifiersSplitRuns",
+			in:   `D("example.com", REG, A("b", "1.1.1.1"), A("a", "1.1.1.1"), DefaultTTL(600), A("d", "1.1.1.1"), A("c", "1.1.1.1"));`,
+			want: `D("example.com", REG, A("a", "1.1.1.1"), A("b", "1.1.1.1"), DefaultTTL(600), A("c", "1.1.1.1"), A("d", "1.1.1.1"));`,
+		},
+		{
+			name: "orderedRecordsKept",
+			in:   `D("example.com", REG, A("b", "1.1.1.1"), CF_REDIRECT("z.example.com/*", "https://example.com/$1"), CF_TEMP_REDIRECT("a.example.com/*", "https://example.com/$1"), CF_WORKER_ROUTE("y.example.com/*", "w"), CF_SINGLE_REDIRECT("x", 301, "true", "concat(\"https://example.com\", http.request.uri.path)"), A("a", "1.1.1.1"));`,
+			want: `D("example.com", REG, A("b", "1.1.1.1"), CF_REDIRECT("z.example.com/*", "https://example.com/$1"), CF_TEMP_REDIRECT("a.example.com/*", "https://example.com/$1"), CF_WORKER_ROUTE("y.example.com/*", "w"), CF_SINGLE_REDIRECT("x", 301, "true", "concat(\"https://example.com\", http.request.uri.path)"), A("a", "1.1.1.1"));`,
+		},
+		{
+			name: "extend",
+			in:   `D_EXTEND("sub.example.com", A("b", "1.1.1.1"), A("a", "1.1.1.1"));`,
+			want: `D_EXTEND("sub.example.com", A("a", "1.1.1.1"), A("b", "1.1.1.1"));`,
+		},
+		{
+			name: "quotes",
+			in:   `var REG = NewRegistrar('none'); D('example.com', REG, TXT('@', 'say "hi"'));`,
+			want: `var REG = NewRegistrar("none"); D("example.com", REG, TXT("@", "say \"hi\""));`,
+		},
+		{
+			name: "ttl",
+			in:   `D("example.com", REG, DefaultTTL("1h"), A("@", "1.2.3.4", TTL('5m')), A("www", "1.2.3.4", TTL("1d")));`,
+			want: `D("example.com", REG, DefaultTTL(3600), A("@", "1.2.3.4", TTL(300)), A("www", "1.2.3.4", TTL(86400)));`,
+		},
+		{
+			name: "duplicates",
+			in:   `D("example.com", REG, NO_PURGE, NO_PURGE, A("@", "1.2.3.4", CF_PROXY_ON, CF_PROXY_ON, TTL(60), TTL(60)));`,
+			want: `D("example.com", REG, NO_PURGE, A("@", "1.2.3.4", CF_PROXY_ON, TTL(60)));`,
+		},
+		{
+			name: "differentValuesKept",
+			in:   `D("example.com", REG, DefaultTTL(60), A("@", "1.2.3.4"), DefaultTTL(300), A("www", "1.2.3.4"));`,
+			want: `D("example.com", REG, DefaultTTL(60), A("@", "1.2.3.4"), DefaultTTL(300), A("www", "1.2.3.4"));`,
+		},
+		{
+			name: "comments",
+			in: `D("example.com", REG,
+    NO_PURGE, NO_PURGE,
+    TXT("@", "v=spf1 -all"), // SPF
+    // Web:
+    A("www", "1.2.3.4"),
+    A("@", "1.2.3.4") /* last */
+);`,
+			want: `D("example.com", REG,
+    NO_PURGE,
+    A("@", "1.2.3.4"),
+    TXT("@", "v=spf1 -all"), // SPF
+    // Web:
+    A("www", "1.2.3.4") /* last */
+);`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FormatSemantic(tt.name+".js", []byte(tt.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestFormatSemanticIdempotent checks that formatting the parse tests
+// a second time changes nothing.
+func TestFormatSemanticIdempotent(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join(testDir, "*.js"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		t.Run(filepath.Base(f), func(t *testing.T) {
+			src, err := os.ReadFile(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			once, err := FormatSemantic(f, src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			twice, err := FormatSemantic(f, once)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(once) != string(twice) {
+				t.Errorf("not idempotent:\n%s\n---\n%s", once, twice)
+			}
+		})
+	}
+}
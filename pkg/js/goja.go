@@ -0,0 +1,516 @@
+package js
+
+// This file implements the default engine (goja). Unlike otto it
+// supports ES2020+: let/const, arrow functions, template literals,
+// destructuring, classes, Promises, and so on.
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/StackExchange/dnscontrol/v4/pkg/rfc4183"
+	"github.com/StackExchange/dnscontrol/v4/pkg/transform"
+	"github.com/dop251/goja"
+	"github.com/robertkrimen/otto/underscore"
+)
+
+// gojaEngine is the state of one run of helpers.js and dnsconfig.js.
+type gojaEngine struct {
+	vm *goja.Runtime
+
+	// Pending setTimeout()/setInterval() calls.
+	timers  map[int64]*gojaTimer
+	timerID int64
+
+	// Promises that were rejected and have no handler (yet).
+	rejected []*goja.Promise
+
+	// CommonJS modules that have been required, by absolute path.
+	modules map[string]*goja.Object
+
+	// The number of records created, for Sandbox.MaxRecords.
+	records int
+}
+
+type gojaTimer struct {
+	id       int64
+	due      time.Time
+	interval time.Duration // Zero for setTimeout().
+	fn       goja.Callable
+	args     []goja.Value
+}
+
+// runGoja runs helpers.js and script with goja and returns conf as JSON.
+func runGoja(script []byte, devMode bool, variables map[string]string) (string, error) {
+	e := &gojaEngine{
+		vm:      goja.New(),
+		timers:  map[int64]*gojaTimer{},
+		modules: map[string]*goja.Object{},
+	}
+	vm := e.vm
+	vm.SetPromiseRejectionTracker(e.trackRejection)
+
+	if Sandbox.Enabled {
+		stop, err := setupSandbox(vm)
+		if err != nil {
+			return "", err
+		}
+		defer stop()
+	}
+
+	// helpers.js depends on underscore. otto loads it automatically.
+	if _, err := vm.RunScript("underscore.js", underscore.Source()); err != nil {
+		return "", err
+	}
+
+	e.defineConsole()
+	if Sandbox.Enabled {
+		// Timers and fetch() make the result depend on more than the input.
+		for _, name := range []string{"setTimeout", "setInterval", "fetch"} {
+			vm.Set(name, e.disabled(name))
+		}
+	} else {
+		vm.Set("setTimeout", func(call goja.FunctionCall) goja.Value { return e.setTimer(call, false) })
+		vm.Set("setInterval", func(call goja.FunctionCall) goja.Value { return e.setTimer(call, true) })
+
+		// only define fetch() when explicitly enabled
+		if EnableFetch {
+			vm.Set("fetch", e.fetch)
+		}
+	}
+	vm.Set("clearTimeout", e.clearTimer)
+	vm.Set("clearInterval", e.clearTimer)
+
+	vm.Set("require", e.require)
+	vm.Set("REV", e.reverse)
+	vm.Set("REVCOMPAT", e.reverseCompat)
+	vm.Set("glob", e.listFiles) // used for require_glob()
+	vm.Set("PANIC", e.jsPanic)
+	vm.Set("__source_location", e.sourceLocation)
+	vm.Set("__count_records", e.countRecords)
+
+	// add cli variables
+	for key, value := range variables {
+		vm.Set(key, value)
+	}
+
+	// run helper script to prime vm and initialize variables
+	if _, err := vm.RunScript("helpers.js", GetHelpers(devMode)); err != nil {
+		return "", err
+	}
+
+	// run user script
+	if _, err := vm.RunScript(scriptFile, string(script)); err != nil {
+		return "", checkESModule(script, err)
+	}
+
+	// wait for timers to finish
+	if err := e.runTimers(); err != nil {
+		return "", err
+	}
+	if len(e.rejected) != 0 {
+		return "", fmt.Errorf("unhandled promise rejection: %s", e.rejected[0].Result())
+	}
+
+	// export conf as string
+	value, err := vm.RunString(`JSON.stringify(conf)`)
+	if err != nil {
+		return "", err
+	}
+	return value.String(), nil
+}
+
+// newError returns a JavaScript Error with the message msg.
+func (e *gojaEngine) newError(msg string) *goja.Object {
+	obj, err := e.vm.New(e.vm.Get("Error"), e.vm.ToValue(msg))
+	if err != nil {
+		panic(err)
+	}
+	return obj
+}
+
+// throw throws a JavaScript Error. It may only be called from a
+// function that was called by JavaScript.
+func (e *gojaEngine) throw(msg string) {
+	panic(e.newError(msg))
+}
+
+// disabled returns a function that throws an error saying that name is
+// disabled in sandbox mode.
+func (e *gojaEngine) disabled(name string) func(goja.FunctionCall) goja.Value {
+	return func(goja.FunctionCall) goja.Value {
+		e.throw(name + "() is disabled in sandbox mode")
+		return nil
+	}
+}
+
+func isDefined(v goja.Value) bool {
+	return !goja.IsUndefined(v) && !goja.IsNull(v)
+}
+
+func (e *gojaEngine) trackRejection(p *goja.Promise, op goja.PromiseRejectionOperation) {
+	switch op {
+	case goja.PromiseRejectionReject:
+		e.rejected = append(e.rejected, p)
+	case goja.PromiseRejectionHandle:
+		for i, r := range e.rejected {
+			if r == p {
+				e.rejected = append(e.rejected[:i], e.rejected[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+func (e *gojaEngine) defineConsole() {
+	printTo := func(w io.Writer) func(call goja.FunctionCall) goja.Value {
+		return func(call goja.FunctionCall) goja.Value {
+			s := make([]string, len(call.Arguments))
+			for i, a := range call.Arguments {
+				s[i] = a.String()
+			}
+			fmt.Fprintln(w, strings.Join(s, " "))
+			return goja.Undefined()
+		}
+	}
+
+	console := e.vm.NewObject()
+	console.Set("log", printTo(os.Stdout))
+	console.Set("debug", printTo(os.Stdout))
+	console.Set("info", printTo(os.Stdout))
+	console.Set("error", printTo(os.Stderr))
+	console.Set("warn", printTo(os.Stderr))
+	e.vm.Set("console", console)
+}
+
+func (e *gojaEngine) setTimer(call goja.FunctionCall, repeat bool) goja.Value {
+	fn, ok := goja.AssertFunction(call.Argument(0))
+	if !ok {
+		e.throw("setTimeout/setInterval: first argument must be a function")
+	}
+	delay := time.Duration(call.Argument(1).ToInteger()) * time.Millisecond
+
+	e.timerID++
+	t := &gojaTimer{id: e.timerID, due: time.Now().Add(delay), fn: fn}
+	if len(call.Arguments) > 2 {
+		t.args = call.Arguments[2:]
+	}
+	if repeat {
+		t.interval = max(delay, time.Millisecond)
+	}
+	e.timers[t.id] = t
+	return e.vm.ToValue(t.id)
+}
+
+func (e *gojaEngine) clearTimer(call goja.FunctionCall) goja.Value {
+	delete(e.timers, call.Argument(0).ToInteger())
+	return goja.Undefined()
+}
+
+// runTimers runs the pending timers in order until there are none left.
+func (e *gojaEngine) runTimers() error {
+	for len(e.timers) != 0 {
+		var next *gojaTimer
+		for _, t := range e.timers {
+			if next == nil || t.due.Before(next.due) || (t.due.Equal(next.due) && t.id < next.id) {
+				next = t
+			}
+		}
+		time.Sleep(time.Until(next.due))
+
+		if next.interval != 0 {
+			next.due = next.due.Add(next.interval)
+		} else {
+			delete(e.timers, next.id)
+		}
+		if _, err := next.fn(goja.Undefined(), next.args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *gojaEngine) require(call goja.FunctionCall) goja.Value {
+	if len(call.Arguments) != 1 {
+		e.throw("require takes exactly one argument")
+	}
+	file := call.Argument(0).String() // The filename as given by the user
+
+	// Record the old currentDirectory so that we can return there.
+	currentDirectoryOld := currentDirectory
+
+	data, relFile, isJSON, err := requireFile(file)
+	if err != nil {
+		e.throw(err.Error())
+	}
+
+	var value = e.vm.ToValue(true)
+
+	// If its a json file return the json value, if it is a module
+	// return its exports, else default to true
+	if isJSON {
+		cmd := fmt.Sprintf(`JSON.parse(JSON.stringify(%s))`, string(data))
+		value, err = e.vm.RunScript(relFile, cmd)
+	} else if isModule(relFile) {
+		value, err = e.requireModule(relFile, data)
+	} else {
+		_, err = e.vm.RunScript(relFile, string(data))
+	}
+	err = checkESModule(data, err)
+
+	if err != nil {
+		var interrupted *goja.InterruptedError
+		if errors.As(err, &interrupted) {
+			// Keep the interrupt uncatchable.
+			panic(interrupted)
+		}
+		e.throw(fmt.Sprintf("File %s: %s", filepath.Base(relFile), err.Error()))
+	}
+
+	// Pop back to the old directory.
+	currentDirectory = currentDirectoryOld
+
+	return value
+}
+
+// sourceLocation returns {file, line}: where the innermost function
+// outside helpers.js was called. It is used to mark records with the
+// line that created them. It returns undefined if that isn't in a file.
+func (e *gojaEngine) sourceLocation(goja.FunctionCall) goja.Value {
+	for _, f := range e.vm.CaptureCallStack(0, nil) {
+		switch f.SrcName() {
+		case "helpers.js", "underscore.js", "<native>":
+			continue
+		case "":
+			return goja.Undefined()
+		}
+		p := f.Position()
+		return e.vm.ToValue(map[string]interface{}{"file": filepath.ToSlash(p.Filename), "line": p.Line})
+	}
+	return goja.Undefined()
+}
+
+func (e *gojaEngine) listFiles(call goja.FunctionCall) goja.Value {
+	// Check amount of arguments provided
+	if !(len(call.Arguments) >= 1 && len(call.Arguments) <= 3) {
+		e.throw(errGlobUsage)
+	}
+
+	// Check if provided parameters are valid
+	// First: Let's check dir.
+	dir, ok := call.Argument(0).Export().(string) // Path where to start listing
+	if !ok || dir == "" {
+		e.throw(errGlobDir)
+	}
+
+	// Second: Recursive?
+	var recursive = true
+	if a := call.Argument(1); isDefined(a) {
+		if recursive, ok = a.Export().(bool); !ok {
+			e.throw(errGlobRecursive)
+		}
+	}
+
+	// Third: File extension filter.
+	var fileExtension = ".js"
+	if a := call.Argument(2); isDefined(a) {
+		if fileExtension, ok = a.Export().(string); !ok {
+			e.throw(errGlobExtension)
+		}
+	}
+
+	files, err := globFiles(dir, recursive, fileExtension)
+	if err != nil {
+		e.throw(err.Error())
+	}
+
+	// let's pass the data back to the JS engine.
+	items := make([]interface{}, len(files))
+	for i, f := range files {
+		items[i] = f
+	}
+	return e.vm.NewArray(items...)
+}
+
+func (e *gojaEngine) jsPanic(call goja.FunctionCall) goja.Value {
+	if len(call.Arguments) != 1 {
+		e.throw("PANIC takes exactly one argument")
+	}
+	if Sandbox.Enabled {
+		// Don't let untrusted code exit the process.
+		e.throw("PANIC: " + call.Argument(0).String())
+	}
+	panicExit(call.Argument(0).String())
+	return goja.Undefined() // Won't be actually executed
+}
+
+func (e *gojaEngine) reverse(call goja.FunctionCall) goja.Value {
+	if len(call.Arguments) != 1 {
+		e.throw("REV takes exactly one argument")
+	}
+	rev, err := transform.ReverseDomainName(call.Argument(0).String())
+	if err != nil {
+		e.throw(err.Error())
+	}
+	return e.vm.ToValue(rev)
+}
+
+func (e *gojaEngine) reverseCompat(call goja.FunctionCall) goja.Value {
+	if len(call.Arguments) != 1 {
+		e.throw("REVCOMPAT takes exactly one argument")
+	}
+	if err := rfc4183.SetCompatibilityMode(call.Argument(0).String()); err != nil {
+		e.throw(err.Error())
+	}
+	return goja.Null()
+}
+
+// fetch implements the subset of the Fetch API described in
+// commands/types/fetch.d.ts. The request is made before fetch()
+// returns; the promise it returns is already settled.
+func (e *gojaEngine) fetch(call goja.FunctionCall) goja.Value {
+	promise, resolve, reject := e.vm.NewPromise()
+	res, err := e.doFetch(call.Argument(0).String(), call.Argument(1))
+	if err != nil {
+		reject(e.newError(err.Error()))
+	} else {
+		resolve(res)
+	}
+	return e.vm.ToValue(promise)
+}
+
+func (e *gojaEngine) doFetch(url string, init goja.Value) (*goja.Object, error) {
+	method := "GET"
+	header := http.Header{}
+	var body io.Reader
+	if opts, ok := init.(*goja.Object); ok {
+		if v := opts.Get("method"); v != nil && isDefined(v) {
+			method = strings.ToUpper(v.String())
+		}
+		if v := opts.Get("body"); v != nil && isDefined(v) {
+			body = strings.NewReader(v.String())
+		}
+		if h, ok := opts.Get("headers").(*goja.Object); ok {
+			for _, k := range h.Keys() {
+				if vals, ok := h.Get(k).Export().([]interface{}); ok {
+					for _, v := range vals {
+						header.Add(k, fmt.Sprint(v))
+					}
+				} else {
+					header.Add(k, h.Get(k).String())
+				}
+			}
+		}
+	}
+
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = header
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	r := e.vm.NewObject()
+	r.Set("status", resp.StatusCode)
+	r.Set("statusText", http.StatusText(resp.StatusCode))
+	r.Set("ok", resp.StatusCode >= 200 && resp.StatusCode <= 299)
+	r.Set("type", "basic")
+	r.Set("url", resp.Request.URL.String())
+	r.Set("bodyUsed", false)
+	r.Set("headers", e.newHeaders(resp.Header))
+	r.Set("text", func(call goja.FunctionCall) goja.Value {
+		r.Set("bodyUsed", true)
+		p, resolve, _ := e.vm.NewPromise()
+		resolve(string(data))
+		return e.vm.ToValue(p)
+	})
+	r.Set("json", func(call goja.FunctionCall) goja.Value {
+		r.Set("bodyUsed", true)
+		p, resolve, reject := e.vm.NewPromise()
+		parse, _ := goja.AssertFunction(e.vm.Get("JSON").ToObject(e.vm).Get("parse"))
+		if v, err := parse(goja.Undefined(), e.vm.ToValue(string(data))); err != nil {
+			if ex, ok := err.(*goja.Exception); ok {
+				reject(ex.Value())
+			} else {
+				reject(e.newError(err.Error()))
+			}
+		} else {
+			resolve(v)
+		}
+		return e.vm.ToValue(p)
+	})
+	return r, nil
+}
+
+// newHeaders returns a Headers-like object backed by h.
+func (e *gojaEngine) newHeaders(h http.Header) *goja.Object {
+	o := e.vm.NewObject()
+	o.Set("get", func(name string) goja.Value {
+		if vals := h.Values(name); len(vals) != 0 {
+			return e.vm.ToValue(strings.Join(vals, ", "))
+		}
+		return goja.Null()
+	})
+	o.Set("getAll", func(name string) []string { return h.Values(name) })
+	o.Set("has", func(name string) bool { return len(h.Values(name)) != 0 })
+	o.Set("append", func(name, value string) { h.Add(name, value) })
+	o.Set("delete", func(name string) { h.Del(name) })
+	o.Set("set", func(name, value string) { h.Set(name, value) })
+	return o
+}
+
+// ErrorPosition returns the line and column in file, the script run by
+// ExecuteJavaScript, where err, which it returned, happened. An error in
+// helpers.js or in a required file is reported where the script called
+// it. ok is false if the position is unknown, for example with the
+// legacy engine.
+func ErrorPosition(file string, err error) (line, column int, ok bool) {
+	var ex *goja.Exception
+	var interrupted *goja.InterruptedError
+	switch {
+	case errors.As(err, &ex):
+	case errors.As(err, &interrupted):
+		// The script was stopped, by the sandbox for example.
+		ex = &interrupted.Exception
+	default:
+		return 0, 0, false
+	}
+	for _, f := range ex.Stack() {
+		if f.SrcName() == file {
+			p := f.Position()
+			return p.Line, p.Column, true
+		}
+	}
+
+	// A syntax error has no stack. The position is in the message.
+	name := file
+	if name == "" {
+		name = "(anonymous)"
+	}
+	re := regexp.MustCompile(`^(?:SyntaxError: )+` + regexp.QuoteMeta(name) + `: Line (\d+):(\d+) `)
+	if ex.Value() == nil {
+		return 0, 0, false
+	}
+	if m := re.FindStringSubmatch(ex.Value().String()); m != nil {
+		line, _ = strconv.Atoi(m[1])
+		column, _ = strconv.Atoi(m[2])
+		return line, column, true
+	}
+	return 0, 0, false
+}
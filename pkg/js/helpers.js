@@ -112,6 +112,7 @@ function newDomain(name, registrar) {
         ignored_names: [],
         ignored_targets: [],
         unmanaged: [],
+        protected: [],
     };
 }
 
@@ -164,6 +165,7 @@ function INCLUDE(name) {
         );
     }
     return function (d) {
+        __count_records(domain.obj.records.length);
         d.records.push.apply(d.records, domain.obj.records);
     };
 }
@@ -235,7 +237,7 @@ function stringToDuration(v) {
     if (matches == null) {
         throw v + ' is not a valid duration string';
     }
-    unit = 's';
+    var unit = 's';
     if (matches[2]) {
         unit = matches[2];
     }
@@ -385,6 +387,119 @@ function validateR53AliasType(value) {
     );
 }
 
+// r53RoutingPolicy returns a record modifier that gives the record the
+// Route53 routing policy described by fields, in a record set
+// identified by setIdentifier. A record may only have one policy.
+function r53RoutingPolicy(name, setIdentifier, fields) {
+    if (!_.isString(setIdentifier) || setIdentifier === '') {
+        throw name + ' requires a set identifier';
+    }
+    return function (r) {
+        if (_isDomain(r)) {
+            throw name + ' can only be used on a record';
+        }
+        if (!_.isObject(r.r53_routing)) {
+            r.r53_routing = {};
+        }
+        if (_.isString(r.r53_routing['set_identifier'])) {
+            throw (
+                name +
+                ': ' +
+                r.name +
+                ' already has the routing policy of set ' +
+                r.r53_routing['set_identifier']
+            );
+        }
+        r.r53_routing['set_identifier'] = setIdentifier;
+        for (var k in fields) {
+            r.r53_routing[k] = fields[k];
+        }
+    };
+}
+
+// R53_WEIGHT(setIdentifier, weight)
+function R53_WEIGHT(setIdentifier, weight) {
+    if (
+        !_.isNumber(weight) ||
+        weight < 0 ||
+        weight > 255 ||
+        weight % 1 !== 0
+    ) {
+        throw 'R53_WEIGHT: ' + weight + ' is not a weight from 0 to 255';
+    }
+    return r53RoutingPolicy('R53_WEIGHT', setIdentifier, {
+        weight: weight.toString(),
+    });
+}
+
+// R53_LATENCY_REGION(setIdentifier, region)
+function R53_LATENCY_REGION(setIdentifier, region) {
+    if (!_.isString(region) || region === '') {
+        throw 'R53_LATENCY_REGION requires an AWS region';
+    }
+    return r53RoutingPolicy('R53_LATENCY_REGION', setIdentifier, {
+        region: region,
+    });
+}
+
+// R53_FAILOVER(setIdentifier, role)
+function R53_FAILOVER(setIdentifier, role) {
+    if (role !== 'PRIMARY' && role !== 'SECONDARY') {
+        throw 'R53_FAILOVER: ' + role + ' is not PRIMARY or SECONDARY';
+    }
+    return r53RoutingPolicy('R53_FAILOVER', setIdentifier, {
+        failover: role,
+    });
+}
+
+// R53_GEO(setIdentifier, location)
+// location is {continent: "EU"}, {country: "US"},
+// {country: "US", subdivision: "CA"} or {country: "*"}.
+function R53_GEO(setIdentifier, location) {
+    if (!_.isObject(location)) {
+        throw 'R53_GEO requires a location such as {country: "US"}';
+    }
+    var fields = {};
+    if (_.isString(location.continent)) {
+        fields['geo_continent'] = location.continent;
+    }
+    if (_.isString(location.country)) {
+        fields['geo_country'] = location.country;
+    }
+    if (_.isString(location.subdivision)) {
+        fields['geo_subdivision'] = location.subdivision;
+    }
+    var continent = 'geo_continent' in fields;
+    var country = 'geo_country' in fields;
+    if (continent === country || ('geo_subdivision' in fields && !country)) {
+        throw 'R53_GEO requires either a continent or a country, optionally with a subdivision';
+    }
+    return r53RoutingPolicy('R53_GEO', setIdentifier, fields);
+}
+
+// R53_MULTIVALUE(setIdentifier)
+function R53_MULTIVALUE(setIdentifier) {
+    return r53RoutingPolicy('R53_MULTIVALUE', setIdentifier, {
+        multivalue: 'true',
+    });
+}
+
+// R53_HEALTH_CHECK_ID(id)
+function R53_HEALTH_CHECK_ID(id) {
+    if (!_.isString(id) || id === '') {
+        throw 'R53_HEALTH_CHECK_ID requires a health check id';
+    }
+    return function (r) {
+        if (_isDomain(r)) {
+            throw 'R53_HEALTH_CHECK_ID can only be used on a record';
+        }
+        if (!_.isObject(r.r53_routing)) {
+            r.r53_routing = {};
+        }
+        r.r53_routing['health_check_id'] = id;
+    };
+}
+
 // CAA(name,tag,value, recordModifiers...)
 var CAA = recordBuilder('CAA', {
     // TODO(tlim): It should be an error if value is not 0 or 128.
@@ -682,11 +797,11 @@ function getENotationInt(x) {
        1cm = 1e0 == 16 (1^4 + 0) or 0<<4 + 0
        0cm = 0e0 == 0
     */
-    size = x * 100; // get cm value
+    var size = x * 100; // get cm value
     // get the m^e version of size
-    array = size.toExponential(0).split('e+').map(Number);
+    var array = size.toExponential(0).split('e+').map(Number);
     // convert it to 4bit:4bit uint8
-    m_e = (array[0] << 4) | array[1];
+    var m_e = (array[0] << 4) | array[1];
     return m_e;
 }
 
@@ -699,11 +814,11 @@ function locStringBuilder(record, args) {
     // it is a good sanity check to compare with later on down the chain
     // when you're in the weeds with maths.
     // Tests depend on it being present. Changes here must reflect in tests.
-    nsstring = '';
-    ewstring = '';
-    precisionbuffer = '';
-    ns = args.ns.toUpperCase();
-    ew = args.ew.toUpperCase();
+    var nsstring = '';
+    var ewstring = '';
+    var precisionbuffer = '';
+    var ns = args.ns.toUpperCase();
+    var ew = args.ew.toUpperCase();
 
     // Handle N/S coords - can use also s1.toFixed(3)
     nsstring =
@@ -748,14 +863,16 @@ function locStringBuilder(record, args) {
 // Renders LOC type internal properties from D˚M'S" parameters.
 // Change anything here at your peril.
 function locDMSBuilder(record, args) {
-    LOCEquator = 1 << 31; // RFC 1876, Section 2.
-    LOCPrimeMeridian = 1 << 31; // RFC 1876, Section 2.
-    LOCHours = 60 * 1000;
-    LOCDegrees = 60 * LOCHours;
-    LOCAltitudeBase = 100000;
-
-    lat = args.d1 * LOCDegrees + args.m1 * LOCHours + args.s1 * 1000;
-    lon = args.d2 * LOCDegrees + args.m2 * LOCHours + args.s2 * 1000;
+    var LOCEquator = 1 << 31; // RFC 1876, Section 2.
+    var LOCPrimeMeridian = 1 << 31; // RFC 1876, Section 2.
+    var LOCHours = 60 * 1000;
+    var LOCDegrees = 60 * LOCHours;
+    var LOCAltitudeBase = 100000;
+
+    var ns = args.ns.toUpperCase();
+    var ew = args.ew.toUpperCase();
+    var lat = args.d1 * LOCDegrees + args.m1 * LOCHours + args.s1 * 1000;
+    var lon = args.d2 * LOCDegrees + args.m2 * LOCHours + args.s2 * 1000;
     if (ns == 'N') record.loclatitude = LOCEquator + lat;
     // S
     else record.loclatitude = LOCEquator - lat;
@@ -767,7 +884,7 @@ function locDMSBuilder(record, args) {
     // Size
     record.locsize = getENotationInt(args.siz);
     // Horizontal Precision
-    m_e = args.hp;
+    var m_e = args.hp;
     record.lochorizpre = getENotationInt(args.hp);
     // if (m_e != 0) {
     // } else {
@@ -850,6 +967,19 @@ function NAMESERVER_TTL(v) {
     return { ns_ttl: v.toString() };
 }
 
+// MAX_CHANGES(n)
+// ppush refuses to change more than n records in the domain unless --force is given.
+function MAX_CHANGES(n) {
+    return { max_changes: n.toString() };
+}
+
+// MAX_DELETE_PERCENT(p)
+// ppush refuses to delete more than p percent of the domain's existing
+// records unless --force is given.
+function MAX_DELETE_PERCENT(p) {
+    return { max_delete_percent: p.toString() };
+}
+
 function format_tt(transform_table) {
     // Turn [[low: 1, high: 2, newBase: 3], [low: 4, high: 5, newIP: 6]]
     // into "1 ~ 2 ~ 3 ~; 4 ~ 5 ~  ~ 6"
@@ -921,6 +1051,28 @@ function IGNORE(labelPattern, rtypePattern, targetPattern) {
     };
 }
 
+// PROTECT(labelPattern, rtypePattern, targetPattern)
+// Records that match may not be deleted or modified unless
+// --allow-protected is given. Unlike IGNORE(), they remain managed.
+function PROTECT(labelPattern, rtypePattern, targetPattern) {
+    if (labelPattern === undefined) {
+        labelPattern = '*';
+    }
+    if (rtypePattern === undefined) {
+        rtypePattern = '*';
+    }
+    if (targetPattern === undefined) {
+        targetPattern = '*';
+    }
+    return function (d) {
+        d.protected.push({
+            label_pattern: labelPattern,
+            rType_pattern: rtypePattern,
+            target_pattern: targetPattern,
+        });
+    };
+}
+
 // IGNORE_NAME(name, rTypes)
 function IGNORE_NAME(name, rTypes) {
     return IGNORE(name, rTypes);
@@ -1042,6 +1194,8 @@ function recordBuilder(type, opts) {
     return function () {
         var parsedArgs = {};
         var modifiers = [];
+        // Where dnsconfig.js called us, for error messages.
+        var source = __source_location();
 
         if (arguments.length < opts.args.length) {
             var argumentsList = opts.args
@@ -1090,6 +1244,7 @@ function recordBuilder(type, opts) {
                 type: type,
                 meta: {},
                 ttl: d.defaultTTL,
+                source: source,
             };
 
             opts.applyModifier(record, modifiers);
@@ -1103,7 +1258,7 @@ function recordBuilder(type, opts) {
                 record.type != 'CF_TEMP_REDIRECT' &&
                 record.type != 'CF_WORKER_ROUTE'
             ) {
-                fqdn = [d.subdomain, d.name].join('.');
+                var fqdn = [d.subdomain, d.name].join('.');
 
                 record.subdomain = d.subdomain;
                 if (record.name == '@') {
@@ -1118,6 +1273,7 @@ function recordBuilder(type, opts) {
             // Now we finally have the record. If it is a normal record, we add
             // it to "records". If it is an ENSURE_ABSENT record, we add it to
             // the ensure_absent list.
+            __count_records(1);
             if (record.ensure_absent) {
                 d.recordsabsent.push(record);
             } else {
@@ -1170,6 +1326,7 @@ function addRecord(d, type, name, target, mods) {
             }
         }
     }
+    __count_records(1);
     d.records.push(rec);
     return rec;
 }
@@ -1280,7 +1437,7 @@ function LOC_BUILDER_DD(value) {
     var lati = ConvertDDToDMS(value.x, false);
     var long = ConvertDDToDMS(value.y, true);
 
-    dms = { lati: lati, long: long };
+    var dms = { lati: lati, long: long };
 
     return LOC_builder_push(value, dms);
 }
@@ -1347,8 +1504,8 @@ function LOC_BUILDER_STR(value) {
 }
 
 function LOC_builder_push(value, dms) {
-    r = []; // The list of records to return.
-    p = {}; // The metaparameters to set on the LOC record.
+    var r = []; // The list of records to return.
+    var p = {}; // The metaparameters to set on the LOC record.
     // rawloc = "";
 
     // Generate a LOC record with the metaparameters.
@@ -1458,16 +1615,16 @@ function SPF_BUILDER(value) {
         value.raw = '_rawspf';
     }
 
-    r = []; // The list of records to return.
-    p = {}; // The metaparameters to set on the main TXT record.
-    rawspf = value.parts.join(' '); // The unaltered SPF settings.
+    var r = []; // The list of records to return.
+    var p = {}; // The metaparameters to set on the main TXT record.
+    var rawspf = value.parts.join(' '); // The unaltered SPF settings.
 
     // If flattening is requested, generate a TXT record with the raw SPF settings.
     if (value.flatten && value.flatten.length > 0) {
         p.flatten = value.flatten.join(',');
         // Only add the raw spf record if it isn't an empty string
         if (value.raw !== '') {
-            rp = {};
+            var rp = {};
             if (value.ttl) {
                 r.push(TXT(value.raw, rawspf, rp, TTL(value.ttl)));
             } else {
@@ -1529,7 +1686,7 @@ function CAA_BUILDER(value) {
     if (value.ttl) {
         CAA_TTL = TTL(value.ttl);
     }
-    r = []; // The list of records to return.
+    var r = []; // The list of records to return.
 
     if (value.iodef) {
         if (value.iodef_critical) {
@@ -1937,6 +2094,9 @@ var DISABLE_REPEATED_DOMAIN_CHECK = { skip_fqdn_check: 'true' };
 
 function rawrecordBuilder(type) {
     return function () {
+        // Where dnsconfig.js called us, for error messages.
+        var source = __source_location();
+
         // Copy the raw args:
         var rawArgs = [];
         for (var i = 0; i < arguments.length; i++) {
@@ -1946,6 +2106,7 @@ function rawrecordBuilder(type) {
         return function (d) {
             var record = {
                 type: type,
+                source: source,
             };
 
             // Process the args: Functions are executed, objects are assumed to
@@ -1973,6 +2134,7 @@ function rawrecordBuilder(type) {
             record.metas = processedMetas;
 
             // Add this raw record to the list of records.
+            __count_records(1);
             d.rawrecords.push(record);
 
             return record;
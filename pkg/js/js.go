@@ -3,6 +3,7 @@ package js
 import (
 	_ "embed" // Used to embed helpers.js in the binary.
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -11,14 +12,6 @@ import (
 
 	"github.com/StackExchange/dnscontrol/v4/models"
 	"github.com/StackExchange/dnscontrol/v4/pkg/printer"
-	"github.com/StackExchange/dnscontrol/v4/pkg/rfc4183"
-	"github.com/StackExchange/dnscontrol/v4/pkg/transform"
-	"github.com/robertkrimen/otto"              // load underscore js into vm by default
-	_ "github.com/robertkrimen/otto/underscore" // required by otto
-	"github.com/xddxdd/ottoext/fetch"
-	"github.com/xddxdd/ottoext/loop"
-	"github.com/xddxdd/ottoext/promise"
-	"github.com/xddxdd/ottoext/timers"
 )
 
 //go:embed helpers.js
@@ -33,75 +26,54 @@ var helpersJsFileName = "pkg/js/helpers.js"
 // far as require() is concerned, not the actual os.Getwd().
 var currentDirectory string
 
+// scriptFile is the name of the file being run. Records created by it
+// are marked with their location in it. It is empty if the script isn't
+// from a file (ExecuteJavascriptString), in which case only records
+// created by required files are marked.
+var scriptFile string
+
 // EnableFetch sets whether to enable fetch() in JS execution environment
 var EnableFetch bool = false
 
+// LegacyEngine selects the old ES5-only JavaScript interpreter (otto)
+// instead of the ES2020+ one (goja). It is set by the --legacy-js flag.
+// It will be removed in a future release.
+var LegacyEngine bool = false
+
 // ExecuteJavaScript accepts a javascript file and runs it, returning the resulting dnsConfig.
 func ExecuteJavaScript(file string, devMode bool, variables map[string]string) (*models.DNSConfig, error) {
 	script, err := os.ReadFile(file)
 	if err != nil {
 		return nil, err
 	}
+	return ExecuteJavaScriptSource(file, script, devMode, variables)
+}
 
+// ExecuteJavaScriptSource runs script as if it were the contents of
+// file, which need not be saved. require() is relative to the directory
+// of file.
+func ExecuteJavaScriptSource(file string, script []byte, devMode bool, variables map[string]string) (*models.DNSConfig, error) {
 	// Record the directory path leading up to this file.
 	currentDirectory = filepath.Dir(file)
 
+	scriptFile = file
+	defer func() { scriptFile = "" }()
+
 	return ExecuteJavascriptString(script, devMode, variables)
 }
 
 // ExecuteJavascriptString accepts a string containing javascript and runs it, returning the resulting dnsConfig.
 func ExecuteJavascriptString(script []byte, devMode bool, variables map[string]string) (*models.DNSConfig, error) {
-
-	vm := otto.New()
-	l := loop.New(vm)
-
-	if err := timers.Define(vm, l); err != nil {
-		return nil, err
-	}
-	if err := promise.Define(vm, l); err != nil {
-		return nil, err
-	}
-
-	// only define fetch() when explicitly enabled
-	if EnableFetch {
-		if err := fetch.Define(vm, l); err != nil {
-			return nil, err
+	run := runGoja
+	if LegacyEngine {
+		if Sandbox.Enabled {
+			return nil, errors.New("sandbox mode is not supported by the legacy JavaScript engine")
 		}
+		run = runOtto
 	}
 
-	vm.Set("require", require)
-	vm.Set("REV", reverse)
-	vm.Set("REVCOMPAT", reverseCompat)
-	vm.Set("glob", listFiles) // used for require_glob()
-	vm.Set("PANIC", jsPanic)
-
-	// add cli variables to otto
-	for key, value := range variables {
-		vm.Set(key, value)
-	}
-
-	helperJs := GetHelpers(devMode)
-	// run helper script to prime vm and initialize variables
-	if err := l.Eval(helperJs); err != nil {
-		return nil, err
-	}
-
-	// run user script
-	if err := l.Eval(script); err != nil {
-		return nil, err
-	}
-
-	// wait for event loop to finish
-	if err := l.Run(); err != nil {
-		return nil, err
-	}
-
-	// export conf as string and unmarshal
-	value, err := vm.Run(`JSON.stringify(conf)`)
-	if err != nil {
-		return nil, err
-	}
-	str, err := value.ToString()
+	// run returns conf as a JSON string.
+	str, err := run(script, devMode, variables)
 	if err != nil {
 		return nil, err
 	}
@@ -109,6 +81,9 @@ func ExecuteJavascriptString(script []byte, devMode bool, variables map[string]s
 	if err = json.Unmarshal([]byte(str), conf); err != nil {
 		return nil, err
 	}
+	if err = checkSandboxRecords(conf); err != nil {
+		return nil, err
+	}
 	return conf, nil
 }
 
@@ -127,100 +102,57 @@ func GetHelpers(devMode bool) string {
 	return helpersJsStatic
 }
 
-func require(call otto.FunctionCall) otto.Value {
-	if len(call.ArgumentList) != 1 {
-		throw(call.Otto, "require takes exactly one argument")
-	}
-	file := call.Argument(0).String() // The filename as given by the user
-
+// requireFile reads the file named by require(file). It returns the
+// contents, the path that was read, and whether the file is JSON (or
+// JSON5). currentDirectory is updated to the directory of the file;
+// the caller must restore it after the file has been run.
+func requireFile(file string) (data []byte, relFile string, isJSON bool, err error) {
 	// relFile is the file we're actually going to pass to ReadFile().
 	// It defaults to the user-provided name unless it is relative.
-	relFile := file
+	relFile = file
 	cleanFile := filepath.Clean(filepath.Join(currentDirectory, file))
 	if strings.HasPrefix(file, ".") {
 		relFile = cleanFile
 	}
 
-	// Record the old currentDirectory so that we can return there.
-	currentDirectoryOld := currentDirectory
 	// Record the directory path leading up to the file we're about to require.
 	currentDirectory = filepath.Dir(cleanFile)
 
-	printer.Debugf("requiring: %s (%s)\n", file, relFile)
-	// quick fix, by replacing to linux slashes, to make it work with windows paths too.
-	data, err := os.ReadFile(filepath.ToSlash(relFile))
-
-	if err != nil {
-		throw(call.Otto, err.Error())
+	if err := checkSandboxPath(relFile); err != nil {
+		return nil, relFile, false, err
 	}
 
-	var value = otto.TrueValue()
+	printer.Debugf("requiring: %s (%s)\n", file, relFile)
+	// quick fix, by replacing to linux slashes, to make it work with windows paths too.
+	data, err = os.ReadFile(filepath.ToSlash(relFile))
 
 	// If its a json file return the json value, else default to true
 	var ext = strings.ToLower(filepath.Ext(relFile))
-	if strings.HasSuffix(ext, "json") || strings.HasSuffix(ext, "json5") {
-		cmd := fmt.Sprintf(`JSON.parse(JSON.stringify(%s))`, string(data))
-		value, err = call.Otto.Run(cmd)
-	} else {
-		_, err = call.Otto.Run(string(data))
-	}
-
-	if err != nil {
-		throw(call.Otto, fmt.Sprintf("File %s: %s", filepath.Base(relFile), err.Error()))
-	}
+	isJSON = strings.HasSuffix(ext, "json") || strings.HasSuffix(ext, "json5")
 
-	// Pop back to the old directory.
-	currentDirectory = currentDirectoryOld
-
-	return value
+	return data, relFile, isJSON, err
 }
 
-func listFiles(call otto.FunctionCall) otto.Value {
-	// Check amount of arguments provided
-	if !(len(call.ArgumentList) >= 1 && len(call.ArgumentList) <= 3) {
-		throw(call.Otto, "glob requires at least one argument: folder (string). "+
-			"Optional: recursive (bool) [true], fileExtension (string) [.js]")
-	}
-
-	// Check if provided parameters are valid
-	// First: Let's check dir.
-	if !(call.Argument(0).IsDefined() && call.Argument(0).IsString() &&
-		len(call.Argument(0).String()) > 0) {
-		throw(call.Otto, "glob: first argument needs to be a path, provided as string.")
-	}
-	dir := call.Argument(0).String() // Path where to start listing
+// globFiles lists the files in dir (relative to currentDirectory) that
+// have the extension fileExtension ("*" for all). It is used by glob().
+func globFiles(dir string, recursive bool, fileExtension string) ([]string, error) {
 	printer.Debugf("listFiles: cd: %s, user: %s \n", currentDirectory, dir)
 	// now we always prepend the current directory we're working in, which is being set within
 	// the func ExecuteJavascript() above. So when require("domains/load_all.js") is being used,
 	// where glob("customer1/") is being used, we basically search for files in domains/customer1/.
 	dir = filepath.ToSlash(filepath.Join(currentDirectory, dir))
 
-	if _, err := os.Stat(dir); os.IsNotExist(err) {
-		throw(call.Otto, "glob: provided path does not exist.")
+	if err := checkSandboxPath(dir); err != nil {
+		return nil, err
 	}
 
-	// Second: Recursive?
-	var recursive = true
-	if call.Argument(1).IsDefined() && !call.Argument(1).IsNull() {
-		if call.Argument(1).IsBoolean() {
-			recursive, _ = call.Argument(1).ToBoolean() // If it should be recursive
-		} else {
-			throw(call.Otto, "glob: second argument, if recursive, needs to be bool.")
-		}
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil, errGlobNoPath
 	}
 
-	// Third: File extension filter.
-	var fileExtension = ".js"
-	if call.Argument(2).IsDefined() && !call.Argument(2).IsNull() {
-		if call.Argument(2).IsString() {
-			fileExtension = call.Argument(2).String() // Which file extension to filter for.
-			if !strings.HasPrefix(fileExtension, ".") {
-				// If it doesn't start with a dot, probably user forgot it and we do it instead.
-				fileExtension = "." + fileExtension
-			}
-		} else {
-			throw(call.Otto, "glob: third argument, file extension, needs to be a string. * for no filter.")
-		}
+	if !strings.HasPrefix(fileExtension, ".") {
+		// If it doesn't start with a dot, probably user forgot it and we do it instead.
+		fileExtension = "." + fileExtension
 	}
 
 	// Now we're doing the actual work: Listing files.
@@ -251,58 +183,24 @@ func listFiles(call otto.FunctionCall) otto.Value {
 		return err
 	})
 	if err != nil {
-		throw(call.Otto, fmt.Sprintf("dirwalk failed: %v", err.Error()))
-	}
-
-	// let's pass the data back to the JS engine.
-	value, err := call.Otto.ToValue(files)
-	if err != nil {
-		throw(call.Otto, fmt.Sprintf("converting value failed: %v", err.Error()))
+		return nil, fmt.Errorf("dirwalk failed: %v", err.Error())
 	}
-
-	return value
+	return files, nil
 }
 
-func jsPanic(call otto.FunctionCall) otto.Value {
-	if len(call.ArgumentList) != 1 {
-		throw(call.Otto, "PANIC takes exactly one argument")
-	}
+var errGlobNoPath = errors.New("glob: provided path does not exist.")
 
-	message := call.Argument(0).String() // The filename as given by the user
+// Error messages shared by both engines.
+const (
+	errGlobUsage = "glob requires at least one argument: folder (string). " +
+		"Optional: recursive (bool) [true], fileExtension (string) [.js]"
+	errGlobDir       = "glob: first argument needs to be a path, provided as string."
+	errGlobRecursive = "glob: second argument, if recursive, needs to be bool."
+	errGlobExtension = "glob: third argument, file extension, needs to be a string. * for no filter."
+)
+
+// panicExit prints message and exits. It implements PANIC().
+func panicExit(message string) {
 	fmt.Fprintln(os.Stderr, message)
 	os.Exit(1)
-
-	// Won't be actually executed
-	v, _ := otto.ToValue(0)
-	return v
-}
-
-func throw(vm *otto.Otto, str string) {
-	panic(vm.MakeCustomError("Error", str))
-}
-
-func reverse(call otto.FunctionCall) otto.Value {
-	if len(call.ArgumentList) != 1 {
-		throw(call.Otto, "REV takes exactly one argument")
-	}
-	dom := call.Argument(0).String()
-	rev, err := transform.ReverseDomainName(dom)
-	if err != nil {
-		throw(call.Otto, err.Error())
-	}
-	v, _ := otto.ToValue(rev)
-	return v
-}
-
-func reverseCompat(call otto.FunctionCall) otto.Value {
-	if len(call.ArgumentList) != 1 {
-		throw(call.Otto, "REVCOMPAT takes exactly one argument")
-	}
-	dom := call.Argument(0).String()
-	err := rfc4183.SetCompatibilityMode(dom)
-	if err != nil {
-		throw(call.Otto, err.Error())
-	}
-	v, _ := otto.ToValue(nil)
-	return v
 }
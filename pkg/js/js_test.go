@@ -6,7 +6,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 	"unicode"
 
 	"github.com/StackExchange/dnscontrol/v4/pkg/normalize"
@@ -21,11 +24,29 @@ const (
 	errorDir = "pkg/js/error_tests"
 )
 
+// legacyUnsupported lists the parse tests that use features the legacy
+// engine (otto) doesn't have.
+var legacyUnsupported = map[string]bool{
+	"053-modules.js": true,
+}
+
 func init() {
 	os.Chdir("../..") // go up a directory so we helpers.js is in a consistent place.
 }
 
 func TestParsedFiles(t *testing.T) {
+	testParsedFiles(t)
+}
+
+// TestParsedFilesLegacy checks that the legacy engine (otto) gives the
+// same results. Remove it when --legacy-js is removed.
+func TestParsedFilesLegacy(t *testing.T) {
+	LegacyEngine = true
+	defer func() { LegacyEngine = false }()
+	testParsedFiles(t)
+}
+
+func testParsedFiles(t *testing.T) {
 	files, err := os.ReadDir(testDir)
 	if err != nil {
 		t.Fatal(err)
@@ -33,15 +54,23 @@ func TestParsedFiles(t *testing.T) {
 	for _, f := range files {
 		name := f.Name()
 
-		// run all js files that start with a number. Skip others.
-		if filepath.Ext(name) != ".js" || !unicode.IsNumber(rune(name[0])) {
+		// run all js and yaml files that start with a number. Skip others.
+		ext := filepath.Ext(name)
+		if (ext != ".js" && !IsYAML(name)) || !unicode.IsNumber(rune(name[0])) {
 			continue
 		}
 		t.Run(name, func(t *testing.T) {
+			if LegacyEngine && legacyUnsupported[name] {
+				t.Skip("not supported by the legacy engine")
+			}
 			var err error
 
-			// Compile the .js file:
-			conf, err := ExecuteJavaScript(string(filepath.Join(testDir, name)), true, nil)
+			// Compile the .js (or .yaml) file:
+			execute := ExecuteJavaScript
+			if IsYAML(name) {
+				execute = ExecuteYAML
+			}
+			conf, err := execute(string(filepath.Join(testDir, name)), true, nil)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -71,12 +100,26 @@ func TestParsedFiles(t *testing.T) {
 				}
 			}
 
+			// Locations are tested by TestSourceLocations. Leaving them out
+			// keeps the .json files readable.
+			for _, dc := range conf.Domains {
+				for _, r := range dc.Records {
+					r.Source = nil
+				}
+				for _, r := range dc.EnsureAbsent {
+					r.Source = nil
+				}
+				for i := range dc.RawRecords {
+					dc.RawRecords[i].Source = nil
+				}
+			}
+
 			// Test the JS compiled as expected (compare to the .json file)
 			actualJSON, err := json.MarshalIndent(conf, "", "  ")
 			if err != nil {
 				t.Fatal(err)
 			}
-			testName := name[:len(name)-3]
+			testName := strings.TrimSuffix(name, ext)
 			expectedFile := filepath.Join(testDir, testName+".json")
 			expectedJSON, err := os.ReadFile(expectedFile)
 			if err != nil {
@@ -151,3 +194,296 @@ func TestErrors(t *testing.T) {
 
 	}
 }
+
+func TestR53RoutingErrors(t *testing.T) {
+	tests := []struct{ desc, text, want string }{
+		{"no set identifier", `R53_WEIGHT("", 10)`, "requires a set identifier"},
+		{"bad weight", `R53_WEIGHT("a", 256)`, "is not a weight"},
+		{"bad failover", `R53_FAILOVER("a", "TERTIARY")`, "is not PRIMARY or SECONDARY"},
+		{"geo continent and country", `R53_GEO("a", {continent: "EU", country: "DE"})`, "either a continent or a country"},
+		{"geo subdivision only", `R53_GEO("a", {subdivision: "CA"})`, "either a continent or a country"},
+		{"two policies", `D("foo.com", "none", A("www", "1.2.3.4", R53_WEIGHT("a", 1), R53_FAILOVER("b", "PRIMARY")))`, "already has the routing policy of set a"},
+		{"on a domain", `D("foo.com", "none", R53_MULTIVALUE("a"))`, "can only be used on a record"},
+	}
+	for _, tst := range tests {
+		t.Run(tst.desc, func(t *testing.T) {
+			_, err := ExecuteJavascriptString([]byte(tst.text), true, nil)
+			if err == nil || !strings.Contains(err.Error(), tst.want) {
+				t.Fatalf("got error %v, want %q", err, tst.want)
+			}
+		})
+	}
+}
+
+func TestYAMLErrors(t *testing.T) {
+	const head = "domains:\n  - name: foo.com\n    registrar: reg\n    records:\n"
+	tests := []struct{ desc, text, want string }{
+		{"unknown key", "domain:\n  - name: foo.com\n", "field domain not found"},
+		{"no registrar", "domains:\n  - name: foo.com\n", "config.yaml:2: domain foo.com has no registrar"},
+		{"bad function", head + "      - \"A(1)\": [\"@\", \"1.2.3.4\"]\n", "config.yaml:5: \"A(1)\" is not a record type or modifier"},
+		{"require", head + "      - require: [\"/etc/passwd.json\"]\n", "config.yaml:5: \"require\" is not a record type or modifier"},
+		{"fetch", head + "      - A: [\"@\", \"1.2.3.4\"]\n        modifiers: [{fetch: \"http://localhost/\"}]\n", "config.yaml:6: \"fetch\" is not a record type or modifier"},
+		{"PANIC", "defaults:\n  - PANIC: boom\n", "config.yaml:2: \"PANIC\" is not a record type or modifier"},
+		{"scalar modifier", "defaults:\n  - D_EXTEND\n", "config.yaml:2: \"D_EXTEND\" is not a modifier"},
+		{"two types", head + "      - A: [\"@\", \"1.2.3.4\"]\n        MX: [\"@\", 10, \"mx.\"]\n", "config.yaml:6: record has both A and MX"},
+		{"no type", head + "      - ttl: 300\n", "config.yaml:5: record has no type"},
+		{"bad modifier", "defaults:\n  - [NO_PURGE]\n", "config.yaml:2: a modifier must be"},
+		{"inf", head + "      - A: [\"@\", \"1.2.3.4\"]\n        ttl: .inf\n", "config.yaml:6: json: unsupported value: +Inf"},
+		{"nan", head + "      - MX: [\"@\", .nan, \"mx.\"]\n", "config.yaml:5: json: unsupported value: NaN"},
+		{"non-string key", head + "      - A: [\"@\", \"1.2.3.4\", {true: x}]\n", "config.yaml:5: json: unsupported"},
+		{"non-string meta key", head + "      - A: [\"@\", \"1.2.3.4\"]\n        meta: {a: {true: x}}\n", "config.yaml:6: json: unsupported"},
+		{"runtime", head + "      - A: [\"@\", \"1.2.3.4\"]\n      - MX: [\"@\", \"mx.\"]\n", "config.yaml:6: "},
+	}
+	for _, tst := range tests {
+		t.Run(tst.desc, func(t *testing.T) {
+			script, err := yamlToJavaScript("config.yaml", []byte(tst.text))
+			if err == nil {
+				_, err = ExecuteJavascriptString([]byte(script), true, nil)
+			}
+			if err == nil {
+				t.Fatal("Expected error but found none")
+			}
+			if !strings.Contains(err.Error(), tst.want) {
+				t.Errorf("got error %q, want %q", err, tst.want)
+			}
+		})
+	}
+}
+
+// TestYAMLFunctions checks that the YAML front-end accepts every
+// documented record type and modifier.
+func TestYAMLFunctions(t *testing.T) {
+	for _, dir := range []string{"domain-modifiers", "record-modifiers"} {
+		files, err := filepath.Glob(filepath.Join("documentation/language-reference", dir, "*.md"))
+		if err != nil || len(files) == 0 {
+			t.Fatalf("no documentation in %s: %v", dir, err)
+		}
+		for _, f := range files {
+			if name := strings.TrimSuffix(filepath.Base(f), ".md"); !yamlFunctions[name] {
+				t.Errorf("%s is documented in %s but not in yamlFunctions", name, dir)
+			}
+		}
+	}
+}
+
+func TestYAMLSourceLocations(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "dnsconfig.yaml")
+	if err := os.WriteFile(file, []byte(`registrars:
+  - name: none
+    type: NONE
+domains:
+  - name: foo.com
+    registrar: none
+    records:
+      - A: [www, 1.2.3.4]
+
+      - MX: ["@", 10, mx.foo.com.]
+        ttl: 300
+  - name: bar.com
+    registrar: none
+    records: [{A: ["@", 1.2.3.4]}, {AAAA: ["@", "2001:db8::1"]}]
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	conf, err := ExecuteYAML(file, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, dc := range conf.Domains {
+		for _, r := range dc.Records {
+			got = append(got, r.Source.String())
+		}
+	}
+	yaml := filepath.ToSlash(file)
+	want := []string{yaml + ":8", yaml + ":10", yaml + ":14", yaml + ":14"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestModernSyntax(t *testing.T) {
+	script := `
+const ip = "1.2.3.4";
+let hosts = ["a", "b"];
+const mk = (h, {ttl = 300} = {}) => A(h, ip, TTL(ttl));
+class Zone {
+	constructor(name) { this.name = name; }
+	get label() { return ` + "`${this.name}`" + `; }
+}
+const [first, ...rest] = hosts;
+D(new Zone("foo.com").label, "none",
+	mk(first),
+	...rest.map(h => mk(h, {ttl: 600})),
+	TXT("@", ` + "`v=${hosts?.length ?? 0}`" + `),
+);
+`
+	conf, err := ExecuteJavascriptString([]byte(script), true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conf.Domains) != 1 || conf.Domains[0].Name != "foo.com" {
+		t.Fatalf("got domains %+v", conf.Domains)
+	}
+	recs := conf.Domains[0].Records
+	if len(recs) != 3 {
+		t.Fatalf("got %d records, want 3", len(recs))
+	}
+	if recs[1].TTL != 600 || recs[2].GetTargetField() != "v=2" {
+		t.Errorf("got %+v %+v", recs[1], recs[2])
+	}
+}
+
+func TestAsync(t *testing.T) {
+	tests := []struct {
+		desc, text string
+		wantErr    bool
+	}{
+		{"timers", `
+var order = [];
+setTimeout(() => { if (order.join("") !== "ai") throw new Error(order.join("")); }, 20);
+var i = setInterval(() => { order.push("i"); clearInterval(i); }, 1);
+setTimeout(() => order.push("never"), 5) && clearTimeout(3);
+order.push("a");
+`, false},
+		{"promise", `Promise.resolve("foo.com").then(n => D(n, "none"));`, false},
+		{"timer throws", `setTimeout(() => { throw new Error("boom"); }, 0);`, true},
+		{"unhandled rejection", `Promise.reject(new Error("boom"));`, true},
+		{"handled rejection", `Promise.reject(new Error("boom")).catch(() => {});`, false},
+	}
+	for _, tst := range tests {
+		t.Run(tst.desc, func(t *testing.T) {
+			_, err := ExecuteJavascriptString([]byte(tst.text), true, nil)
+			if (err != nil) != tst.wantErr {
+				t.Fatalf("got error %v, wantErr %v", err, tst.wantErr)
+			}
+		})
+	}
+}
+
+func TestIsModule(t *testing.T) {
+	for name, want := range map[string]bool{
+		"a.cjs":       true,
+		"lib/A.CJS":   true,
+		"a.js":        false,
+		"a.cjs.js":    false,
+		"modules.txt": false,
+	} {
+		if got := isModule(name); got != want {
+			t.Errorf("isModule(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestESModules(t *testing.T) {
+	dir := t.TempDir()
+	for name, src := range map[string]string{
+		"export.js":  "var x = 1;\nexport { x };\n",
+		"export.cjs": "export default 1;\n",
+		"syntax.js":  "var x = ;\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct{ text, want string }{
+		{`import { x } from "./lib.js";`, "ES modules are not supported"},
+		{`require(` + strconv.Quote(filepath.Join(dir, "export.js")) + `);`, "ES modules are not supported"},
+		{`require(` + strconv.Quote(filepath.Join(dir, "export.cjs")) + `);`, "ES modules are not supported"},
+		{`require(` + strconv.Quote(filepath.Join(dir, "syntax.js")) + `);`, "Unexpected token"},
+		{`var importance = ;`, "Unexpected token"},
+	}
+	for _, tst := range tests {
+		_, err := ExecuteJavaScriptSource("test.js", []byte(tst.text), true, nil)
+		if err == nil || !strings.Contains(err.Error(), tst.want) {
+			t.Errorf("%s: got error %v, want %q", tst.text, err, tst.want)
+		} else if tst.want == "Unexpected token" && strings.Contains(err.Error(), "ES modules") {
+			t.Errorf("%s: got error %v, which isn't about ES modules", tst.text, err)
+		}
+	}
+}
+
+func TestSandbox(t *testing.T) {
+	tests := []struct {
+		desc, text string
+		wantErr    string // Empty if no error is expected.
+	}{
+		{"ok", `D("foo.com", "none", A("@", "1.2.3.4"), require("./modules/web.cjs").web("www"))`, ""},
+		{"loop", `while (true) {}`, "took longer than"},
+		{"loop catch", `for (;;) { try { while (true) {} } catch (e) {} }`, "took longer than"},
+		{"records", `D("foo.com", "none", A("a", "1.2.3.4"), A("b", "1.2.3.4"), A("c", "1.2.3.4"), A("d", "1.2.3.4"))`, "exceeds the limit of 3"},
+		{"records loop", `for (var i = 0; ; i++) { try { D("d" + i + ".com", "none", A("@", "1.2.3.4")) } catch (e) {} }`, "4 records exceeds the limit of 3"},
+		{"records conf", `D("foo.com", "none"); for (var i = 0; i < 4; i++) { conf.domains[0].records.push({type: "A", name: "a" + i, target: "1.2.3.4"}) }`, "exceeds the limit of 3"},
+		{"require outside", `require("../js.go.json")`, "is outside of"},
+		{"require absolute", `require("/etc/hosts.json")`, "is outside of"},
+		{"glob outside", `glob("../")`, "is outside of"},
+		{"setTimeout", `setTimeout(() => {}, 0)`, "setTimeout() is disabled"},
+		{"fetch", `fetch("http://localhost/")`, "fetch() is disabled"},
+		{"PANIC", `PANIC("oops")`, "PANIC: oops"},
+		{"date", `if (new Date().getFullYear() !== 2000) throw new Error("not deterministic")`, ""},
+	}
+
+	Sandbox = SandboxConfig{Enabled: true, Timeout: 200 * time.Millisecond, MaxRecords: 3, Root: testDir}
+	EnableFetch = true
+	defer func() {
+		Sandbox = SandboxConfig{}
+		EnableFetch = false
+	}()
+	for _, tst := range tests {
+		t.Run(tst.desc, func(t *testing.T) {
+			currentDirectory = testDir
+			_, err := ExecuteJavascriptString([]byte(tst.text), true, nil)
+			if tst.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tst.wantErr) {
+				t.Fatalf("got error %v, want %q", err, tst.wantErr)
+			}
+		})
+	}
+}
+
+func TestSourceLocations(t *testing.T) {
+	dir := t.TempDir()
+	main := filepath.Join(dir, "dnsconfig.js")
+	lib := filepath.Join(dir, "lib", "records.js")
+	if err := os.Mkdir(filepath.Dir(lib), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(lib, []byte(`var records = [
+  CNAME("@", "www.foo.com.")
+];`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(main, []byte(`require("./lib/records.js");
+D("foo.com", "none",
+  A("www", "1.2.3.4"),
+  records
+);`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	conf, err := ExecuteJavaScript(main, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recs := conf.Domains[0].Records
+	if len(recs) != 2 {
+		t.Fatalf("got %d records, want 2", len(recs))
+	}
+	for i, want := range []string{filepath.ToSlash(main) + ":3", filepath.ToSlash(lib) + ":2"} {
+		if recs[i].Source == nil || recs[i].Source.String() != want {
+			t.Errorf("record %d is from %v, want %s", i, recs[i].Source, want)
+		}
+	}
+
+	errs := normalize.ValidateAndNormalizeConfig(conf)
+	if len(errs) != 1 || !strings.HasPrefix(errs[0].Error(), filepath.ToSlash(lib)+":2: ") {
+		t.Errorf("got errors %v, want one citing %s:2", errs, lib)
+	}
+}
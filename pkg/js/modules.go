@@ -0,0 +1,107 @@
+package js
+
+// This file implements CommonJS modules for require(). A file whose
+// name ends in .cjs is a module: it has its own scope, it is evaluated
+// only once, and require() returns its module.exports. Any other .js
+// file is run in the global scope every time it is required, as it
+// always has been.
+//
+// ES modules (import/export) are not supported by goja. Files that use
+// them get an error that says so.
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja/parser"
+)
+
+// isModule reports whether the file named name should be loaded as a
+// CommonJS module.
+func isModule(name string) bool {
+	return strings.EqualFold(filepath.Ext(name), ".cjs")
+}
+
+// checkESModule adds to err, the error of running src, that ES modules
+// are not supported if src has a syntax error at an import or export
+// statement.
+func checkESModule(src []byte, err error) error {
+	if err == nil {
+		return nil
+	}
+	// Parse src again to find the position of the syntax error, if any.
+	// The error of a module is in a wrapper, which changes its position.
+	_, perr := parser.ParseFile(nil, "", string(src), 0)
+	var list parser.ErrorList
+	if !errors.As(perr, &list) || len(list) == 0 {
+		return err
+	}
+	pos := list[0].Position
+	lines := strings.Split(string(src), "\n")
+	if pos.Line < 1 || pos.Line > len(lines) || pos.Column < 1 || pos.Column > len(lines[pos.Line-1]) {
+		return err
+	}
+	rest := lines[pos.Line-1][pos.Column-1:]
+	for _, keyword := range []string{"import", "export"} {
+		if after, ok := strings.CutPrefix(rest, keyword); ok && (after == "" || strings.ContainsAny(after[:1], " \t{*(")) {
+			return fmt.Errorf("%w (ES modules are not supported: use require() and module.exports in a .cjs file instead of %s)", err, keyword)
+		}
+	}
+	return err
+}
+
+// requireModule returns the module.exports of the module in the file
+// name. The module is evaluated the first time it is required; after
+// that the cached exports are returned.
+func (e *gojaEngine) requireModule(name string, src []byte) (goja.Value, error) {
+	key, err := filepath.Abs(name)
+	if err != nil {
+		return nil, err
+	}
+	if m, ok := e.modules[key]; ok {
+		return m.Get("exports"), nil
+	}
+
+	// Wrap the code in a function like Node.js does. The wrapper is on
+	// the first line so that line numbers in errors are unchanged.
+	prg, err := goja.Compile(name, "(function (exports, require, module, __filename, __dirname) {"+string(src)+"\n})", false)
+	if err != nil {
+		return nil, err
+	}
+	fnValue, err := e.vm.RunProgram(prg)
+	if err != nil {
+		return nil, err
+	}
+	fn, _ := goja.AssertFunction(fnValue)
+
+	exports := e.vm.NewObject()
+	module := e.vm.NewObject()
+	module.Set("exports", exports)
+	module.Set("id", key)
+	module.Set("filename", key)
+	module.Set("loaded", false)
+
+	// require() inside the module is relative to the module's
+	// directory, even if it is called after the module has loaded.
+	dir := filepath.Dir(name)
+	require := func(call goja.FunctionCall) goja.Value {
+		currentDirectoryOld := currentDirectory
+		currentDirectory = dir
+		defer func() { currentDirectory = currentDirectoryOld }()
+		return e.require(call)
+	}
+
+	// Cache the module before running it so that a circular require()
+	// gets the (partial) exports instead of looping forever.
+	e.modules[key] = module
+	if _, err := fn(exports, exports, e.vm.ToValue(require), module, e.vm.ToValue(key), e.vm.ToValue(filepath.Dir(key))); err != nil {
+		delete(e.modules, key)
+		return nil, err
+	}
+	module.Set("loaded", true)
+
+	return module.Get("exports"), nil
+}
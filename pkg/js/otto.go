@@ -0,0 +1,202 @@
+package js
+
+// This file implements the legacy ES5-only engine (otto). It is used
+// when --legacy-js is given and will be removed in a future release.
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/StackExchange/dnscontrol/v4/pkg/rfc4183"
+	"github.com/StackExchange/dnscontrol/v4/pkg/transform"
+	"github.com/robertkrimen/otto"              // load underscore js into vm by default
+	_ "github.com/robertkrimen/otto/underscore" // required by otto
+	"github.com/xddxdd/ottoext/fetch"
+	"github.com/xddxdd/ottoext/loop"
+	"github.com/xddxdd/ottoext/promise"
+	"github.com/xddxdd/ottoext/timers"
+)
+
+// runOtto runs helpers.js and script with otto and returns conf as JSON.
+func runOtto(script []byte, devMode bool, variables map[string]string) (string, error) {
+
+	vm := otto.New()
+	l := loop.New(vm)
+
+	if err := timers.Define(vm, l); err != nil {
+		return "", err
+	}
+	if err := promise.Define(vm, l); err != nil {
+		return "", err
+	}
+
+	// only define fetch() when explicitly enabled
+	if EnableFetch {
+		if err := fetch.Define(vm, l); err != nil {
+			return "", err
+		}
+	}
+
+	vm.Set("require", require)
+	vm.Set("REV", reverse)
+	vm.Set("REVCOMPAT", reverseCompat)
+	vm.Set("glob", listFiles) // used for require_glob()
+	vm.Set("PANIC", jsPanic)
+	// The legacy engine doesn't record where records were created.
+	vm.Set("__source_location", func(otto.FunctionCall) otto.Value { return otto.UndefinedValue() })
+	// The legacy engine doesn't support the sandbox.
+	vm.Set("__count_records", func(otto.FunctionCall) otto.Value { return otto.UndefinedValue() })
+
+	// add cli variables to otto
+	for key, value := range variables {
+		vm.Set(key, value)
+	}
+
+	helperJs := GetHelpers(devMode)
+	// run helper script to prime vm and initialize variables
+	if err := l.Eval(helperJs); err != nil {
+		return "", err
+	}
+
+	// run user script
+	if err := l.Eval(script); err != nil {
+		return "", err
+	}
+
+	// wait for event loop to finish
+	if err := l.Run(); err != nil {
+		return "", err
+	}
+
+	// export conf as string
+	value, err := vm.Run(`JSON.stringify(conf)`)
+	if err != nil {
+		return "", err
+	}
+	return value.ToString()
+}
+
+func require(call otto.FunctionCall) otto.Value {
+	if len(call.ArgumentList) != 1 {
+		throw(call.Otto, "require takes exactly one argument")
+	}
+	file := call.Argument(0).String() // The filename as given by the user
+
+	// Record the old currentDirectory so that we can return there.
+	currentDirectoryOld := currentDirectory
+
+	data, relFile, isJSON, err := requireFile(file)
+	if err != nil {
+		throw(call.Otto, err.Error())
+	}
+
+	var value = otto.TrueValue()
+
+	// If its a json file return the json value, else default to true
+	if isJSON {
+		cmd := fmt.Sprintf(`JSON.parse(JSON.stringify(%s))`, string(data))
+		value, err = call.Otto.Run(cmd)
+	} else {
+		_, err = call.Otto.Run(string(data))
+	}
+
+	if err != nil {
+		throw(call.Otto, fmt.Sprintf("File %s: %s", filepath.Base(relFile), err.Error()))
+	}
+
+	// Pop back to the old directory.
+	currentDirectory = currentDirectoryOld
+
+	return value
+}
+
+func listFiles(call otto.FunctionCall) otto.Value {
+	// Check amount of arguments provided
+	if !(len(call.ArgumentList) >= 1 && len(call.ArgumentList) <= 3) {
+		throw(call.Otto, errGlobUsage)
+	}
+
+	// Check if provided parameters are valid
+	// First: Let's check dir.
+	if !(call.Argument(0).IsDefined() && call.Argument(0).IsString() &&
+		len(call.Argument(0).String()) > 0) {
+		throw(call.Otto, errGlobDir)
+	}
+	dir := call.Argument(0).String() // Path where to start listing
+
+	// Second: Recursive?
+	var recursive = true
+	if call.Argument(1).IsDefined() && !call.Argument(1).IsNull() {
+		if call.Argument(1).IsBoolean() {
+			recursive, _ = call.Argument(1).ToBoolean() // If it should be recursive
+		} else {
+			throw(call.Otto, errGlobRecursive)
+		}
+	}
+
+	// Third: File extension filter.
+	var fileExtension = ".js"
+	if call.Argument(2).IsDefined() && !call.Argument(2).IsNull() {
+		if call.Argument(2).IsString() {
+			fileExtension = call.Argument(2).String() // Which file extension to filter for.
+		} else {
+			throw(call.Otto, errGlobExtension)
+		}
+	}
+
+	files, err := globFiles(dir, recursive, fileExtension)
+	if err != nil {
+		throw(call.Otto, err.Error())
+	}
+
+	// let's pass the data back to the JS engine.
+	value, err := call.Otto.ToValue(files)
+	if err != nil {
+		throw(call.Otto, fmt.Sprintf("converting value failed: %v", err.Error()))
+	}
+
+	return value
+}
+
+func jsPanic(call otto.FunctionCall) otto.Value {
+	if len(call.ArgumentList) != 1 {
+		throw(call.Otto, "PANIC takes exactly one argument")
+	}
+
+	message := call.Argument(0).String() // The filename as given by the user
+	panicExit(message)
+
+	// Won't be actually executed
+	v, _ := otto.ToValue(0)
+	return v
+}
+
+func throw(vm *otto.Otto, str string) {
+	panic(vm.MakeCustomError("Error", str))
+}
+
+func reverse(call otto.FunctionCall) otto.Value {
+	if len(call.ArgumentList) != 1 {
+		throw(call.Otto, "REV takes exactly one argument")
+	}
+	dom := call.Argument(0).String()
+	rev, err := transform.ReverseDomainName(dom)
+	if err != nil {
+		throw(call.Otto, err.Error())
+	}
+	v, _ := otto.ToValue(rev)
+	return v
+}
+
+func reverseCompat(call otto.FunctionCall) otto.Value {
+	if len(call.ArgumentList) != 1 {
+		throw(call.Otto, "REVCOMPAT takes exactly one argument")
+	}
+	dom := call.Argument(0).String()
+	err := rfc4183.SetCompatibilityMode(dom)
+	if err != nil {
+		throw(call.Otto, err.Error())
+	}
+	v, _ := otto.ToValue(nil)
+	return v
+}
@@ -0,0 +1 @@
+D("foo.com", "none", MAX_CHANGES(10), MAX_DELETE_PERCENT(12.5));
@@ -0,0 +1,5 @@
+D("foo.com", "none",
+    PROTECT("@", "MX"),
+    PROTECT("www", "A,AAAA", "10.*"),
+    A("www", "10.1.1.1")
+);
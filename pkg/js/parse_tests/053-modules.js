@@ -0,0 +1,12 @@
+var REG = NewRegistrar("Third-Party", "NONE");
+var CF = NewDnsProvider("Cloudflare", "CLOUDFLAREAPI");
+
+const { web } = require("./modules/web.cjs");
+const mail = require("./modules/mail.cjs");
+
+D("foo.com", REG, DnsProvider(CF),
+    web("www"),
+    mail.records("foo.com"),
+    TXT("loads", String(webLoads)),
+    TXT("isolated", typeof helper)
+);
@@ -0,0 +1,20 @@
+var REG = NewRegistrar("none", "NONE");
+var CF = NewDnsProvider("Cloudflare", "CLOUDFLAREAPI", {"manage_redirects": true});
+var BIND = NewDnsProvider("bind");
+
+DEFAULTS(DefaultTTL(600));
+
+D("foo.com", REG, DnsProvider(CF), DnsProvider(BIND, 0), {"owner": "web"},
+    NO_PURGE,
+    IGNORE("*", "A"),
+    A("@", "1.2.3.4"),
+    A("www", "1.2.3.4", TTL("1h"), CF_PROXY_ON),
+    MX("@", 10, "mx.foo.com."),
+    TXT("@", ["v=spf1 -all", "second"]),
+    CNAME("ftp", "www", {"note": "legacy"}),
+    CAA_BUILDER({label: "@", iodef: "mailto:admin@foo.com", issue: ["letsencrypt.org"]})
+);
+
+D("bar.com", REG, DnsProvider(BIND),
+    AAAA("@", "2001:db8::1")
+);
@@ -0,0 +1,13 @@
+D("foo.com", "none",
+  A("www", "1.2.3.4", R53_WEIGHT("blue", 90), R53_HEALTH_CHECK_ID("hc-blue")),
+  A("www", "1.2.3.5", R53_WEIGHT("green", 10)),
+  CNAME("api", "api-us.foo.com.", R53_LATENCY_REGION("us", "us-east-1")),
+  CNAME("api", "api-eu.foo.com.", R53_LATENCY_REGION("eu", "eu-west-1")),
+  A("app", "10.0.0.1", R53_FAILOVER("primary", "PRIMARY"), R53_HEALTH_CHECK_ID("hc-primary")),
+  R53_ALIAS("app", "A", "standby.foo.com.", R53_FAILOVER("secondary", "SECONDARY")),
+  A("geo", "10.1.0.1", R53_GEO("europe", {continent: "EU"})),
+  A("geo", "10.2.0.1", R53_GEO("california", {country: "US", subdivision: "CA"})),
+  A("geo", "10.3.0.1", R53_GEO("default", {country: "*"})),
+  A("mv", "10.4.0.1", R53_MULTIVALUE("one")),
+  A("mv", "10.4.0.2", R53_MULTIVALUE("two"))
+);
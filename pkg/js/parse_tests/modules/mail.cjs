@@ -0,0 +1,6 @@
+// require() is relative to this file, and returns the cached exports.
+const { TTL_SHORT } = require("./web.cjs");
+
+module.exports = {
+    records: (domain) => MX("@", 10, `mx.${domain}.`, TTL(TTL_SHORT)),
+};
@@ -0,0 +1,9 @@
+// helper is local to this module; it doesn't leak into the global scope.
+function helper(name, ip) {
+    return A(name, ip);
+}
+
+globalThis.webLoads = (globalThis.webLoads || 0) + 1;
+
+exports.TTL_SHORT = 60;
+exports.web = (name) => [helper(name, "1.2.3.4"), helper(name, "5.6.7.8")];
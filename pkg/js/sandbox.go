@@ -0,0 +1,120 @@
+package js
+
+import (
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+	"github.com/dop251/goja"
+)
+
+// SandboxConfig limits what dnsconfig.js may do. It is meant for
+// running configuration that isn't fully trusted, such as fragments
+// submitted by many teams to a central repository.
+type SandboxConfig struct {
+	Enabled bool
+
+	// Timeout stops the script if it runs longer than this. Zero means no limit.
+	Timeout time.Duration
+
+	// MaxRecords is the maximum number of records in all domains. Zero means no limit.
+	MaxRecords int
+
+	// Root is the directory that require() and glob() are confined
+	// to. It defaults to the directory of the configuration file.
+	Root string
+}
+
+// Sandbox is the sandbox configuration. It is set by the --sandbox flags.
+var Sandbox SandboxConfig
+
+// sandboxRoot is the absolute path of Sandbox.Root during a run.
+var sandboxRoot string
+
+// sandboxEpoch is the time returned by Date() in the sandbox.
+var sandboxEpoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// setupSandbox prepares vm for sandbox mode. The returned function must
+// be called when the run is over.
+func setupSandbox(vm *goja.Runtime) (func(), error) {
+	root := Sandbox.Root
+	if root == "" {
+		root = currentDirectory
+	}
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	if sandboxRoot, err = filepath.EvalSymlinks(root); err != nil {
+		return nil, err
+	}
+
+	// Make the output depend only on the input.
+	vm.SetRandSource(rand.New(rand.NewSource(1)).Float64)
+	vm.SetTimeSource(func() time.Time { return sandboxEpoch })
+
+	stop := func() {}
+	if Sandbox.Timeout > 0 {
+		t := time.AfterFunc(Sandbox.Timeout, func() {
+			vm.Interrupt(fmt.Errorf("sandbox: execution took longer than %v", Sandbox.Timeout))
+		})
+		stop = func() { t.Stop() }
+	}
+	return stop, nil
+}
+
+// checkSandboxPath returns an error if path is outside of the sandbox
+// root. Symbolic links are followed.
+func checkSandboxPath(path string) error {
+	if !Sandbox.Enabled {
+		return nil
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = resolved
+	}
+	rel, err := filepath.Rel(sandboxRoot, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("sandbox: %q is outside of %q", path, sandboxRoot)
+	}
+	return nil
+}
+
+// countRecords is called by helpers.js with the number of records it
+// adds to a domain. It stops the script as soon as there are more
+// records than the sandbox permits, so that a script can't use all
+// the memory before checkSandboxRecords runs.
+func (e *gojaEngine) countRecords(call goja.FunctionCall) goja.Value {
+	if !Sandbox.Enabled || Sandbox.MaxRecords <= 0 {
+		return goja.Undefined()
+	}
+	e.records += int(call.Argument(0).ToInteger())
+	if e.records > Sandbox.MaxRecords {
+		// Unlike an exception, an interrupt can't be caught by the script.
+		e.vm.Interrupt(fmt.Errorf("sandbox: %d records exceeds the limit of %d", e.records, Sandbox.MaxRecords))
+	}
+	return goja.Undefined()
+}
+
+// checkSandboxRecords returns an error if conf has more records than
+// the sandbox permits. It catches records that were added without
+// helpers.js, for example by changing conf directly.
+func checkSandboxRecords(conf *models.DNSConfig) error {
+	if !Sandbox.Enabled || Sandbox.MaxRecords <= 0 {
+		return nil
+	}
+	count := 0
+	for _, dc := range conf.Domains {
+		count += len(dc.Records)
+	}
+	if count > Sandbox.MaxRecords {
+		return fmt.Errorf("sandbox: %d records exceeds the limit of %d", count, Sandbox.MaxRecords)
+	}
+	return nil
+}
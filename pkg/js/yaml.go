@@ -0,0 +1,423 @@
+package js
+
+// This file implements the YAML front-end. A YAML document is
+// translated to calls to the functions in helpers.js (the same ones
+// dnsconfig.js uses), which are then run as usual. This guarantees that
+// both front-ends produce the same models.DNSConfig.
+//
+//	registrars:
+//	  - name: none
+//	    type: NONE
+//	providers:
+//	  - name: bind
+//	    type: BIND
+//	defaults:
+//	  - DefaultTTL: 600
+//	domains:
+//	  - name: example.com
+//	    registrar: none
+//	    providers: [bind]
+//	    modifiers:
+//	      - NO_PURGE
+//	      - IGNORE: ["*", "A"]
+//	    records:
+//	      - A: ["@", "1.2.3.4"]
+//	      - MX: ["@", 10, "mx.example.com."]
+//	        ttl: 300
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+	"gopkg.in/yaml.v3"
+)
+
+type yamlConfig struct {
+	Registrars []yamlProvider `yaml:"registrars"`
+	Providers  []yamlProvider `yaml:"providers"`
+	Defaults   []yaml.Node    `yaml:"defaults"`
+	Domains    []yaml.Node    `yaml:"domains"`
+}
+
+type yamlProvider struct {
+	Name string    `yaml:"name"`
+	Type string    `yaml:"type"`
+	Meta yaml.Node `yaml:"meta"`
+}
+
+type yamlDomain struct {
+	Name      string      `yaml:"name"`
+	Registrar string      `yaml:"registrar"`
+	Providers []yaml.Node `yaml:"providers"`
+	Meta      yaml.Node   `yaml:"meta"`
+	Modifiers []yaml.Node `yaml:"modifiers"`
+	Records   []yaml.Node `yaml:"records"`
+}
+
+type yamlDomainProvider struct {
+	Name        string `yaml:"name"`
+	Nameservers *int   `yaml:"nameservers"`
+}
+
+// IsYAML reports whether file should be read with ExecuteYAML instead
+// of ExecuteJavaScript.
+func IsYAML(file string) bool {
+	ext := strings.ToLower(filepath.Ext(file))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// ExecuteYAML accepts a YAML configuration file and runs it, returning the resulting dnsConfig.
+func ExecuteYAML(file string, devMode bool, variables map[string]string) (*models.DNSConfig, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	script, err := yamlToJavaScript(filepath.Base(file), data)
+	if err != nil {
+		return nil, err
+	}
+
+	// The records are on the same lines of the script as in the YAML
+	// file, so their source locations point into the YAML file.
+	return ExecuteJavaScriptSource(file, []byte(script), devMode, variables)
+}
+
+// yamlPrologue defines the function that adds the YAML file and line
+// number to errors. It must run with either engine (ES5). It is on one
+// line so that the script can keep the line numbers of the YAML file.
+const yamlPrologue = `function __yaml_at(where, f) { ` +
+	`try { return f(); } catch (e) { ` +
+	`if (e && e.yamlWhere) { throw e; } ` +
+	`var err = new Error(where + ': ' + (e && e.message ? e.message : e)); ` +
+	`err.yamlWhere = where; throw err; } }`
+
+// yamlFunctions are the records and modifiers that a YAML file may use.
+// Anything else in helpers.js or the engine, such as require(), fetch()
+// or PANIC(), is refused.
+var yamlFunctions = map[string]bool{}
+
+func init() {
+	for _, name := range strings.Fields(`
+		A AAAA AKAMAICDN ALIAS AZURE_ALIAS CAA CAA_BUILDER CF_REDIRECT
+		CF_SINGLE_REDIRECT CF_TEMP_REDIRECT CF_WORKER_ROUTE CLOUDNS_WR CNAME
+		DHCID DMARC_BUILDER DNAME DNSKEY DS FRAME HTTPS IMPORT_TRANSFORM LOC
+		LOC_BUILDER_DD LOC_BUILDER_DMM_STR LOC_BUILDER_DMS_STR LOC_BUILDER_STR
+		M365_BUILDER MX NAMESERVER NAPTR NS NS1_URLFWD PTR R53_ALIAS SOA
+		SPF_BUILDER SRV SSHFP SVCB TLSA TXT URL URL301
+
+		AUTODNSSEC_OFF AUTODNSSEC_ON DISABLE_IGNORE_SAFETY_CHECK DefaultTTL
+		DnsProvider IGNORE IGNORE_NAME IGNORE_TARGET INCLUDE MAX_CHANGES
+		MAX_DELETE_PERCENT NAMESERVER_TTL NO_PURGE PROTECT PURGE
+
+		R53_EVALUATE_TARGET_HEALTH R53_FAILOVER R53_GEO R53_HEALTH_CHECK_ID
+		R53_LATENCY_REGION R53_MULTIVALUE R53_WEIGHT R53_ZONE TTL
+
+		AUTOSPLIT CAA_CRITICAL CF_PROXY_DEFAULT_OFF CF_PROXY_DEFAULT_ON
+		CF_PROXY_FULL CF_PROXY_OFF CF_PROXY_ON CF_UNIVERSALSSL_OFF
+		CF_UNIVERSALSSL_ON IGNORE_NAME_DISABLE_SAFETY_CHECK
+	`) {
+		yamlFunctions[name] = true
+	}
+}
+
+// yamlToJavaScript translates the YAML document data to JavaScript.
+// name is used in error messages.
+func yamlToJavaScript(name string, data []byte) (string, error) {
+	var cfg yamlConfig
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil {
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+
+	y := &yamlWriter{name: name, line: 1}
+	y.WriteString(yamlPrologue)
+
+	for _, r := range cfg.Registrars {
+		call, err := y.providerCall("NewRegistrar", r)
+		if err != nil {
+			return "", err
+		}
+		y.WriteString(" " + call + ";")
+	}
+	for _, p := range cfg.Providers {
+		call, err := y.providerCall("NewDnsProvider", p)
+		if err != nil {
+			return "", err
+		}
+		y.WriteString(" " + call + ";")
+	}
+
+	if len(cfg.Defaults) != 0 {
+		mods, err := y.modifiers(cfg.Defaults)
+		if err != nil {
+			return "", err
+		}
+		y.WriteString(" DEFAULTS(" + strings.Join(mods, ", ") + ");")
+	}
+
+	for i := range cfg.Domains {
+		if err := y.domain(&cfg.Domains[i]); err != nil {
+			return "", err
+		}
+	}
+	return y.String(), nil
+}
+
+type yamlWriter struct {
+	strings.Builder
+	name string
+	line int // The line that is being written.
+}
+
+// newlines returns the newlines that move from the current line to
+// line, if it is further down, and counts them.
+func (y *yamlWriter) newlines(line int) string {
+	if line <= y.line {
+		return ""
+	}
+	n := line - y.line
+	y.line = line
+	return strings.Repeat("\n", n)
+}
+
+// at returns a JavaScript expression that evaluates expr, adding the
+// position of node to any error.
+func (y *yamlWriter) at(node *yaml.Node, expr string) string {
+	where := jsString(fmt.Sprintf("%s:%d", y.name, node.Line))
+	return fmt.Sprintf("__yaml_at(%s, function () { return %s; })", where, expr)
+}
+
+func (y *yamlWriter) errorf(node *yaml.Node, format string, args ...interface{}) error {
+	return fmt.Errorf("%s:%d: %s", y.name, node.Line, fmt.Sprintf(format, args...))
+}
+
+func (y *yamlWriter) providerCall(fn string, p yamlProvider) (string, error) {
+	args := []string{jsString(p.Name)}
+	typ := p.Type
+	if typ == "" {
+		typ = "-" // Look up the type in creds.json.
+	}
+	args = append(args, jsString(typ))
+	meta, err := y.meta(&p.Meta)
+	if err != nil {
+		return "", err
+	}
+	if meta != "" {
+		args = append(args, meta)
+	}
+	return fn + "(" + strings.Join(args, ", ") + ")", nil
+}
+
+// meta returns the metadata map in node as a JavaScript object, or ""
+// if there is none.
+func (y *yamlWriter) meta(node *yaml.Node) (string, error) {
+	if node.Kind == 0 {
+		return "", nil // Not in the YAML.
+	}
+	var meta map[string]interface{}
+	if err := node.Decode(&meta); err != nil {
+		return "", y.errorf(node, "%v", err)
+	}
+	if meta == nil {
+		return "", nil
+	}
+	return y.value(node, meta)
+}
+
+func (y *yamlWriter) domain(node *yaml.Node) error {
+	var d yamlDomain
+	if err := node.Decode(&d); err != nil {
+		return y.errorf(node, "%v", err)
+	}
+	if d.Name == "" {
+		return y.errorf(node, "domain has no name")
+	}
+	if d.Registrar == "" {
+		return y.errorf(node, "domain %s has no registrar", d.Name)
+	}
+
+	args := []string{jsString(d.Name), jsString(d.Registrar)}
+	for i := range d.Providers {
+		p := &d.Providers[i]
+		var dp yamlDomainProvider
+		if p.Kind == yaml.ScalarNode {
+			dp.Name = p.Value
+		} else if err := p.Decode(&dp); err != nil {
+			return y.errorf(p, "%v", err)
+		}
+		if dp.Nameservers != nil {
+			args = append(args, fmt.Sprintf("DnsProvider(%s, %d)", jsString(dp.Name), *dp.Nameservers))
+		} else {
+			args = append(args, fmt.Sprintf("DnsProvider(%s)", jsString(dp.Name)))
+		}
+	}
+	meta, err := y.meta(&d.Meta)
+	if err != nil {
+		return err
+	}
+	if meta != "" {
+		args = append(args, meta)
+	}
+
+	mods, err := y.modifiers(d.Modifiers)
+	if err != nil {
+		return err
+	}
+	args = append(args, mods...)
+
+	// Each record is on its line of the YAML file, so that the source
+	// location of the record is where it is in the YAML file.
+	y.WriteString(y.newlines(node.Line))
+	recs := []string{}
+	for i := range d.Records {
+		r, err := y.record(&d.Records[i])
+		if err != nil {
+			return err
+		}
+		recs = append(recs, y.newlines(d.Records[i].Line)+r)
+	}
+	args = append(args, "["+strings.Join(recs, ", ")+"]")
+
+	y.WriteString(y.at(node, "D("+strings.Join(args, ", ")+")") + ";")
+	return nil
+}
+
+// record translates a record, which is a map such as:
+//
+//	MX: ["@", 10, "mx.example.com."]
+//	ttl: 300
+//	meta: {foo: bar}
+//	modifiers: [CF_PROXY_ON]
+func (y *yamlWriter) record(node *yaml.Node) (string, error) {
+	if node.Kind != yaml.MappingNode {
+		return "", y.errorf(node, "a record must be a map such as {A: [\"@\", \"1.2.3.4\"]}")
+	}
+
+	var fn string
+	var args []string
+	var extra []string
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		switch key.Value {
+		case "ttl":
+			var ttl interface{}
+			if err := value.Decode(&ttl); err != nil {
+				return "", y.errorf(value, "%v", err)
+			}
+			v, err := y.value(value, ttl)
+			if err != nil {
+				return "", err
+			}
+			extra = append(extra, "TTL("+v+")")
+		case "meta":
+			meta, err := y.meta(value)
+			if err != nil {
+				return "", err
+			}
+			if meta != "" {
+				extra = append(extra, meta)
+			}
+		case "modifiers":
+			var mods []yaml.Node
+			if err := value.Decode(&mods); err != nil {
+				return "", y.errorf(value, "%v", err)
+			}
+			m, err := y.modifiers(mods)
+			if err != nil {
+				return "", err
+			}
+			extra = append(extra, m...)
+		default:
+			if fn != "" {
+				return "", y.errorf(key, "record has both %s and %s", fn, key.Value)
+			}
+			var err error
+			fn = key.Value
+			if args, err = y.args(fn, key, value); err != nil {
+				return "", err
+			}
+		}
+	}
+	if fn == "" {
+		return "", y.errorf(node, "record has no type")
+	}
+
+	return y.at(node, fn+"("+strings.Join(append(args, extra...), ", ")+")"), nil
+}
+
+// modifiers translates a list of modifiers. Each is either a name
+// (NO_PURGE) or a map from a name to its arguments ({IGNORE: ["*", "A"]}
+// or {DefaultTTL: 600}).
+func (y *yamlWriter) modifiers(nodes []yaml.Node) ([]string, error) {
+	var result []string
+	for i := range nodes {
+		node := &nodes[i]
+		switch {
+		case node.Kind == yaml.ScalarNode:
+			if !yamlFunctions[node.Value] {
+				return nil, y.errorf(node, "%q is not a modifier", node.Value)
+			}
+			result = append(result, node.Value)
+		case node.Kind == yaml.MappingNode && len(node.Content) == 2:
+			key, value := node.Content[0], node.Content[1]
+			args, err := y.args(key.Value, key, value)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, y.at(node, key.Value+"("+strings.Join(args, ", ")+")"))
+		default:
+			return nil, y.errorf(node, "a modifier must be a name or a map with one key")
+		}
+	}
+	return result, nil
+}
+
+// args returns the arguments for a call to fn. A list is the list of
+// arguments, anything else is the only argument.
+func (y *yamlWriter) args(fn string, key, value *yaml.Node) ([]string, error) {
+	if !yamlFunctions[fn] {
+		return nil, y.errorf(key, "%q is not a record type or modifier", fn)
+	}
+	var v interface{}
+	if err := value.Decode(&v); err != nil {
+		return nil, y.errorf(value, "%v", err)
+	}
+	list, ok := v.([]interface{})
+	if !ok {
+		if v == nil {
+			return nil, nil
+		}
+		list = []interface{}{v}
+	}
+	args := make([]string, len(list))
+	for i, a := range list {
+		var err error
+		if args[i], err = y.value(value, a); err != nil {
+			return nil, err
+		}
+	}
+	return args, nil
+}
+
+// value returns v, which was decoded from node, as a JavaScript
+// expression. JSON is valid JavaScript, but not every YAML value is
+// JSON: .inf, .nan and maps with keys that aren't strings aren't.
+func (y *yamlWriter) value(node *yaml.Node, v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", y.errorf(node, "%v", err)
+	}
+	return string(b), nil
+}
+
+// jsString returns s as a JavaScript string.
+func jsString(s string) string {
+	b, _ := json.Marshal(s) // A string can always be marshaled.
+	return string(b)
+}
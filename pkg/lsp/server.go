@@ -0,0 +1,303 @@
+// Package lsp implements a Language Server Protocol server for
+// dnsconfig.js. It reports the problems found by running the file as
+// diagnostics, completes the functions of the DSL and the names of the
+// providers in creds.json, and shows the documentation of functions on
+// hover.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+)
+
+// Problem is an error or warning found in a file.
+type Problem struct {
+	Line    int // 1-based. 0 if unknown.
+	Message string
+	Warning bool
+}
+
+// Server is a language server. It handles one client.
+type Server struct {
+	// Check runs file, whose contents are src, and returns the problems found.
+	Check func(file string, src []byte) []Problem
+	// Providers returns the names of the providers in creds.json.
+	Providers func() []string
+
+	symbols   []*Symbol
+	byName    map[string]*Symbol
+	documents map[string]string // The text of the open documents, by URI.
+	out       io.Writer
+	outErr    error // The first error writing to out.
+	shutdown  bool
+}
+
+// NewServer returns a server that offers the symbols documented in dts,
+// the TypeScript declarations written by write-types.
+func NewServer(dts string, check func(file string, src []byte) []Problem, providers func() []string) *Server {
+	s := &Server{
+		Check:     check,
+		Providers: providers,
+		symbols:   ParseDeclarations(dts),
+		byName:    map[string]*Symbol{},
+		documents: map[string]string{},
+	}
+	for _, sym := range s.symbols {
+		s.byName[sym.Name] = sym
+	}
+	return s
+}
+
+// errExit is returned by handle when the client asks the server to exit.
+var errExit = errors.New("exit")
+
+// Serve reads requests from in and writes responses to out until the
+// client sends "exit" or closes in.
+func (s *Server) Serve(in io.Reader, out io.Writer) error {
+	s.out = out
+	r := bufio.NewReader(in)
+	for {
+		body, err := readMessage(r)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			if err := s.reply(nil, nil, &responseError{codeParseError, err.Error()}); err != nil {
+				return err
+			}
+			continue
+		}
+		result, rerr := s.handle(&req)
+		if s.outErr != nil {
+			return s.outErr
+		}
+		if rerr == errExit {
+			if !s.shutdown {
+				return fmt.Errorf("exit before shutdown")
+			}
+			return nil
+		}
+		if req.ID == nil {
+			// Notifications have no reply, not even an error.
+			continue
+		}
+		var respErr *responseError
+		if rerr != nil && !errors.As(rerr, &respErr) {
+			respErr = &responseError{codeInvalidParams, rerr.Error()}
+		}
+		if err := s.reply(req.ID, result, respErr); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) reply(id *json.RawMessage, result interface{}, respErr *responseError) error {
+	resp := &response{JSONRPC: "2.0", ID: id, Error: respErr}
+	if respErr == nil {
+		b, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		resp.Result = b
+	}
+	return writeMessage(s.out, resp)
+}
+
+// notify sends a notification. Serve stops if it fails.
+func (s *Server) notify(method string, params interface{}) {
+	if s.outErr == nil {
+		s.outErr = writeMessage(s.out, &notification{JSONRPC: "2.0", Method: method, Params: params})
+	}
+}
+
+// handle runs the request and returns its result.
+func (s *Server) handle(req *request) (interface{}, error) {
+	switch req.Method {
+	case "initialize":
+		var result initializeResult
+		result.Capabilities.TextDocumentSync = textDocumentSyncFull
+		result.Capabilities.CompletionProvider.TriggerCharacters = []string{`"`, `'`}
+		result.Capabilities.HoverProvider = true
+		result.ServerInfo.Name = "dnscontrol"
+		return result, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "exit":
+		return nil, errExit
+
+	case "textDocument/didOpen":
+		var p didOpenParams
+		if err := json.Unmarshal(req.Params, &p); err != nil {
+			return nil, err
+		}
+		s.documents[p.TextDocument.URI] = p.TextDocument.Text
+		s.publishDiagnostics(p.TextDocument.URI)
+		return nil, nil
+	case "textDocument/didChange":
+		var p didChangeParams
+		if err := json.Unmarshal(req.Params, &p); err != nil {
+			return nil, err
+		}
+		if n := len(p.ContentChanges); n != 0 {
+			// With full sync, the last change is the whole document.
+			s.documents[p.TextDocument.URI] = p.ContentChanges[n-1].Text
+		}
+		s.publishDiagnostics(p.TextDocument.URI)
+		return nil, nil
+	case "textDocument/didSave":
+		var p didCloseParams
+		if err := json.Unmarshal(req.Params, &p); err != nil {
+			return nil, err
+		}
+		// Files that the document requires may have changed.
+		s.publishDiagnostics(p.TextDocument.URI)
+		return nil, nil
+	case "textDocument/didClose":
+		var p didCloseParams
+		if err := json.Unmarshal(req.Params, &p); err != nil {
+			return nil, err
+		}
+		delete(s.documents, p.TextDocument.URI)
+		s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{URI: p.TextDocument.URI, Diagnostics: []Diagnostic{}})
+		return nil, nil
+
+	case "textDocument/completion":
+		var p textDocumentPositionParams
+		if err := json.Unmarshal(req.Params, &p); err != nil {
+			return nil, err
+		}
+		return s.complete(s.linePrefix(p)), nil
+	case "textDocument/hover":
+		var p textDocumentPositionParams
+		if err := json.Unmarshal(req.Params, &p); err != nil {
+			return nil, err
+		}
+		return s.hover(p), nil
+	}
+
+	if req.ID != nil && !strings.HasPrefix(req.Method, "$/") {
+		return nil, &responseError{codeMethodNotFound, "method not found: " + req.Method}
+	}
+	return nil, nil
+}
+
+// publishDiagnostics checks the document and sends the problems found
+// to the client.
+func (s *Server) publishDiagnostics(uri string) {
+	text, ok := s.documents[uri]
+	if !ok {
+		return
+	}
+	lines := strings.Split(text, "\n")
+	diags := []Diagnostic{}
+	for _, p := range s.Check(uriToPath(uri), []byte(text)) {
+		d := Diagnostic{Severity: SeverityError, Source: "dnscontrol", Message: p.Message}
+		if p.Warning {
+			d.Severity = SeverityWarning
+		}
+		// Mark the whole line. Problems without a line go on the first.
+		if line := p.Line - 1; line >= 0 && line < len(lines) {
+			d.Range.Start.Line = line
+			d.Range.End.Line = line
+			d.Range.End.Character = len(utf16.Encode([]rune(strings.TrimRight(lines[line], "\r"))))
+		}
+		diags = append(diags, d)
+	}
+	s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{URI: uri, Diagnostics: diags})
+}
+
+// linePrefix returns the text of the line before the position.
+func (s *Server) linePrefix(p textDocumentPositionParams) string {
+	lines := strings.Split(s.documents[p.TextDocument.URI], "\n")
+	if p.Position.Line >= len(lines) {
+		return ""
+	}
+	line := utf16.Encode([]rune(lines[p.Position.Line]))
+	if p.Position.Character < len(line) {
+		line = line[:p.Position.Character]
+	}
+	return string(utf16.Decode(line))
+}
+
+// providerArgRe matches a line that ends in the first argument of
+// NewRegistrar() or NewDnsProvider(), which is a name from creds.json.
+var providerArgRe = regexp.MustCompile(`\b(NewRegistrar|NewDnsProvider)\(\s*["'][^"']*$`)
+
+// complete returns the completions after prefix.
+func (s *Server) complete(prefix string) []CompletionItem {
+	items := []CompletionItem{}
+	if providerArgRe.MatchString(prefix) {
+		if s.Providers != nil {
+			for _, name := range s.Providers() {
+				items = append(items, CompletionItem{Label: name, Kind: kindValue, Detail: "creds.json"})
+			}
+		}
+		return items
+	}
+	if strings.Count(prefix, `"`)%2 == 1 || strings.Count(prefix, `'`)%2 == 1 {
+		// In a string.
+		return items
+	}
+	for _, sym := range s.symbols {
+		item := CompletionItem{
+			Label:         sym.Name,
+			Kind:          kindConstant,
+			Detail:        sym.Declarations[0],
+			Documentation: &MarkupContent{Kind: "markdown", Value: sym.Doc},
+		}
+		if sym.IsFunction {
+			item.Kind = kindFunction
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// hover returns the documentation of the word at the position, or nil.
+func (s *Server) hover(p textDocumentPositionParams) *Hover {
+	lines := strings.Split(s.documents[p.TextDocument.URI], "\n")
+	if p.Position.Line >= len(lines) {
+		return nil
+	}
+	line := utf16.Encode([]rune(lines[p.Position.Line]))
+	isWord := func(c uint16) bool {
+		return c == '_' || c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z'
+	}
+	start, end := p.Position.Character, p.Position.Character
+	for start > 0 && start <= len(line) && isWord(line[start-1]) {
+		start--
+	}
+	for end < len(line) && isWord(line[end]) {
+		end++
+	}
+	if start >= end {
+		return nil
+	}
+	sym, ok := s.byName[string(utf16.Decode(line[start:end]))]
+	if !ok {
+		return nil
+	}
+	return &Hover{Contents: MarkupContent{Kind: "markdown", Value: sym.markdown()}}
+}
+
+// uriToPath returns the file name of a file:// URI.
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(u.Path)
+}
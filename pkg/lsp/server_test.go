@@ -0,0 +1,167 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const testDTS = `
+/**
+ * A adds an A record.
+ *
+ * @see https://docs.dnscontrol.org/language-reference/domain-modifiers/a
+ */
+declare function A(name: string, address: string | number, ...modifiers: RecordModifier[]): DomainModifier;
+
+interface RecordModifier {}
+
+/**
+ * Don't purge.
+ */
+declare const NO_PURGE: DomainModifier;
+`
+
+func TestParseDeclarations(t *testing.T) {
+	symbols := ParseDeclarations(testDTS)
+	if len(symbols) != 2 {
+		t.Fatalf("got %d symbols, want 2", len(symbols))
+	}
+	a, p := symbols[0], symbols[1]
+	if a.Name != "A" || !a.IsFunction || a.Doc != "A adds an A record.\n\n@see https://docs.dnscontrol.org/language-reference/domain-modifiers/a" {
+		t.Errorf("unexpected A: %+v", a)
+	}
+	if want := "function A(name: string, address: string | number, ...modifiers: RecordModifier[]): DomainModifier"; a.Declarations[0] != want {
+		t.Errorf("got declaration %q, want %q", a.Declarations[0], want)
+	}
+	if p.Name != "NO_PURGE" || p.IsFunction || p.Doc != "Don't purge." {
+		t.Errorf("unexpected NO_PURGE: %+v", p)
+	}
+}
+
+// session sends the messages to a server and returns what it wrote.
+func session(t *testing.T, s *Server, msgs ...string) []map[string]interface{} {
+	t.Helper()
+	var in bytes.Buffer
+	for _, m := range msgs {
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(m), m)
+	}
+	var out bytes.Buffer
+	if err := s.Serve(&in, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	var result []map[string]interface{}
+	r := bufio.NewReader(&out)
+	for {
+		body, err := readMessage(r)
+		if err != nil {
+			break
+		}
+		var m map[string]interface{}
+		if err := json.Unmarshal(body, &m); err != nil {
+			t.Fatal(err)
+		}
+		result = append(result, m)
+	}
+	return result
+}
+
+func TestServer(t *testing.T) {
+	const doc = `var REG = NewRegistrar("");
+D("example.com", REG,
+  A("www", "1.2.3.4")
+);`
+	check := func(file string, src []byte) []Problem {
+		if file != "/tmp/dnsconfig.js" || string(src) != doc {
+			t.Errorf("unexpected file %q: %s", file, src)
+		}
+		return []Problem{{Line: 3, Message: "bad record"}, {Message: "warning", Warning: true}}
+	}
+	providers := func() []string { return []string{"bind", "none"} }
+	s := NewServer(testDTS, check, providers)
+
+	text, _ := json.Marshal(doc)
+	out := session(t, s,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`,
+		`{"jsonrpc":"2.0","method":"initialized","params":{}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"file:///tmp/dnsconfig.js","text":`+string(text)+`}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"textDocument/hover","params":{"textDocument":{"uri":"file:///tmp/dnsconfig.js"},"position":{"line":2,"character":2}}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"textDocument/completion","params":{"textDocument":{"uri":"file:///tmp/dnsconfig.js"},"position":{"line":0,"character":24}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"textDocument/completion","params":{"textDocument":{"uri":"file:///tmp/dnsconfig.js"},"position":{"line":2,"character":2}}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"textDocument/definition","params":{}}`,
+		`{"jsonrpc":"2.0","id":6,"method":"shutdown"}`,
+		`{"jsonrpc":"2.0","method":"exit"}`,
+	)
+	if len(out) != 7 {
+		t.Fatalf("got %d messages, want 7: %v", len(out), out)
+	}
+	get := func(v interface{}, path ...interface{}) interface{} {
+		for _, p := range path {
+			switch p := p.(type) {
+			case string:
+				v = v.(map[string]interface{})[p]
+			case int:
+				v = v.([]interface{})[p]
+			}
+		}
+		return v
+	}
+
+	if got := get(out[0], "result", "capabilities", "hoverProvider"); got != true {
+		t.Errorf("hoverProvider = %v", got)
+	}
+
+	diags := get(out[1], "params", "diagnostics").([]interface{})
+	if len(diags) != 2 {
+		t.Fatalf("got %d diagnostics, want 2", len(diags))
+	}
+	if got := get(diags[0], "range", "start", "line"); got != 2.0 {
+		t.Errorf("diagnostic on line %v, want 2", got)
+	}
+	if got := get(diags[0], "range", "end", "character"); got != 21.0 {
+		t.Errorf("diagnostic ends at %v, want 21", got)
+	}
+	if got := get(diags[1], "severity"); got != float64(SeverityWarning) {
+		t.Errorf("severity = %v, want warning", got)
+	}
+
+	if got := get(out[2], "result", "contents", "value").(string); !strings.HasPrefix(got, "```typescript\nfunction A(") {
+		t.Errorf("unexpected hover %q", got)
+	}
+
+	if got := get(out[3], "result").([]interface{}); len(got) != 2 || get(got[0], "label") != "bind" {
+		t.Errorf("unexpected provider completions %v", got)
+	}
+	if got := get(out[4], "result").([]interface{}); len(got) != 2 || get(got[0], "label") != "A" {
+		t.Errorf("unexpected completions %v", got)
+	}
+
+	if got := get(out[5], "error", "code"); got != float64(codeMethodNotFound) {
+		t.Errorf("error code = %v, want %d", got, codeMethodNotFound)
+	}
+	if out[6]["result"] != nil || out[6]["error"] != nil {
+		t.Errorf("unexpected shutdown response %v", out[6])
+	}
+}
+
+func TestReadMessage(t *testing.T) {
+	for _, tt := range []struct{ in, want string }{
+		{"Content-Length: 2\r\n\r\n{}", ""},
+		{"Content-Length: x\r\n\r\n{}", `invalid Content-Length "x"`},
+		{"Content-Length: -1\r\n\r\n{}", `invalid Content-Length "-1"`},
+		{"Content-Length: 1000000000000\r\n\r\n{}", "exceeds the limit"},
+	} {
+		body, err := readMessage(bufio.NewReader(strings.NewReader(tt.in)))
+		if tt.want == "" {
+			if err != nil || string(body) != "{}" {
+				t.Errorf("%q: got %q %v", tt.in, body, err)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: got error %v, want %q", tt.in, err, tt.want)
+		}
+	}
+}
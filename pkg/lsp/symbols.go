@@ -0,0 +1,60 @@
+package lsp
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Symbol is a function or constant of the DSL.
+type Symbol struct {
+	Name         string
+	Declarations []string // The TypeScript declarations; functions may be overloaded.
+	Doc          string   // Markdown.
+	IsFunction   bool
+}
+
+// markdown returns the documentation of the symbol for hover.
+func (s *Symbol) markdown() string {
+	return "```typescript\n" + strings.Join(s.Declarations, "\n") + "\n```\n\n" + s.Doc
+}
+
+var declarationRe = regexp.MustCompile(`/\*\*((?:[^*]|\*[^/])*)\*/\s*(declare (function|const) (\w+)[^\n]*)`)
+
+// ParseDeclarations returns the symbols documented in dts, the
+// TypeScript declarations written by write-types.
+func ParseDeclarations(dts string) []*Symbol {
+	byName := map[string]*Symbol{}
+	for _, m := range declarationRe.FindAllStringSubmatch(dts, -1) {
+		name := m[4]
+		decl := strings.TrimSuffix(strings.TrimPrefix(m[2], "declare "), ";")
+		if s, ok := byName[name]; ok {
+			s.Declarations = append(s.Declarations, decl)
+			continue
+		}
+		byName[name] = &Symbol{
+			Name:         name,
+			Declarations: []string{decl},
+			Doc:          uncomment(m[1]),
+			IsFunction:   m[3] == "function",
+		}
+	}
+
+	symbols := make([]*Symbol, 0, len(byName))
+	for _, s := range byName {
+		symbols = append(symbols, s)
+	}
+	sort.Slice(symbols, func(i, j int) bool { return symbols[i].Name < symbols[j].Name })
+	return symbols
+}
+
+// uncomment removes the leading " * " of each line of a doc comment.
+func uncomment(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		l = strings.TrimLeft(l, " \t")
+		l = strings.TrimPrefix(l, "*")
+		lines[i] = strings.TrimPrefix(l, " ")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
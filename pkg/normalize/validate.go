@@ -307,6 +307,15 @@ type Warning struct {
 	error
 }
 
+// recordError prefixes err, which is about rec, with where rec was
+// created in dnsconfig.js. Warnings stay warnings.
+func recordError(rec *models.RecordConfig, err error) error {
+	if w, ok := err.(Warning); ok {
+		return Warning{rec.SourceError(w.error)}
+	}
+	return rec.SourceError(err)
+}
+
 // ValidateAndNormalizeConfig performs and normalization and/or validation of the IR.
 func ValidateAndNormalizeConfig(config *models.DNSConfig) (errs []error) {
 	err := processSplitHorizonDomains(config)
@@ -363,7 +372,7 @@ func ValidateAndNormalizeConfig(config *models.DNSConfig) (errs []error) {
 			}
 			// If label ends with dot, add to the list of errors.
 			if strings.HasSuffix(rec.GetLabel(), ".") {
-				errs = append(errs, fmt.Errorf("label %q does not match D(%q)", rec.GetLabel(), domain.Name))
+				errs = append(errs, rec.SourceError(fmt.Errorf("label %q does not match D(%q)", rec.GetLabel(), domain.Name)))
 				return errs // Exit early.
 			}
 
@@ -377,14 +386,14 @@ func ValidateAndNormalizeConfig(config *models.DNSConfig) (errs []error) {
 
 			// Validate the unmodified inputs:
 			if err := validateRecordTypes(rec, domain.Name, pTypes); err != nil {
-				errs = append(errs, err)
+				errs = append(errs, recordError(rec, err))
 			}
 			if err := checkLabel(rec.GetLabel(), rec.Type, domain.Name, rec.Metadata); err != nil {
-				errs = append(errs, err)
+				errs = append(errs, recordError(rec, err))
 			}
 
-			if errs2 := checkTargets(rec, domain.Name); errs2 != nil {
-				errs = append(errs, errs2...)
+			for _, err := range checkTargets(rec, domain.Name) {
+				errs = append(errs, recordError(rec, err))
 			}
 
 			// Canonicalize Targets.
@@ -404,25 +413,25 @@ func ValidateAndNormalizeConfig(config *models.DNSConfig) (errs []error) {
 				var err error
 				var name string
 				if name, err = transform.PtrNameMagic(rec.GetLabel(), domain.Name); err != nil {
-					errs = append(errs, err)
+					errs = append(errs, rec.SourceError(err))
 				}
 				rec.SetLabel(name, domain.Name)
 			} else if rec.Type == "CAA" {
 				if rec.CaaTag != "issue" && rec.CaaTag != "issuewild" && rec.CaaTag != "iodef" {
-					errs = append(errs, fmt.Errorf("CAA tag %s is invalid", rec.CaaTag))
+					errs = append(errs, rec.SourceError(fmt.Errorf("CAA tag %s is invalid", rec.CaaTag)))
 				}
 			} else if rec.Type == "TLSA" {
 				if rec.TlsaUsage > 3 {
-					errs = append(errs, fmt.Errorf("TLSA Usage %d is invalid in record %s (domain %s)",
-						rec.TlsaUsage, rec.GetLabel(), domain.Name))
+					errs = append(errs, rec.SourceError(fmt.Errorf("TLSA Usage %d is invalid in record %s (domain %s)",
+						rec.TlsaUsage, rec.GetLabel(), domain.Name)))
 				}
 				if rec.TlsaSelector > 1 {
-					errs = append(errs, fmt.Errorf("TLSA Selector %d is invalid in record %s (domain %s)",
-						rec.TlsaSelector, rec.GetLabel(), domain.Name))
+					errs = append(errs, rec.SourceError(fmt.Errorf("TLSA Selector %d is invalid in record %s (domain %s)",
+						rec.TlsaSelector, rec.GetLabel(), domain.Name)))
 				}
 				if rec.TlsaMatchingType > 2 {
-					errs = append(errs, fmt.Errorf("TLSA MatchingType %d is invalid in record %s (domain %s)",
-						rec.TlsaMatchingType, rec.GetLabel(), domain.Name))
+					errs = append(errs, rec.SourceError(fmt.Errorf("TLSA MatchingType %d is invalid in record %s (domain %s)",
+						rec.TlsaMatchingType, rec.GetLabel(), domain.Name)))
 				}
 			}
 
@@ -430,7 +439,7 @@ func ValidateAndNormalizeConfig(config *models.DNSConfig) (errs []error) {
 			rec.SetLabel(rec.GetLabel(), domain.Name)
 
 			if _, ok := rec.Metadata["ignore_name_disable_safety_check"]; ok {
-				errs = append(errs, fmt.Errorf("IGNORE_NAME_DISABLE_SAFETY_CHECK no longer supported. Please use DISABLE_IGNORE_SAFETY_CHECK for the entire domain"))
+				errs = append(errs, rec.SourceError(fmt.Errorf("IGNORE_NAME_DISABLE_SAFETY_CHECK no longer supported. Please use DISABLE_IGNORE_SAFETY_CHECK for the entire domain")))
 			}
 
 		}
@@ -447,17 +456,17 @@ func ValidateAndNormalizeConfig(config *models.DNSConfig) (errs []error) {
 			if rec.Type == "IMPORT_TRANSFORM" {
 				table, err := transform.DecodeTransformTable(rec.Metadata["transform_table"])
 				if err != nil {
-					errs = append(errs, err)
+					errs = append(errs, rec.SourceError(err))
 					continue
 				}
 				c := config.FindDomain(rec.GetTargetField())
 				if c == nil {
 					err = fmt.Errorf("IMPORT_TRANSFORM mentions non-existant domain %q", rec.GetTargetField())
-					errs = append(errs, err)
+					errs = append(errs, rec.SourceError(err))
 				}
 				err = importTransform(c, domain, table, rec.TTL)
 				if err != nil {
-					errs = append(errs, err)
+					errs = append(errs, rec.SourceError(err))
 				}
 			}
 		}
@@ -488,7 +497,7 @@ func ValidateAndNormalizeConfig(config *models.DNSConfig) (errs []error) {
 		// Validate FQDN consistency
 		for _, r := range d.Records {
 			if r.NameFQDN == "" || !strings.HasSuffix(r.NameFQDN, d.Name) {
-				errs = append(errs, fmt.Errorf("record named '%s' does not have correct FQDN for domain '%s'. FQDN: %s", r.Name, d.Name, r.NameFQDN))
+				errs = append(errs, r.SourceError(fmt.Errorf("record named '%s' does not have correct FQDN for domain '%s'. FQDN: %s", r.Name, d.Name, r.NameFQDN)))
 			}
 		}
 		// Verify AutoDNSSEC is valid.
@@ -556,17 +565,21 @@ func checkAutoDNSSEC(dc *models.DomainConfig) (errs []error) {
 
 func checkCNAMEs(dc *models.DomainConfig) (errs []error) {
 	cnames := map[string]bool{}
+	// Route53 serves one of several CNAMEs, each in its own record set.
+	sets := map[string]bool{}
 	for _, r := range dc.Records {
 		if r.Type == "CNAME" {
-			if cnames[r.GetLabel()] {
-				errs = append(errs, fmt.Errorf("cannot have multiple CNAMEs with same name: %s", r.GetLabelFQDN()))
+			set := r.GetLabel() + "/" + r.R53Routing[models.R53RoutingSetIdentifier]
+			if sets[set] {
+				errs = append(errs, r.SourceError(fmt.Errorf("cannot have multiple CNAMEs with same name: %s", r.GetLabelFQDN())))
 			}
+			sets[set] = true
 			cnames[r.GetLabel()] = true
 		}
 	}
 	for _, r := range dc.Records {
 		if cnames[r.GetLabel()] && r.Type != "CNAME" {
-			errs = append(errs, fmt.Errorf("cannot have CNAME and %s record with same name: %s", r.Type, r.GetLabelFQDN()))
+			errs = append(errs, r.SourceError(fmt.Errorf("cannot have CNAME and %s record with same name: %s", r.Type, r.GetLabelFQDN())))
 		}
 	}
 	return
@@ -576,8 +589,11 @@ func checkDuplicates(records []*models.RecordConfig) (errs []error) {
 	seen := map[string]*models.RecordConfig{}
 	for _, r := range records {
 		diffable := fmt.Sprintf("%s %s %s", r.GetLabelFQDN(), r.Type, r.ToComparableNoTTL())
+		if id := r.R53Routing[models.R53RoutingSetIdentifier]; id != "" {
+			diffable += " set_identifier=" + id
+		}
 		if seen[diffable] != nil {
-			errs = append(errs, fmt.Errorf("exact duplicate record found: %s", diffable))
+			errs = append(errs, r.SourceError(fmt.Errorf("exact duplicate record found: %s", diffable)))
 		}
 		seen[diffable] = r
 	}
@@ -692,6 +708,7 @@ var providerCapabilityChecks = []pairTypeCapability{
 	capabilityCheck("NAPTR", providers.CanUseNAPTR),
 	capabilityCheck("PTR", providers.CanUsePTR),
 	capabilityCheck("R53_ALIAS", providers.CanUseRoute53Alias),
+	capabilityCheck("R53_ROUTING", providers.CanUseRoute53Routing),
 	capabilityCheck("SOA", providers.CanUseSOA),
 	capabilityCheck("SRV", providers.CanUseSRV),
 	capabilityCheck("SSHFP", providers.CanUseSSHFP),
@@ -767,6 +784,13 @@ func checkProviderCapabilities(dc *models.DomainConfig) error {
 			if dc.AutoDNSSEC != "" {
 				hasAny = true
 			}
+		case "R53_ROUTING":
+			for _, r := range dc.Records {
+				if len(r.R53Routing) != 0 {
+					hasAny = true
+					break
+				}
+			}
 		default:
 			for _, r := range dc.Records {
 				if r.Type == ty.rType {
@@ -525,6 +525,32 @@ func Test_DSChecks(t *testing.T) {
 	})
 }
 
+func TestR53RoutingCapability(t *testing.T) {
+	weighted := func(id string) *models.RecordConfig {
+		rc := &models.RecordConfig{Type: "CNAME", R53Routing: map[string]string{
+			models.R53RoutingSetIdentifier: id,
+			models.R53RoutingWeight:        "50",
+		}}
+		rc.SetLabel("www", "example.com")
+		rc.SetTarget(id + ".example.net.")
+		return rc
+	}
+	dc := &models.DomainConfig{
+		Name:    "example.com",
+		Records: models.Records{weighted("a"), weighted("b")},
+		DNSProviderInstances: []*models.DNSProviderInstance{
+			{ProviderBase: models.ProviderBase{Name: "p", ProviderType: ProviderNoDS}},
+		},
+	}
+	if errs := checkCNAMEs(dc); len(errs) != 0 {
+		t.Errorf("checkCNAMEs: %v", errs)
+	}
+	// The routing policies only make sense to Route53.
+	if err := checkProviderCapabilities(dc); err == nil {
+		t.Errorf("Provider %s does not support Route53 routing policies, so should have failed the check", ProviderNoDS)
+	}
+}
+
 func Test_errorRepeat(t *testing.T) {
 	type args struct {
 		label  string
@@ -7,6 +7,7 @@ import (
 )
 
 func init() {
+	configKeys = append(configKeys, "bonfire_url")
 	initers = append(initers, func(cfg map[string]string) Notifier {
 		if url, ok := cfg["bonfire_url"]; ok {
 			return bonfireNotifier(url)
@@ -27,6 +28,10 @@ func (b bonfireNotifier) Notify(domain, provider, msg string, err error, preview
 	} else {
 		payload = fmt.Sprintf(`Successfully ran correction for **%s[%s]** - %s`, domain, provider, msg)
 	}
+	b.notifyText(domain, provider, payload, err, preview)
+}
+
+func (b bonfireNotifier) notifyText(domain, provider, payload string, err error, preview bool) {
 	// chat doesn't markdownify multiline messages. Split in two so the first line can have markdown
 	parts := strings.SplitN(payload, "\n", 2)
 	for _, p := range parts {
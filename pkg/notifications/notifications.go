@@ -1,6 +1,15 @@
 package notifications
 
-import "regexp"
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/gobwas/glob"
+)
 
 // Notifier is a type that can send a notification
 type Notifier interface {
@@ -13,23 +22,214 @@ type Notifier interface {
 	Done()
 }
 
+// textNotifier is implemented by notifiers that can send a message
+// that was already formatted by a template.
+type textNotifier interface {
+	Notifier
+	// notifyText is like Notify but sends text verbatim instead of
+	// formatting a message.
+	notifyText(domain, provider string, text string, err error, preview bool)
+}
+
 // new notification types should add themselves to this array
 var initers = []func(map[string]string) Notifier{}
 
+// configKeys are the config keys that are read by the initers and the
+// routes. New notification types should add their keys to it. A key
+// that ends in "*" allows all keys with that prefix.
+var configKeys = []string{"domains", "providers", "template"}
+
 // matches ansi color codes
 var ansiColorRegex = regexp.MustCompile(`\x1b\[[0-9;]*m`)
 
 // Init will take the given config map (from creds.json notifications key) and create a single Notifier with
 // all notifications it has full config for.
-func Init(config map[string]string) Notifier {
+//
+// Keys of the form "ROUTE.key" configure an additional route named
+// ROUTE. A route has its own destinations (for example
+// "payments.slack_url") and only receives the notifications that match
+// its "ROUTE.domains" (comma-separated globs) and "ROUTE.providers"
+// (comma-separated provider names). Keys without a prefix configure the
+// default route, which receives all notifications.
+//
+// The "template" key (or "ROUTE.template") is a Go text/template that
+// replaces the default message format. See Message for the fields
+// available.
+func Init(config map[string]string) (Notifier, error) {
 	notifiers := multiNotifier{}
-	for _, i := range initers {
-		n := i(config)
-		if n != nil {
+	routes, err := parseRoutes(config)
+	if err != nil {
+		return notifiers, err
+	}
+	for _, r := range routes {
+		for _, i := range initers {
+			n := i(r.config)
+			if n == nil {
+				continue
+			}
+			if r.template != nil {
+				tn, ok := n.(textNotifier)
+				if !ok {
+					return notifiers, fmt.Errorf("notifications: route %q: %T does not support templates", r.name, n)
+				}
+				n = &templateNotifier{template: r.template, next: tn}
+			}
+			if r.domains != nil || r.providers != nil {
+				n = &routedNotifier{domains: r.domains, providers: r.providers, next: n}
+			}
 			notifiers = append(notifiers, n)
 		}
 	}
-	return notifiers
+	return notifiers, nil
+}
+
+// route is a set of destinations and the rules that select which
+// notifications they receive.
+type route struct {
+	name      string            // "" for the default route.
+	config    map[string]string // The config with the "name." prefix removed.
+	domains   []glob.Glob       // nil means "all domains".
+	providers map[string]bool   // nil means "all providers".
+	template  *template.Template
+}
+
+func parseRoutes(config map[string]string) ([]*route, error) {
+	byName := map[string]*route{}
+	for k, v := range config {
+		name, key, ok := strings.Cut(k, ".")
+		if !ok {
+			name, key = "", k
+		}
+		if !isConfigKey(key) {
+			return nil, fmt.Errorf("notifications: unknown key %q", k)
+		}
+		r, ok := byName[name]
+		if !ok {
+			r = &route{name: name, config: map[string]string{}}
+			byName[name] = r
+		}
+		r.config[key] = v
+	}
+
+	var routes []*route
+	for _, r := range byName {
+		if err := r.compile(); err != nil {
+			return nil, err
+		}
+		routes = append(routes, r)
+	}
+	// Sort for reproducibility. The default route is first.
+	sort.Slice(routes, func(i, j int) bool { return routes[i].name < routes[j].name })
+	return routes, nil
+}
+
+// isConfigKey reports whether key is one of configKeys.
+func isConfigKey(key string) bool {
+	for _, k := range configKeys {
+		if prefix, ok := strings.CutSuffix(k, "*"); ok {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		} else if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *route) compile() error {
+	if s, ok := r.config["domains"]; ok {
+		r.domains = []glob.Glob{}
+		for _, pattern := range strings.Split(s, ",") {
+			pattern = strings.TrimSpace(pattern)
+			if pattern == "" {
+				continue
+			}
+			g, err := glob.Compile(pattern)
+			if err != nil {
+				return fmt.Errorf("notifications: route %q: invalid domains glob %q: %w", r.name, pattern, err)
+			}
+			r.domains = append(r.domains, g)
+		}
+	}
+	if s, ok := r.config["providers"]; ok {
+		r.providers = map[string]bool{}
+		for _, p := range strings.Split(s, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				r.providers[p] = true
+			}
+		}
+	}
+	if s, ok := r.config["template"]; ok {
+		t, err := template.New(r.name).Parse(s)
+		if err != nil {
+			return fmt.Errorf("notifications: route %q: invalid template: %w", r.name, err)
+		}
+		r.template = t
+	}
+	return nil
+}
+
+// Message is the data passed to notification templates.
+type Message struct {
+	Domain   string
+	Provider string
+	Message  string // The correction's message.
+	Error    error  // The result of running the correction.
+	Preview  bool   // True if the correction was not run.
+}
+
+// templateNotifier formats messages with a template before passing them on.
+type templateNotifier struct {
+	template *template.Template
+	next     textNotifier
+}
+
+func (t *templateNotifier) Notify(domain, provider string, message string, err error, preview bool) {
+	var b bytes.Buffer
+	m := Message{Domain: domain, Provider: provider, Message: message, Error: err, Preview: preview}
+	if terr := t.template.Execute(&b, m); terr != nil {
+		// Better to send the message in the default format than to lose it.
+		t.next.Notify(domain, provider, message, err, preview)
+		return
+	}
+	t.next.notifyText(domain, provider, b.String(), err, preview)
+}
+
+func (t *templateNotifier) Done() {
+	t.next.Done()
+}
+
+// routedNotifier only passes on the notifications that match its rules.
+type routedNotifier struct {
+	domains   []glob.Glob
+	providers map[string]bool
+	next      Notifier
+}
+
+func (r *routedNotifier) match(domain, provider string) bool {
+	if r.providers != nil && !r.providers[provider] {
+		return false
+	}
+	if r.domains == nil {
+		return true
+	}
+	for _, g := range r.domains {
+		if g.Match(domain) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *routedNotifier) Notify(domain, provider string, message string, err error, preview bool) {
+	if r.match(domain, provider) {
+		r.next.Notify(domain, provider, message, err, preview)
+	}
+}
+
+func (r *routedNotifier) Done() {
+	r.next.Done()
 }
 
 type multiNotifier []Notifier
@@ -1,6 +1,10 @@
 package notifications
 
-import "testing"
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
 
 func Test_stripAnsiColorsValid(t *testing.T) {
 
@@ -23,3 +27,79 @@ func Test_stripAnsiColorsInvalid(t *testing.T) {
 		t.Errorf("stripAnsiColors() stripped %q should be different from %q", coloredStr, nonColoredStr)
 	}
 }
+
+// recordingNotifier records the text of each notification.
+type recordingNotifier struct {
+	id   string
+	sent *[]string
+}
+
+func (r *recordingNotifier) Notify(domain, provider, msg string, err error, preview bool) {
+	r.notifyText(domain, provider, "default: "+msg, err, preview)
+}
+
+func (r *recordingNotifier) notifyText(domain, provider, text string, err error, preview bool) {
+	*r.sent = append(*r.sent, r.id+" "+text)
+}
+
+func (r *recordingNotifier) Done() {}
+
+func Test_InitRoutes(t *testing.T) {
+	var sent []string
+	old, oldKeys := initers, configKeys
+	defer func() { initers, configKeys = old, oldKeys }()
+	configKeys = append(configKeys, "test_id")
+	initers = []func(map[string]string) Notifier{func(cfg map[string]string) Notifier {
+		if id, ok := cfg["test_id"]; ok {
+			return &recordingNotifier{id: id, sent: &sent}
+		}
+		return nil
+	}}
+
+	n, err := Init(map[string]string{
+		"test_id":           "all",
+		"payments.test_id":  "payments",
+		"payments.domains":  "payments.example.com, *.payments.example.com",
+		"payments.template": `{{if .Preview}}PREVIEW {{end}}{{.Domain}}/{{.Provider}}: {{.Message}}{{with .Error}} ({{.}}){{end}}`,
+		"r53only.test_id":   "r53only",
+		"r53only.providers": "r53",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n.Notify("payments.example.com", "bind", "\x1b[32m+ CREATE www\x1b[0m", nil, true)
+	n.Notify("eu.payments.example.com", "r53", "- DELETE mx", errors.New("boom"), false)
+	n.Notify("example.com", "bind", "± MODIFY www", nil, false)
+	n.Done()
+
+	want := []string{
+		"all default: + CREATE www",
+		"payments PREVIEW payments.example.com/bind: + CREATE www",
+		"all default: - DELETE mx",
+		"payments eu.payments.example.com/r53: - DELETE mx (boom)",
+		"r53only default: - DELETE mx",
+		"all default: ± MODIFY www",
+	}
+	if !reflect.DeepEqual(sent, want) {
+		t.Errorf("Init() sent:\n%q\nwant:\n%q", sent, want)
+	}
+}
+
+func Test_InitRoutesErrors(t *testing.T) {
+	if _, err := Init(map[string]string{"x.template": "{{.Domain"}); err == nil {
+		t.Error("Init() expected error for bad template")
+	}
+	if _, err := Init(map[string]string{"x.domains": "[a-"}); err == nil {
+		t.Error("Init() expected error for bad glob")
+	}
+	if _, err := Init(map[string]string{"payments.slack_url": "https://example.com", "payments.domain": "example.com"}); err == nil {
+		t.Error("Init() expected error for unknown route key")
+	}
+	if _, err := Init(map[string]string{"slack_ulr": "https://example.com"}); err == nil {
+		t.Error("Init() expected error for unknown key")
+	}
+	if _, err := Init(map[string]string{"webhook_url": "https://example.com", "webhook_header_X-Team": "dns"}); err != nil {
+		t.Errorf("Init() unexpected error for webhook header: %v", err)
+	}
+}
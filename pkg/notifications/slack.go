@@ -8,6 +8,7 @@ import (
 )
 
 func init() {
+	configKeys = append(configKeys, "slack_url")
 	initers = append(initers, func(cfg map[string]string) Notifier {
 		if url, ok := cfg["slack_url"]; ok {
 			notifier := &slackNotifier{
@@ -25,19 +26,24 @@ type slackNotifier struct {
 }
 
 func (s *slackNotifier) Notify(domain, provider, msg string, err error, preview bool) {
+	var text string
+	if preview {
+		text = fmt.Sprintf(`**Preview: %s[%s] -** %s`, domain, provider, msg)
+	} else if err != nil {
+		text = fmt.Sprintf(`**ERROR running correction on %s[%s] -** (%s) Error: %s`, domain, provider, msg, err)
+	} else {
+		text = fmt.Sprintf(`Successfully ran correction for **%s[%s]** - %s`, domain, provider, msg)
+	}
+	s.notifyText(domain, provider, text, err, preview)
+}
+
+func (s *slackNotifier) notifyText(domain, provider, text string, err error, preview bool) {
 	var payload struct {
 		Username string `json:"username"`
 		Text     string `json:"text"`
 	}
 	payload.Username = "DNSControl"
-
-	if preview {
-		payload.Text = fmt.Sprintf(`**Preview: %s[%s] -** %s`, domain, provider, msg)
-	} else if err != nil {
-		payload.Text = fmt.Sprintf(`**ERROR running correction on %s[%s] -** (%s) Error: %s`, domain, provider, msg, err)
-	} else {
-		payload.Text = fmt.Sprintf(`Successfully ran correction for **%s[%s]** - %s`, domain, provider, msg)
-	}
+	payload.Text = text
 
 	json, _ := json.Marshal(payload)
 	http.Post(s.URL, "text/json", bytes.NewReader(json))
@@ -0,0 +1,121 @@
+package notifications
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/StackExchange/dnscontrol/v4/pkg/printer"
+)
+
+func init() {
+	configKeys = append(configKeys, "smtp_host", "smtp_port", "smtp_username", "smtp_password", "smtp_from", "smtp_to", "smtp_subject")
+	initers = append(initers, func(cfg map[string]string) Notifier {
+		host, ok := cfg["smtp_host"]
+		if !ok {
+			return nil
+		}
+		to, ok := cfg["smtp_to"]
+		if !ok {
+			return nil
+		}
+
+		notifier := &smtpNotifier{
+			Host:     host,
+			Port:     cfg["smtp_port"],
+			Username: cfg["smtp_username"],
+			Password: cfg["smtp_password"],
+			From:     cfg["smtp_from"],
+			Subject:  cfg["smtp_subject"],
+		}
+		for _, addr := range strings.Split(to, ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				notifier.To = append(notifier.To, addr)
+			}
+		}
+		if notifier.Port == "" {
+			notifier.Port = "25"
+		}
+		if notifier.From == "" {
+			notifier.From = "dnscontrol@localhost"
+		}
+		if notifier.Subject == "" {
+			notifier.Subject = "DNSControl changes"
+		}
+		return notifier
+	})
+}
+
+// smtpNotifier collects all notifications and sends them as a single
+// email when Done() is called.
+type smtpNotifier struct {
+	Host     string
+	Port     string
+	Username string // If set, authenticate with PLAIN auth.
+	Password string
+	From     string
+	To       []string
+	Subject  string
+
+	mu    sync.Mutex
+	lines []string
+}
+
+func (s *smtpNotifier) Notify(domain, provider, msg string, err error, preview bool) {
+	var line string
+	if preview {
+		line = fmt.Sprintf("Preview: %s[%s] - %s", domain, provider, msg)
+	} else if err != nil {
+		line = fmt.Sprintf("ERROR running correction on %s[%s] - (%s) Error: %s", domain, provider, msg, err)
+	} else {
+		line = fmt.Sprintf("Successfully ran correction for %s[%s] - %s", domain, provider, msg)
+	}
+	s.notifyText(domain, provider, line, err, preview)
+}
+
+func (s *smtpNotifier) notifyText(domain, provider, line string, err error, preview bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lines = append(s.lines, line)
+}
+
+func (s *smtpNotifier) Done() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Don't send empty emails.
+	if len(s.lines) == 0 {
+		return
+	}
+
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+	addr := net.JoinHostPort(s.Host, s.Port)
+	if err := smtp.SendMail(addr, auth, s.From, s.To, s.message()); err != nil {
+		printer.Warnf("SMTP notification to %s via %s failed: %s\n", strings.Join(s.To, ", "), addr, err)
+	}
+	s.lines = nil
+}
+
+// message returns the email (headers and body) to be sent.
+func (s *smtpNotifier) message() []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", s.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(s.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", s.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&b, "Content-Type: text/plain; charset=UTF-8\r\n")
+	fmt.Fprintf(&b, "\r\n")
+	for _, l := range s.lines {
+		// SMTP requires CRLF line endings.
+		fmt.Fprintf(&b, "%s\r\n\r\n", strings.ReplaceAll(l, "\n", "\r\n"))
+	}
+	return b.Bytes()
+}
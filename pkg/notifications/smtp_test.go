@@ -0,0 +1,93 @@
+package notifications
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+)
+
+// fakeSMTPServer accepts one SMTP session and sends the DATA it
+// received to the returned channel.
+func fakeSMTPServer(t *testing.T) (addr string, data <-chan string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch := make(chan string, 1)
+	go func() {
+		defer l.Close()
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		w := func(s string) { conn.Write([]byte(s + "\r\n")) }
+
+		w("220 localhost ESMTP")
+		var body strings.Builder
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				w("250 localhost")
+			case strings.HasPrefix(cmd, "DATA"):
+				w("354 go ahead")
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if l == ".\r\n" {
+						break
+					}
+					body.WriteString(l)
+				}
+				ch <- body.String()
+				w("250 ok")
+			case strings.HasPrefix(cmd, "QUIT"):
+				w("221 bye")
+				return
+			default:
+				w("250 ok")
+			}
+		}
+	}()
+	return l.Addr().String(), ch
+}
+
+func Test_smtpNotifier(t *testing.T) {
+	addr, data := fakeSMTPServer(t)
+	host, port, _ := net.SplitHostPort(addr)
+
+	n, err := Init(map[string]string{
+		"smtp_host": host,
+		"smtp_port": port,
+		"smtp_from": "dns@example.com",
+		"smtp_to":   "a@example.com, b@example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.Notify("example.com", "bind", "+ CREATE www.example.com A 1.2.3.4", nil, false)
+	n.Notify("example.org", "r53", "- DELETE mail.example.org MX 10 mx.", nil, true)
+	n.Done()
+
+	got := <-data
+	for _, want := range []string{
+		"From: dns@example.com\r\n",
+		"To: a@example.com, b@example.com\r\n",
+		"Subject: DNSControl changes\r\n",
+		"Successfully ran correction for example.com[bind] - + CREATE www.example.com A 1.2.3.4\r\n",
+		"Preview: example.org[r53] - - DELETE mail.example.org MX 10 mx.\r\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("email is missing %q:\n%s", want, got)
+		}
+	}
+}
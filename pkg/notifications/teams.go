@@ -9,6 +9,7 @@ import (
 )
 
 func init() {
+	configKeys = append(configKeys, "teams_url")
 	initers = append(initers, func(cfg map[string]string) Notifier {
 		url, ok := cfg["teams_url"]
 		if !ok {
@@ -28,22 +29,27 @@ type teamsNotifier struct {
 }
 
 func (s *teamsNotifier) Notify(domain, provider, msg string, err error, preview bool) {
-	var payload struct {
-		Username string `json:"username"`
-		Text     string `json:"text"`
-	}
-	payload.Username = "DnsControl"
-
 	// Format changes as 'preformated' text
 	msg = strings.ReplaceAll(msg, "\n", "\n    ")
 
+	var text string
 	if preview {
-		payload.Text = fmt.Sprintf("**DnsControl Preview %s**\n%s", domain, msg)
+		text = fmt.Sprintf("**DnsControl Preview %s**\n%s", domain, msg)
 	} else if err != nil {
-		payload.Text = fmt.Sprintf("**DnsControl Error Making Changes %s**\n%s\nError: %s", domain, msg, err)
+		text = fmt.Sprintf("**DnsControl Error Making Changes %s**\n%s\nError: %s", domain, msg, err)
 	} else {
-		payload.Text = fmt.Sprintf("**DnsControl Successfully Changed %s**\n%s", domain, msg)
+		text = fmt.Sprintf("**DnsControl Successfully Changed %s**\n%s", domain, msg)
 	}
+	s.notifyText(domain, provider, text, err, preview)
+}
+
+func (s *teamsNotifier) notifyText(domain, provider, text string, err error, preview bool) {
+	var payload struct {
+		Username string `json:"username"`
+		Text     string `json:"text"`
+	}
+	payload.Username = "DnsControl"
+	payload.Text = text
 
 	json, _ := json.Marshal(payload)
 	http.Post(s.URL, "text/json", bytes.NewReader(json))
@@ -9,6 +9,7 @@ import (
 )
 
 func init() {
+	configKeys = append(configKeys, "telegram_bot_token", "telegram_chat_id")
 	initers = append(initers, func(cfg map[string]string) Notifier {
 		if botToken, ok := cfg["telegram_bot_token"]; ok {
 			if chatID, ok := cfg["telegram_chat_id"]; ok {
@@ -30,6 +31,18 @@ type telegramNotifier struct {
 }
 
 func (s *telegramNotifier) Notify(domain, provider, msg string, err error, preview bool) {
+	var text string
+	if preview {
+		text = fmt.Sprintf(`DNSControl preview: %s[%s] -** %s`, domain, provider, msg)
+	} else if err != nil {
+		text = fmt.Sprintf(`DNSControl ERROR running correction on %s[%s] -** (%s) Error: %s`, domain, provider, msg, err)
+	} else {
+		text = fmt.Sprintf(`DNSControl successfully ran correction for **%s[%s]** - %s`, domain, provider, msg)
+	}
+	s.notifyText(domain, provider, text, err, preview)
+}
+
+func (s *telegramNotifier) notifyText(domain, provider, text string, err error, preview bool) {
 	var payload struct {
 		ChatID int64  `json:"chat_id"`
 		Text   string `json:"text"`
@@ -38,14 +51,7 @@ func (s *telegramNotifier) Notify(domain, provider, msg string, err error, previ
 	var url = fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", s.BotToken)
 
 	payload.ChatID, _ = strconv.ParseInt(s.ChatID, 10, 64)
-
-	if preview {
-		payload.Text = fmt.Sprintf(`DNSControl preview: %s[%s] -** %s`, domain, provider, msg)
-	} else if err != nil {
-		payload.Text = fmt.Sprintf(`DNSControl ERROR running correction on %s[%s] -** (%s) Error: %s`, domain, provider, msg, err)
-	} else {
-		payload.Text = fmt.Sprintf(`DNSControl successfully ran correction for **%s[%s]** - %s`, domain, provider, msg)
-	}
+	payload.Text = text
 
 	marshaledPayload, _ := json.Marshal(payload)
 
@@ -0,0 +1,107 @@
+package notifications
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// webhookHeaderPrefix is the prefix of creds.json keys that set HTTP headers.
+// For example, "webhook_header_Authorization": "Bearer 12345"
+const webhookHeaderPrefix = "webhook_header_"
+
+// webhookSignatureHeader is the header that holds the HMAC-SHA256 of the body.
+const webhookSignatureHeader = "X-DNSControl-Signature"
+
+func init() {
+	configKeys = append(configKeys, "webhook_url", "webhook_secret", webhookHeaderPrefix+"*")
+	initers = append(initers, func(cfg map[string]string) Notifier {
+		url, ok := cfg["webhook_url"]
+		if !ok {
+			return nil
+		}
+
+		notifier := &webhookNotifier{
+			URL:     url,
+			Secret:  cfg["webhook_secret"],
+			Headers: map[string]string{},
+		}
+		for k, v := range cfg {
+			if name, ok := strings.CutPrefix(k, webhookHeaderPrefix); ok {
+				notifier.Headers[name] = v
+			}
+		}
+		return notifier
+	})
+}
+
+// webhookNotifier sends notifications as a JSON object to any URL.
+type webhookNotifier struct {
+	URL     string
+	Secret  string            // If set, the body is signed with HMAC-SHA256.
+	Headers map[string]string // Additional HTTP headers.
+}
+
+// webhookPayload is the JSON object POSTed by webhookNotifier.
+type webhookPayload struct {
+	Domain   string `json:"domain"`
+	Provider string `json:"provider"`
+	Message  string `json:"message"`
+	Error    string `json:"error,omitempty"`
+	Preview  bool   `json:"preview"`
+	Success  bool   `json:"success"`
+	Time     string `json:"time"`
+}
+
+func (s *webhookNotifier) Notify(domain, provider, msg string, err error, preview bool) {
+	s.notifyText(domain, provider, msg, err, preview)
+}
+
+// notifyText sends text (the output of a template) as the payload's message.
+func (s *webhookNotifier) notifyText(domain, provider, msg string, err error, preview bool) {
+	payload := webhookPayload{
+		Domain:   domain,
+		Provider: provider,
+		Message:  msg,
+		Preview:  preview,
+		Success:  err == nil,
+		Time:     time.Now().UTC().Format(time.RFC3339),
+	}
+	if err != nil {
+		payload.Error = err.Error()
+	}
+
+	body, _ := json.Marshal(payload)
+	req, rerr := http.NewRequest(http.MethodPost, s.URL, bytes.NewReader(body))
+	if rerr != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "DNSControl")
+	for k, v := range s.Headers {
+		req.Header.Set(k, v)
+	}
+	if s.Secret != "" {
+		req.Header.Set(webhookSignatureHeader, "sha256="+webhookSignature(s.Secret, body))
+	}
+
+	resp, rerr := http.DefaultClient.Do(req)
+	if rerr != nil {
+		return
+	}
+	resp.Body.Close()
+}
+
+func (s *webhookNotifier) Done() {}
+
+// webhookSignature returns the hex-encoded HMAC-SHA256 of body.
+func webhookSignature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
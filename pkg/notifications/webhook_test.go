@@ -0,0 +1,53 @@
+package notifications
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_webhookNotifier(t *testing.T) {
+	var gotBody []byte
+	var gotHeader http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+		gotHeader = r.Header
+	}))
+	defer srv.Close()
+
+	n, err := Init(map[string]string{
+		"webhook_url":                  srv.URL,
+		"webhook_secret":               "s3cr3t",
+		"webhook_header_Authorization": "Bearer 12345",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.Notify("example.com", "bind", "\x1b[32m+ CREATE www.example.com A 1.2.3.4\x1b[0m", fmt.Errorf("boom"), false)
+	n.Done()
+
+	var p webhookPayload
+	if err := json.Unmarshal(gotBody, &p); err != nil {
+		t.Fatalf("bad payload %q: %s", gotBody, err)
+	}
+	if p.Domain != "example.com" || p.Provider != "bind" || p.Message != "+ CREATE www.example.com A 1.2.3.4" || p.Error != "boom" || p.Success || p.Preview {
+		t.Errorf("unexpected payload: %+v", p)
+	}
+	if got := gotHeader.Get("Authorization"); got != "Bearer 12345" {
+		t.Errorf("Authorization header = %q", got)
+	}
+	if got, want := gotHeader.Get(webhookSignatureHeader), "sha256="+webhookSignature("s3cr3t", gotBody); got != want {
+		t.Errorf("signature = %q, want %q", got, want)
+	}
+}
+
+func Test_webhookSignature(t *testing.T) {
+	// echo -n 'hello' | openssl dgst -sha256 -hmac key
+	want := "9307b3b915efb5171ff14d8cb55fbcc798c6c0ef1456d66ded1a6aa723a58b7b"
+	if got := webhookSignature("key", []byte("hello")); got != want {
+		t.Errorf("webhookSignature() = %q, want %q", got, want)
+	}
+}
@@ -197,6 +197,12 @@ func LabelLess(a, b string) bool {
 	return ia < ib
 }
 
+// RrtypeLess provides a "Less" function for two RR types as needed for
+// sorting. SOA, NS, CNAME, A, AAAA, MX, SRV and TXT come first.
+func RrtypeLess(a, b string) bool {
+	return zoneRrtypeLess(a, b)
+}
+
 func zoneRrtypeLess(a, b string) bool {
 	// Compare two RR types for the purpose of sorting the RRs in a Zone.
 
@@ -0,0 +1,209 @@
+package printer
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+)
+
+// JSONLinesPrinter is a CLI that outputs one JSON object per line
+// for each event (start of a domain, each correction, etc.).  The
+// output is meant to be parsed by log aggregators and other programs.
+type JSONLinesPrinter struct {
+	Reader *bufio.Reader
+	Writer io.Writer
+
+	Verbose bool
+
+	// Now returns the timestamp of each event. If nil, time.Now is used.
+	Now func() time.Time
+
+	mu          sync.Mutex
+	domain      string // The current domain.
+	provider    string // The current DNS provider or registrar.
+	isRegistrar bool   // True if provider is a registrar.
+}
+
+// JSONEvent is the object output by JSONLinesPrinter.
+type JSONEvent struct {
+	Time        time.Time `json:"time"`
+	Event       string    `json:"event"`
+	Domain      string    `json:"domain,omitempty"`
+	Provider    string    `json:"provider,omitempty"`
+	Registrar   string    `json:"registrar,omitempty"`
+	N           int       `json:"n,omitempty"` // Correction number, starting at 1.
+	Msg         string    `json:"msg,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	Skip        bool      `json:"skip,omitempty"`
+	Corrections *int      `json:"corrections,omitempty"`
+}
+
+// Events output by JSONLinesPrinter.
+const (
+	EventStartDomain    = "start_domain"
+	EventStartProvider  = "start_provider"
+	EventStartRegistrar = "start_registrar"
+	EventEndProvider    = "end_provider"
+	EventCorrection     = "correction"
+	EventReport         = "report"
+	EventEndCorrection  = "end_correction"
+	EventPrompt         = "prompt"
+	EventDebug          = "debug"
+	EventMessage        = "message"
+	EventWarning        = "warning"
+	EventError          = "error"
+)
+
+func (j *JSONLinesPrinter) emit(e JSONEvent) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.Now != nil {
+		e.Time = j.Now()
+	} else {
+		e.Time = time.Now()
+	}
+	if e.Domain == "" {
+		e.Domain = j.domain
+	}
+	if e.Provider == "" && e.Registrar == "" {
+		if j.isRegistrar {
+			e.Registrar = j.provider
+		} else {
+			e.Provider = j.provider
+		}
+	}
+
+	b, err := json.Marshal(e)
+	if err != nil {
+		// This should never happen. JSONEvent contains only simple types.
+		panic(err)
+	}
+	fmt.Fprintf(j.Writer, "%s\n", b)
+}
+
+func (j *JSONLinesPrinter) setDomain(domain string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.domain = domain
+	j.provider = ""
+}
+
+func (j *JSONLinesPrinter) setProvider(provider string, isRegistrar bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.provider = provider
+	j.isRegistrar = isRegistrar
+}
+
+// StartDomain is called at the start of each domain.
+func (j *JSONLinesPrinter) StartDomain(domain string) {
+	j.setDomain(domain)
+	j.emit(JSONEvent{Event: EventStartDomain})
+}
+
+// StartDNSProvider is called at the start of each new provider.
+func (j *JSONLinesPrinter) StartDNSProvider(name string, skip bool) {
+	j.setProvider(name, false)
+	j.emit(JSONEvent{Event: EventStartProvider, Skip: skip})
+}
+
+// StartRegistrar is called at the start of each new registrar.
+func (j *JSONLinesPrinter) StartRegistrar(name string, skip bool) {
+	j.setProvider(name, true)
+	j.emit(JSONEvent{Event: EventStartRegistrar, Skip: skip})
+}
+
+// EndProvider is called at the end of each provider.
+func (j *JSONLinesPrinter) EndProvider(name string, numCorrections int, err error) {
+	e := JSONEvent{Event: EventEndProvider, Corrections: &numCorrections}
+	j.mu.Lock()
+	if j.isRegistrar {
+		e.Registrar = name
+	} else {
+		e.Provider = name
+	}
+	j.mu.Unlock()
+	if err != nil {
+		e.Error = err.Error()
+	}
+	j.emit(e)
+}
+
+// EndProvider2 is called at the end of each provider.
+func (j *JSONLinesPrinter) EndProvider2(name string, numCorrections int) {
+	j.EndProvider(name, numCorrections, nil)
+}
+
+// PrintCorrection is called to print/format each correction.
+func (j *JSONLinesPrinter) PrintCorrection(i int, correction *models.Correction) {
+	j.emit(JSONEvent{Event: EventCorrection, N: i + 1, Msg: correction.Msg})
+}
+
+// PrintReport is called to print/format each non-mutating correction (diff2.REPORT).
+func (j *JSONLinesPrinter) PrintReport(i int, correction *models.Correction) {
+	j.emit(JSONEvent{Event: EventReport, N: i + 1, Msg: correction.Msg})
+}
+
+// EndCorrection is called at the end of each correction.
+func (j *JSONLinesPrinter) EndCorrection(err error) {
+	e := JSONEvent{Event: EventEndCorrection}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	j.emit(e)
+}
+
+// PromptToRun prompts the user to see if they want to execute a correction.
+// The answer is read from Reader as with ConsolePrinter.
+func (j *JSONLinesPrinter) PromptToRun() bool {
+	j.emit(JSONEvent{Event: EventPrompt, Msg: "Run? (y/N)"})
+	if j.Reader == nil {
+		return false
+	}
+	txt, err := j.Reader.ReadString('\n')
+	if err != nil {
+		return false
+	}
+	return strings.ToLower(strings.TrimSpace(txt)) == "y"
+}
+
+// Debugf is called to print/format debug information.
+func (j *JSONLinesPrinter) Debugf(format string, args ...interface{}) {
+	if j.Verbose {
+		j.emit(JSONEvent{Event: EventDebug, Msg: strings.TrimSuffix(fmt.Sprintf(format, args...), "\n")})
+	}
+}
+
+// Printf is called to print/format information.
+func (j *JSONLinesPrinter) Printf(format string, args ...interface{}) {
+	j.emit(JSONEvent{Event: EventMessage, Msg: strings.TrimSuffix(fmt.Sprintf(format, args...), "\n")})
+}
+
+// Println is called to print/format information.
+func (j *JSONLinesPrinter) Println(lines ...string) {
+	j.emit(JSONEvent{Event: EventMessage, Msg: strings.Join(lines, "\n")})
+}
+
+// Warnf is called to print/format a warning.
+func (j *JSONLinesPrinter) Warnf(format string, args ...interface{}) {
+	j.emit(JSONEvent{Event: EventWarning, Msg: strings.TrimSuffix(fmt.Sprintf(format, args...), "\n")})
+}
+
+// Errorf is called to print/format an error.
+func (j *JSONLinesPrinter) Errorf(format string, args ...interface{}) {
+	j.emit(JSONEvent{Event: EventError, Error: strings.TrimSuffix(fmt.Sprintf(format, args...), "\n")})
+}
+
+// PrintfIf is called to optionally print/format a message.
+func (j *JSONLinesPrinter) PrintfIf(print bool, format string, args ...interface{}) {
+	if print {
+		j.Printf(format, args...)
+	}
+}
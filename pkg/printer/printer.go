@@ -74,6 +74,32 @@ var (
 	}
 )
 
+// DefaultCLI is the CLI used by the preview/push commands. It is
+// selected by SetCLIFormat.
+var DefaultCLI CLI = DefaultPrinter
+
+// SetCLIFormat selects the implementation of DefaultCLI: "console"
+// (human-readable text) or "jsonl" (one JSON object per event, see
+// JSONLinesPrinter).  With "jsonl", the events are written to stdout and
+// any other output of DefaultPrinter is sent to stderr so that stdout
+// remains parseable.
+func SetCLIFormat(format string) error {
+	switch format {
+	case "", "console":
+		DefaultCLI = DefaultPrinter
+	case "jsonl":
+		DefaultCLI = &JSONLinesPrinter{
+			Reader:  DefaultPrinter.Reader,
+			Writer:  os.Stdout,
+			Verbose: DefaultPrinter.Verbose,
+		}
+		DefaultPrinter.Writer = os.Stderr
+	default:
+		return fmt.Errorf("unknown printer %q. Valid values are: console, jsonl", format)
+	}
+	return nil
+}
+
 // SkinnyReport is true to to disable certain print statements.
 // This is a hack until we have the new printer replacement. The long
 // variable name is easy to grep for when we make the conversion.
@@ -2,8 +2,11 @@ package printer
 
 import (
 	"bytes"
+	"fmt"
 	"testing"
+	"time"
 
+	"github.com/StackExchange/dnscontrol/v4/models"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -45,3 +48,30 @@ func TestVerbose(t *testing.T) {
 	p.Debugf("more debugging\n")
 	assert.Equal(t, "WARNING: a dire warning!\noutput\nmore debugging\n", output.String())
 }
+
+func TestJSONLinesPrinter(t *testing.T) {
+	output := &bytes.Buffer{}
+	p := &JSONLinesPrinter{
+		Writer: output,
+		Now:    func() time.Time { return time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC) },
+	}
+
+	p.StartDomain("example.com")
+	p.StartDNSProvider("bind", false)
+	p.EndProvider2("bind", 1)
+	p.PrintCorrection(0, &models.Correction{Msg: "+ CREATE www"})
+	p.EndCorrection(fmt.Errorf("boom"))
+	p.StartRegistrar("none", true)
+	p.Debugf("suppressed\n")
+	p.Printf("Done.\n")
+
+	ts := `"time":"2024-07-01T12:00:00Z"`
+	assert.Equal(t, `{`+ts+`,"event":"start_domain","domain":"example.com"}
+{`+ts+`,"event":"start_provider","domain":"example.com","provider":"bind"}
+{`+ts+`,"event":"end_provider","domain":"example.com","provider":"bind","corrections":1}
+{`+ts+`,"event":"correction","domain":"example.com","provider":"bind","n":1,"msg":"+ CREATE www"}
+{`+ts+`,"event":"end_correction","domain":"example.com","provider":"bind","error":"boom"}
+{`+ts+`,"event":"start_registrar","domain":"example.com","registrar":"none","skip":true}
+{`+ts+`,"event":"message","domain":"example.com","registrar":"none","msg":"Done."}
+`, output.String())
+}
@@ -0,0 +1,215 @@
+// Package ratelimit provides a rate limiter and retry policy that
+// providers can share instead of each implementing their own.
+//
+// A Limiter combines a token bucket (to stay below the API's rate
+// limit) with retries using exponential backoff and jitter (for when
+// the API reports that the limit was exceeded anyway). Retry-After
+// headers are honored.
+//
+// Providers that use net/http directly (or an SDK that accepts an
+// *http.Client) should use Limiter.Client() or Transport. Providers
+// whose SDK hides the HTTP client should wrap each API call with
+// Limiter.Do() and mark retryable errors with Retry().
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/StackExchange/dnscontrol/v4/pkg/printer"
+	"golang.org/x/time/rate"
+)
+
+// Config configures a Limiter.
+type Config struct {
+	MaxRPS     float64       // Requests per second. 0 means unlimited.
+	MaxRetries int           // Retries after the first attempt. 0 means never retry.
+	MinBackoff time.Duration // Delay before the first retry. Default 1s.
+	MaxBackoff time.Duration // Upper bound on any delay, including Retry-After. Default 2m.
+}
+
+// Limiter rate-limits and retries API requests. A nil *Limiter is
+// valid and does neither.
+type Limiter struct {
+	cfg    Config
+	bucket *rate.Limiter
+
+	sleep func(time.Duration) // Replaced in tests.
+}
+
+// New returns a Limiter configured by cfg.
+func New(cfg Config) *Limiter {
+	if cfg.MinBackoff == 0 {
+		cfg.MinBackoff = time.Second
+	}
+	if cfg.MaxBackoff == 0 {
+		cfg.MaxBackoff = 2 * time.Minute
+	}
+	l := &Limiter{cfg: cfg, sleep: time.Sleep}
+	if cfg.MaxRPS > 0 {
+		// A burst of 1 spreads the requests evenly.
+		l.bucket = rate.NewLimiter(rate.Limit(cfg.MaxRPS), 1)
+	}
+	return l
+}
+
+// Config returns the configuration of the Limiter.
+func (l *Limiter) Config() Config {
+	if l == nil {
+		return Config{}
+	}
+	return l.cfg
+}
+
+// Wait blocks until the token bucket permits another request.
+func (l *Limiter) Wait() {
+	if l == nil || l.bucket == nil {
+		return
+	}
+	r := l.bucket.Reserve()
+	l.sleep(r.Delay())
+}
+
+// RetryError marks an error as retryable. See Retry().
+type RetryError struct {
+	Err   error
+	After time.Duration // How long the server asked us to wait. 0 means use the backoff.
+}
+
+func (e *RetryError) Error() string { return e.Err.Error() }
+
+func (e *RetryError) Unwrap() error { return e.Err }
+
+// Retry marks err as retryable. after is the delay requested by the
+// server (for example, from a Retry-After header) or 0. Retry(nil, _)
+// returns nil.
+func Retry(err error, after time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &RetryError{Err: err, After: after}
+}
+
+// RetryableStatus reports whether an HTTP status code indicates that
+// the request may succeed if retried later.
+func RetryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code == http.StatusServiceUnavailable
+}
+
+// Do calls f, waiting for the token bucket before each attempt. If f
+// returns an error created by Retry(), f is called again after a
+// delay, up to MaxRetries times. The error returned is the one f
+// returned, without the RetryError wrapper.
+func (l *Limiter) Do(f func() error) error {
+	for attempt := 0; ; attempt++ {
+		l.Wait()
+		err := f()
+		var rerr *RetryError
+		if !errors.As(err, &rerr) {
+			return err
+		}
+		if l == nil || attempt >= l.cfg.MaxRetries {
+			return rerr.Err
+		}
+		d := l.backoff(attempt, rerr.After)
+		printer.Debugf("ratelimit: retry %d/%d in %v: %v\n", attempt+1, l.cfg.MaxRetries, d, rerr.Err)
+		l.sleep(d)
+	}
+}
+
+// backoff returns how long to wait before retry number attempt+1.
+func (l *Limiter) backoff(attempt int, after time.Duration) time.Duration {
+	if after > 0 {
+		return min(after, l.cfg.MaxBackoff)
+	}
+	d := l.cfg.MinBackoff
+	for i := 0; i < attempt && d < l.cfg.MaxBackoff; i++ {
+		d *= 2
+	}
+	d = min(d, l.cfg.MaxBackoff)
+	// "Equal jitter": half fixed, half random. Concurrent clients that
+	// were limited at the same moment don't all retry at the same moment.
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// RetryAfter parses a Retry-After header, which is either a number of
+// seconds or an HTTP date. It returns 0 if the header is missing or
+// invalid.
+func RetryAfter(h http.Header) time.Duration {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// Transport is an http.RoundTripper that rate-limits requests and
+// retries those that fail with a retryable status code.
+type Transport struct {
+	Limiter *Limiter
+	Base    http.RoundTripper // nil means http.DefaultTransport.
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	var resp *http.Response
+	err := t.Limiter.Do(func() error {
+		r := req
+		if resp != nil {
+			// This is a retry. The body was consumed by the previous attempt.
+			resp.Body.Close()
+			resp = nil
+			if req.Body != nil && req.Body != http.NoBody {
+				if req.GetBody == nil {
+					return fmt.Errorf("ratelimit: can't retry request with a body that can't be rewound")
+				}
+				body, err := req.GetBody()
+				if err != nil {
+					return err
+				}
+				r = req.Clone(req.Context())
+				r.Body = body
+			}
+		}
+		var err error
+		resp, err = base.RoundTrip(r)
+		if err != nil {
+			return err
+		}
+		if RetryableStatus(resp.StatusCode) {
+			return Retry(fmt.Errorf("%s %s: %s", req.Method, req.URL.Redacted(), resp.Status), RetryAfter(resp.Header))
+		}
+		return nil
+	})
+	if resp != nil && (err == nil || RetryableStatus(resp.StatusCode)) {
+		// Out of retries: let the caller see the last response, as it would without us.
+		return resp, nil
+	}
+	return nil, err
+}
+
+// Client returns an *http.Client that uses the Limiter.
+func (l *Limiter) Client() *http.Client {
+	return &http.Client{Transport: &Transport{Limiter: l}}
+}
@@ -0,0 +1,144 @@
+package ratelimit
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestLimiter returns a Limiter that records delays instead of sleeping.
+func newTestLimiter(cfg Config, slept *[]time.Duration) *Limiter {
+	l := New(cfg)
+	l.sleep = func(d time.Duration) { *slept = append(*slept, d) }
+	return l
+}
+
+func TestDo(t *testing.T) {
+	var slept []time.Duration
+	l := newTestLimiter(Config{MaxRetries: 3, MinBackoff: time.Second, MaxBackoff: 3 * time.Second}, &slept)
+
+	limited := errors.New("429")
+	calls := 0
+	err := l.Do(func() error {
+		calls++
+		if calls < 4 {
+			return Retry(limited, 0)
+		}
+		return nil
+	})
+	if err != nil || calls != 4 {
+		t.Fatalf("Do() = %v after %d calls, want nil after 4", err, calls)
+	}
+	// Exponential backoff with jitter: [d/2, d] for d = 1s, 2s, 3s (capped).
+	wantMax := []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}
+	for i, d := range slept {
+		if d < wantMax[i]/2 || d > wantMax[i] {
+			t.Errorf("retry %d slept %v, want between %v and %v", i, d, wantMax[i]/2, wantMax[i])
+		}
+	}
+
+	// Out of retries: the original error is returned, unwrapped.
+	calls = 0
+	err = l.Do(func() error { calls++; return Retry(limited, 0) })
+	if err != limited || calls != 4 {
+		t.Errorf("Do() = %v after %d calls, want %v after 4", err, calls, limited)
+	}
+
+	// Errors that aren't marked as retryable are returned immediately.
+	calls = 0
+	other := errors.New("404")
+	if err := l.Do(func() error { calls++; return other }); err != other || calls != 1 {
+		t.Errorf("Do() = %v after %d calls, want %v after 1", err, calls, other)
+	}
+
+	// Retry-After is honored.
+	slept = nil
+	calls = 0
+	_ = l.Do(func() error {
+		calls++
+		if calls == 1 {
+			return Retry(limited, 2*time.Second)
+		}
+		return nil
+	})
+	if len(slept) != 1 || slept[0] != 2*time.Second {
+		t.Errorf("Retry-After: slept %v, want [2s]", slept)
+	}
+}
+
+func TestNilLimiter(t *testing.T) {
+	var l *Limiter
+	calls := 0
+	err := l.Do(func() error { calls++; return Retry(errors.New("x"), 0) })
+	if err == nil || calls != 1 {
+		t.Errorf("nil Limiter: Do() = %v after %d calls, want an error after 1", err, calls)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		header string
+		want   time.Duration
+	}{
+		{"", 0},
+		{"5", 5 * time.Second},
+		{"-1", 0},
+		{"soon", 0},
+		{time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0},
+	}
+	for _, tt := range tests {
+		h := http.Header{}
+		if tt.header != "" {
+			h.Set("Retry-After", tt.header)
+		}
+		if got := RetryAfter(h); got != tt.want {
+			t.Errorf("RetryAfter(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestTransport(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if calls.Add(1) < 3 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	var slept []time.Duration
+	l := newTestLimiter(Config{MaxRetries: 5}, &slept)
+	resp, err := l.Client().Post(srv.URL, "text/plain", strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "hello" {
+		t.Errorf("got %d %q, want 200 %q", resp.StatusCode, body, "hello")
+	}
+	if calls.Load() != 3 || len(slept) != 2 || slept[0] != time.Second {
+		t.Errorf("calls=%d slept=%v, want 3 calls and 2 sleeps of 1s", calls.Load(), slept)
+	}
+
+	// Out of retries: the caller sees the 429.
+	calls.Store(0)
+	l = newTestLimiter(Config{MaxRetries: 1}, &slept)
+	resp, err = l.Client().Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("got %d, want 429", resp.StatusCode)
+	}
+}
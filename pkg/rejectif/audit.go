@@ -35,7 +35,7 @@ func (aud *Auditor) Audit(records models.Records) (errs []error) {
 		for _, f := range aud.checksFor[rc.Type] {
 			e := f(rc)
 			if e != nil {
-				errs = append(errs, e)
+				errs = append(errs, rc.SourceError(e))
 			}
 		}
 	}
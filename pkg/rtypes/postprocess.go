@@ -19,6 +19,7 @@ func PostProcess(domains []*models.DomainConfig) error {
 				TTL:      rawRec.TTL,
 				Name:     rawRec.Args[0].(string),
 				Metadata: map[string]string{},
+				Source:   rawRec.Source,
 			}
 
 			// Copy the metadata (convert everything to string)
@@ -44,7 +45,7 @@ func PostProcess(domains []*models.DomainConfig) error {
 				err = fmt.Errorf("unknown rawrec type=%q", rawRec.Type)
 			}
 			if err != nil {
-				return fmt.Errorf("%s (%q, %q) record error: %w", rawRec.Type, rec.Name, dc.Name, err)
+				return rec.SourceError(fmt.Errorf("%s (%q, %q) record error: %w", rawRec.Type, rec.Name, dc.Name, err))
 			}
 
 			// Free memeory:
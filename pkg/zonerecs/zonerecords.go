@@ -2,16 +2,39 @@ package zonerecs
 
 import (
 	"github.com/StackExchange/dnscontrol/v4/models"
+	"github.com/StackExchange/dnscontrol/v4/pkg/diff2"
 )
 
 // CorrectZoneRecords calls both GetZoneRecords, does any
 // post-processing, and then calls GetZoneRecordsCorrections.  The
 // name sucks because all the good names were taken.
 func CorrectZoneRecords(driver models.DNSProvider, dc *models.DomainConfig) ([]*models.Correction, []*models.Correction, error) {
+	reports, corrections, _, err := correctZoneRecords(driver, dc, false)
+	return reports, corrections, err
+}
+
+// ZoneChanges is the record-level detail of the corrections for a zone.
+type ZoneChanges struct {
+	Changes  diff2.ChangeList // As computed by diff2.ByRecord.
+	Ignored  models.Records   // Existing records left alone because of IGNORE*().
+	Unpurged models.Records   // Existing records left alone because of NO_PURGE.
+	Existing models.Records   // A copy of the records as they were before any changes.
+}
+
+// CorrectZoneRecordsWithChanges is like CorrectZoneRecords but also
+// returns the record-level changes that the corrections implement.
+// This is used to generate plans that list every record being
+// created, changed or deleted, independent of how the provider groups
+// its API calls.
+func CorrectZoneRecordsWithChanges(driver models.DNSProvider, dc *models.DomainConfig) ([]*models.Correction, []*models.Correction, *ZoneChanges, error) {
+	return correctZoneRecords(driver, dc, true)
+}
+
+func correctZoneRecords(driver models.DNSProvider, dc *models.DomainConfig, wantChanges bool) ([]*models.Correction, []*models.Correction, *ZoneChanges, error) {
 
 	existingRecords, err := driver.GetZoneRecords(dc.Name, dc.Metadata)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	// downcase
@@ -26,7 +49,7 @@ func CorrectZoneRecords(driver models.DNSProvider, dc *models.DomainConfig) ([]*
 	// dc.Records.
 	dc, err = dc.Copy()
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	// punycode
@@ -34,9 +57,43 @@ func CorrectZoneRecords(driver models.DNSProvider, dc *models.DomainConfig) ([]*
 	// FIXME(tlim) It is a waste to PunyCode every iteration.
 	// This should be moved to where the JavaScript is processed.
 
+	// Snapshot the existing records before the provider has a chance to
+	// modify them.
+	var snapshot models.Records
+	if wantChanges {
+		snapshot, err = copyRecords(existingRecords)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
 	everything, err := driver.GetZoneRecordsCorrections(dc, existingRecords)
 	reports, corrections := splitReportsAndCorrections(everything)
-	return reports, corrections, err
+	if err != nil || !wantChanges {
+		return reports, corrections, nil, err
+	}
+
+	// Diff after GetZoneRecordsCorrections() so that any adjustments
+	// the provider made to dc.Records (TTLs, etc.) are reflected.
+	zc := &ZoneChanges{Existing: snapshot}
+	zc.Changes, err = diff2.ByRecord(existingRecords, dc, nil)
+	if err != nil {
+		return reports, corrections, nil, err
+	}
+	zc.Ignored, zc.Unpurged, err = diff2.Unmanaged(existingRecords, dc)
+	return reports, corrections, zc, err
+}
+
+func copyRecords(recs models.Records) (models.Records, error) {
+	c := make(models.Records, 0, len(recs))
+	for _, r := range recs {
+		nr, err := r.Copy()
+		if err != nil {
+			return nil, err
+		}
+		c = append(c, nr)
+	}
+	return c, nil
 }
 
 func splitReportsAndCorrections(everything []*models.Correction) (reports, corrections []*models.Correction) {
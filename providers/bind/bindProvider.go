@@ -33,7 +33,7 @@ import (
 var features = providers.DocumentationNotes{
 	// The default for unlisted capabilities is 'Cannot'.
 	// See providers/capabilities.go for the entire list of capabilities.
-	providers.CanAutoDNSSEC:          providers.Can("Just writes out a comment indicating DNSSEC was requested"),
+	providers.CanAutoDNSSEC:          providers.Can("Signs the zone if keydirectory is set, otherwise just writes out a comment indicating DNSSEC was requested"),
 	providers.CanGetZones:            providers.Can(),
 	providers.CanConcur:              providers.Cannot(),
 	providers.CanUseCAA:              providers.Can(),
@@ -61,6 +61,7 @@ func initBind(config map[string]string, providermeta json.RawMessage) (providers
 	api := &bindProvider{
 		directory:      config["directory"],
 		filenameformat: config["filenameformat"],
+		keydirectory:   config["keydirectory"],
 	}
 	if api.directory == "" {
 		api.directory = "zones"
@@ -74,6 +75,12 @@ func initBind(config map[string]string, providermeta json.RawMessage) (providers
 			return nil, err
 		}
 	}
+	if err := api.parseSigning(); err != nil {
+		return nil, err
+	}
+	if err := api.parseServerConfig(); err != nil {
+		return nil, err
+	}
 	var nss []string
 	for i, ns := range api.DefaultNS {
 		if ns == "" {
@@ -121,13 +128,19 @@ func (s SoaDefaults) String() string {
 
 // bindProvider is the provider handle for the bindProvider driver.
 type bindProvider struct {
-	DefaultNS      []string    `json:"default_ns"`
-	DefaultSoa     SoaDefaults `json:"default_soa"`
+	DefaultNS      []string        `json:"default_ns"`
+	DefaultSoa     SoaDefaults     `json:"default_soa"`
+	Signing        SigningDefaults `json:"signing"`
+	ServerConfig   ServerConfig    `json:"server_config"`
 	nameservers    []*models.Nameserver
 	directory      string
 	filenameformat string
-	zonefile       string // Where the zone data is e texpected
-	zoneFileFound  bool   // Did the zonefile exist?
+	keydirectory   string          // Where the DNSSEC keys are. "" to not sign.
+	validity       time.Duration   // How long signatures are valid.
+	refresh        time.Duration   // Re-sign when signatures expire within this.
+	nsec3          *dns.NSEC3PARAM // nil to use NSEC.
+	zonefile       string          // Where the zone data is e texpected
+	zoneFileFound  bool            // Did the zonefile exist?
 }
 
 // GetNameservers returns the nameservers for a domain.
@@ -156,6 +169,15 @@ func (c *bindProvider) ListZones() ([]string, error) {
 		return files, fmt.Errorf("bind ListZones readdir %q: %w",
 			c.directory, err)
 	}
+	// Skip the signed versions of the zone files and the server config.
+	n := 0
+	for _, name := range filenames {
+		if !strings.HasSuffix(name, signedSuffix) && filepath.Join(c.directory, name) != c.serverConfigFile() {
+			filenames[n] = name
+			n++
+		}
+	}
+	filenames = filenames[:n]
 
 	return extractZonesFromFilenames(c.filenameformat, filenames), nil
 }
@@ -252,8 +274,18 @@ func (c *bindProvider) GetZoneRecordsCorrections(dc *models.DomainConfig, foundR
 	if err != nil {
 		return nil, err
 	}
+	c.zonefile = filepath.Join(c.directory,
+		makeFileName(c.filenameformat,
+			dc.Metadata[models.DomainUniqueName], dc.Name, dc.Metadata[models.DomainTag]),
+	)
+
 	if !changes {
-		return nil, nil
+		// The zone file is up to date, but its signatures may not be.
+		signing, err := c.signingCorrection(dc, false)
+		if signing == nil || err != nil {
+			return nil, err
+		}
+		return []*models.Correction{signing}, nil
 	}
 	msg = strings.Join(msgs, "\n")
 
@@ -269,11 +301,6 @@ func (c *bindProvider) GetZoneRecordsCorrections(dc *models.DomainConfig, foundR
 		comments = append(comments, "Automatic DNSSEC signing requested")
 	}
 
-	c.zonefile = filepath.Join(c.directory,
-		makeFileName(c.filenameformat,
-			dc.Metadata[models.DomainUniqueName], dc.Name, dc.Metadata[models.DomainTag]),
-	)
-
 	// We only change the serial number if there is a change.
 	desiredSoa.SoaSerial = nextSerial
 
@@ -311,6 +338,14 @@ func (c *bindProvider) GetZoneRecordsCorrections(dc *models.DomainConfig, foundR
 			},
 		})
 
+	signing, err := c.signingCorrection(dc, true)
+	if err != nil {
+		return nil, err
+	}
+	if signing != nil {
+		corrections = append(corrections, signing)
+	}
+
 	return corrections, nil
 }
 
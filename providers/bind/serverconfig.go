@@ -0,0 +1,312 @@
+package bind
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+	"github.com/StackExchange/dnscontrol/v4/pkg/printer"
+	"golang.org/x/net/idna"
+)
+
+// ServerConfig contains the "server_config" provider metadata: how to
+// write the configuration that makes a name server load the zones.
+type ServerConfig struct {
+	Format        string   `json:"format"`         // "bind", "nsd" or "knot". "" to not write it.
+	Filename      string   `json:"filename"`       // Relative to the directory of the zone files.
+	ZoneDirectory string   `json:"zone_directory"` // Where the name server finds the zone files.
+	Primaries     []string `json:"primaries"`      // Make the zones secondaries of these.
+	Secondaries   []string `json:"secondaries"`    // Notify these and allow them to transfer the zones.
+	Notify        []string `json:"notify"`         // Also notify these.
+	AllowTransfer []string `json:"allow_transfer"` // Also allow these (addresses or prefixes) to transfer the zones.
+	TSIGKey       string   `json:"tsig_key"`       // The name of the key for transfers and notifies.
+}
+
+// serverConfigFormats are the templates of the server configurations
+// and their default file names.
+var serverConfigFormats = map[string]struct {
+	name     string
+	filename string
+	tmpl     *template.Template
+}{
+	"bind": {"BIND", "named.conf.dnscontrol", template.Must(template.New("bind").Parse(bindTemplate))},
+	"nsd":  {"NSD", "nsd.conf.dnscontrol", template.Must(template.New("nsd").Parse(nsdTemplate))},
+	"knot": {"Knot", "knot.conf.dnscontrol", template.Must(template.New("knot").Funcs(template.FuncMap{"remoteID": remoteID}).Parse(knotTemplate))},
+}
+
+const bindTemplate = `// Generated by dnscontrol. Do not edit.
+{{range .Zones}}
+zone "{{.Name}}" {
+	type {{if $.Primaries}}secondary{{else}}primary{{end}};
+	file "{{.File}}";
+{{- with $.Primaries}}
+	primaries { {{range .}}{{.}}{{with $.Key}} key "{{.}}"{{end}}; {{end}}};
+{{- end}}
+{{- with $.Notify}}
+	notify explicit;
+	also-notify { {{range .}}{{.}}{{with $.Key}} key "{{.}}"{{end}}; {{end}}};
+{{- end}}
+{{- if $.Key}}{{if $.Transfer}}
+	allow-transfer { key "{{$.Key}}"; };
+{{- end}}{{else}}{{with $.Transfer}}
+	allow-transfer { {{range .}}{{.}}; {{end}}};
+{{- end}}{{end}}
+};
+{{end -}}
+`
+
+const nsdTemplate = `# Generated by dnscontrol. Do not edit.
+{{range .Zones}}
+zone:
+	name: "{{.Name}}"
+	zonefile: "{{.File}}"
+{{- range $.Primaries}}
+	allow-notify: {{.}} {{$.NSDKey}}
+	request-xfr: {{.}} {{$.NSDKey}}
+{{- end}}
+{{- range $.Notify}}
+	notify: {{.}} {{$.NSDKey}}
+{{- end}}
+{{- range $.Transfer}}
+	provide-xfr: {{.}} {{$.NSDKey}}
+{{- end}}
+{{end -}}
+`
+
+const knotTemplate = `# Generated by dnscontrol. Do not edit.
+{{- with .Remotes}}
+
+remote:
+{{- range .}}
+  - id: {{.ID}}
+    address: {{.Address}}
+{{- with $.Key}}
+    key: {{.}}
+{{- end}}
+{{- end}}
+{{- end}}
+{{- if or .Transfer .Primaries}}
+
+acl:
+{{- with .Transfer}}
+  - id: dnscontrol_transfer
+    address: [{{range $i, $a := .}}{{if $i}}, {{end}}{{$a}}{{end}}]
+{{- with $.Key}}
+    key: {{.}}
+{{- end}}
+    action: transfer
+{{- end}}
+{{- with .Primaries}}
+  - id: dnscontrol_notify
+    address: [{{range $i, $a := .}}{{if $i}}, {{end}}{{$a}}{{end}}]
+{{- with $.Key}}
+    key: {{.}}
+{{- end}}
+    action: notify
+{{- end}}
+{{- end}}
+{{- with .Zones}}
+
+zone:
+{{- range .}}
+  - domain: "{{.Name}}"
+    file: "{{.File}}"
+{{- with $.Primaries}}
+    master: [{{range $i, $a := .}}{{if $i}}, {{end}}{{remoteID $a}}{{end}}]
+{{- end}}
+{{- with $.Notify}}
+    notify: [{{range $i, $a := .}}{{if $i}}, {{end}}{{remoteID $a}}{{end}}]
+{{- end}}
+{{- if or $.Transfer $.Primaries}}
+    acl: [{{if $.Transfer}}dnscontrol_transfer{{end}}{{if and $.Transfer $.Primaries}}, {{end}}{{if $.Primaries}}dnscontrol_notify{{end}}]
+{{- end}}
+{{- end}}
+{{- end}}
+`
+
+// remoteID returns the ID of the Knot remote with address addr.
+func remoteID(addr string) string {
+	return "dnscontrol_" + strings.NewReplacer(".", "_", ":", "_").Replace(addr)
+}
+
+var tsigKeyName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// parseServerConfig validates the server_config settings.
+func (c *bindProvider) parseServerConfig() error {
+	sc := &c.ServerConfig
+	if sc.Format == "" {
+		if sc.Filename != "" || sc.ZoneDirectory != "" || len(sc.Primaries)+len(sc.Secondaries)+len(sc.Notify)+len(sc.AllowTransfer) != 0 || sc.TSIGKey != "" {
+			return errors.New("server_config requires a format")
+		}
+		return nil
+	}
+	format, ok := serverConfigFormats[sc.Format]
+	if !ok {
+		return fmt.Errorf("server_config format %q must be bind, nsd or knot", sc.Format)
+	}
+	if sc.Filename == "" {
+		sc.Filename = format.filename
+	}
+	for field, addrs := range map[string][]string{"primaries": sc.Primaries, "secondaries": sc.Secondaries, "notify": sc.Notify} {
+		for _, a := range addrs {
+			if _, err := netip.ParseAddr(a); err != nil {
+				return fmt.Errorf("server_config %s: %q is not an IP address", field, a)
+			}
+		}
+	}
+	for _, a := range sc.AllowTransfer {
+		if _, err := netip.ParseAddr(a); err != nil {
+			if _, err := netip.ParsePrefix(a); err != nil {
+				return fmt.Errorf("server_config allow_transfer: %q is not an IP address or prefix", a)
+			}
+		}
+	}
+	if sc.TSIGKey != "" && !tsigKeyName.MatchString(sc.TSIGKey) {
+		return fmt.Errorf("server_config tsig_key %q is not a valid key name", sc.TSIGKey)
+	}
+	return nil
+}
+
+// serverConfigFile returns the name of the server configuration file.
+func (c *bindProvider) serverConfigFile() string {
+	if filepath.IsAbs(c.ServerConfig.Filename) {
+		return c.ServerConfig.Filename
+	}
+	return filepath.Join(c.directory, c.ServerConfig.Filename)
+}
+
+type serverConfigZone struct {
+	Name string // The name of the zone, in ASCII.
+	File string // The zone file, as the name server sees it.
+}
+
+type serverConfigRemote struct {
+	ID      string
+	Address string
+}
+
+// serverConfigData is what the templates are executed with.
+type serverConfigData struct {
+	Zones     []serverConfigZone
+	Primaries []string             // Transfer the zones from these.
+	Notify    []string             // Notify these of changes.
+	Transfer  []string             // Allow these to transfer the zones.
+	Remotes   []serverConfigRemote // Primaries and Notify, for Knot.
+	Key       string
+	NSDKey    string // Key, or NOKEY.
+}
+
+// generateServerConfig returns the server configuration for the zones.
+func (c *bindProvider) generateServerConfig(dcs []*models.DomainConfig) ([]byte, error) {
+	sc := c.ServerConfig
+	zoneDirectory := sc.ZoneDirectory
+	if zoneDirectory == "" {
+		abs, err := filepath.Abs(c.directory)
+		if err != nil {
+			return nil, err
+		}
+		zoneDirectory = abs
+	}
+
+	data := serverConfigData{
+		Primaries: sc.Primaries,
+		Notify:    union(sc.Secondaries, sc.Notify),
+		Transfer:  union(sc.Secondaries, sc.AllowTransfer),
+		Key:       sc.TSIGKey,
+		NSDKey:    sc.TSIGKey,
+	}
+	if data.NSDKey == "" {
+		data.NSDKey = "NOKEY"
+	}
+	for _, a := range union(data.Primaries, data.Notify) {
+		data.Remotes = append(data.Remotes, serverConfigRemote{ID: remoteID(a), Address: a})
+	}
+
+	seen := map[string]string{}
+	for _, dc := range dcs {
+		name, err := idna.ToASCII(dc.Name)
+		if err != nil {
+			return nil, fmt.Errorf("server_config: %w", err)
+		}
+		uniquename := dc.GetUniqueName()
+		if other, ok := seen[name]; ok {
+			return nil, fmt.Errorf("server_config: %s and %s are the same zone; use a separate provider for each view", other, uniquename)
+		}
+		seen[name] = uniquename
+
+		file := filepath.Join(zoneDirectory, makeFileName(c.filenameformat, uniquename, dc.Name, dc.Metadata[models.DomainTag]))
+		if c.signs(dc) {
+			file += signedSuffix
+		}
+		data.Zones = append(data.Zones, serverConfigZone{Name: name, File: file})
+	}
+	sort.Slice(data.Zones, func(i, j int) bool { return data.Zones[i].Name < data.Zones[j].Name })
+
+	var buf bytes.Buffer
+	if err := serverConfigFormats[sc.Format].tmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// union returns the strings of a followed by those of b that aren't in a.
+func union(a, b []string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, s := range append(append([]string{}, a...), b...) {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// GetServerConfigCorrections returns a correction that writes the
+// configuration of the name server to load the zones dcs, if
+// server_config is set.
+func (c *bindProvider) GetServerConfigCorrections(dcs []*models.DomainConfig) ([]*models.Correction, error) {
+	if c.ServerConfig.Format == "" {
+		return nil, nil
+	}
+	content, err := c.generateServerConfig(dcs)
+	if err != nil {
+		return nil, err
+	}
+	filename := c.serverConfigFile()
+	old, err := os.ReadFile(filename)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if bytes.Equal(old, content) {
+		return nil, nil
+	}
+
+	zones := "zones"
+	if len(dcs) == 1 {
+		zones = "zone"
+	}
+	return []*models.Correction{{
+		Msg: fmt.Sprintf("Write %s configuration for %d %s to %s", serverConfigFormats[c.ServerConfig.Format].name, len(dcs), zones, filename),
+		F: func() error {
+			printer.Printf("WRITING SERVER CONFIG: %v\n", filename)
+			fname, err := preprocessFilename(filename)
+			if err != nil {
+				return fmt.Errorf("could not create server config: %w", err)
+			}
+			// Write and rename, so that a name server never reads half a file.
+			if err := os.WriteFile(fname+".tmp", content, 0644); err != nil {
+				return fmt.Errorf("could not create server config: %w", err)
+			}
+			return os.Rename(fname+".tmp", fname)
+		},
+	}}, nil
+}
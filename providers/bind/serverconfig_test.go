@@ -0,0 +1,249 @@
+package bind
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+)
+
+func serverConfigProvider(t *testing.T, creds map[string]string, meta string) *bindProvider {
+	t.Helper()
+	p, err := initBind(creds, json.RawMessage(meta))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p.(*bindProvider)
+}
+
+func serverConfigDomains(names ...string) []*models.DomainConfig {
+	var dcs []*models.DomainConfig
+	for _, name := range names {
+		dc := &models.DomainConfig{Name: name, Metadata: map[string]string{}}
+		dc.UpdateSplitHorizonNames()
+		dcs = append(dcs, dc)
+	}
+	return dcs
+}
+
+func TestServerConfigTemplates(t *testing.T) {
+	var tests = []struct {
+		meta     string
+		expected string
+	}{
+		{
+			`{"server_config": {"format": "bind", "zone_directory": "/var/named", "secondaries": ["192.0.2.2"], "allow_transfer": ["198.51.100.0/24"]}}`,
+			`// Generated by dnscontrol. Do not edit.
+
+zone "example.com" {
+	type primary;
+	file "/var/named/example.com.zone";
+	notify explicit;
+	also-notify { 192.0.2.2; };
+	allow-transfer { 192.0.2.2; 198.51.100.0/24; };
+};
+
+zone "xn--bcher-kva.example" {
+	type primary;
+	file "/var/named/bücher.example.zone";
+	notify explicit;
+	also-notify { 192.0.2.2; };
+	allow-transfer { 192.0.2.2; 198.51.100.0/24; };
+};
+`,
+		},
+		{
+			`{"server_config": {"format": "bind", "zone_directory": "/var/named", "primaries": ["192.0.2.1", "2001:db8::1"], "tsig_key": "xfr"}}`,
+			`// Generated by dnscontrol. Do not edit.
+
+zone "example.com" {
+	type secondary;
+	file "/var/named/example.com.zone";
+	primaries { 192.0.2.1 key "xfr"; 2001:db8::1 key "xfr"; };
+};
+
+zone "xn--bcher-kva.example" {
+	type secondary;
+	file "/var/named/bücher.example.zone";
+	primaries { 192.0.2.1 key "xfr"; 2001:db8::1 key "xfr"; };
+};
+`,
+		},
+		{
+			`{"server_config": {"format": "nsd", "zone_directory": "/etc/nsd/zones", "secondaries": ["192.0.2.2"], "notify": ["192.0.2.3"], "tsig_key": "xfr"}}`,
+			`# Generated by dnscontrol. Do not edit.
+
+zone:
+	name: "example.com"
+	zonefile: "/etc/nsd/zones/example.com.zone"
+	notify: 192.0.2.2 xfr
+	notify: 192.0.2.3 xfr
+	provide-xfr: 192.0.2.2 xfr
+
+zone:
+	name: "xn--bcher-kva.example"
+	zonefile: "/etc/nsd/zones/bücher.example.zone"
+	notify: 192.0.2.2 xfr
+	notify: 192.0.2.3 xfr
+	provide-xfr: 192.0.2.2 xfr
+`,
+		},
+		{
+			`{"server_config": {"format": "nsd", "zone_directory": "/etc/nsd/zones", "primaries": ["192.0.2.1"]}}`,
+			`# Generated by dnscontrol. Do not edit.
+
+zone:
+	name: "example.com"
+	zonefile: "/etc/nsd/zones/example.com.zone"
+	allow-notify: 192.0.2.1 NOKEY
+	request-xfr: 192.0.2.1 NOKEY
+
+zone:
+	name: "xn--bcher-kva.example"
+	zonefile: "/etc/nsd/zones/bücher.example.zone"
+	allow-notify: 192.0.2.1 NOKEY
+	request-xfr: 192.0.2.1 NOKEY
+`,
+		},
+		{
+			`{"server_config": {"format": "knot", "zone_directory": "/var/lib/knot", "secondaries": ["192.0.2.2", "2001:db8::2"], "tsig_key": "xfr"}}`,
+			`# Generated by dnscontrol. Do not edit.
+
+remote:
+  - id: dnscontrol_192_0_2_2
+    address: 192.0.2.2
+    key: xfr
+  - id: dnscontrol_2001_db8__2
+    address: 2001:db8::2
+    key: xfr
+
+acl:
+  - id: dnscontrol_transfer
+    address: [192.0.2.2, 2001:db8::2]
+    key: xfr
+    action: transfer
+
+zone:
+  - domain: "example.com"
+    file: "/var/lib/knot/example.com.zone"
+    notify: [dnscontrol_192_0_2_2, dnscontrol_2001_db8__2]
+    acl: [dnscontrol_transfer]
+  - domain: "xn--bcher-kva.example"
+    file: "/var/lib/knot/bücher.example.zone"
+    notify: [dnscontrol_192_0_2_2, dnscontrol_2001_db8__2]
+    acl: [dnscontrol_transfer]
+`,
+		},
+		{
+			`{"server_config": {"format": "knot", "zone_directory": "/var/lib/knot", "primaries": ["192.0.2.1"]}}`,
+			`# Generated by dnscontrol. Do not edit.
+
+remote:
+  - id: dnscontrol_192_0_2_1
+    address: 192.0.2.1
+
+acl:
+  - id: dnscontrol_notify
+    address: [192.0.2.1]
+    action: notify
+
+zone:
+  - domain: "example.com"
+    file: "/var/lib/knot/example.com.zone"
+    master: [dnscontrol_192_0_2_1]
+    acl: [dnscontrol_notify]
+  - domain: "xn--bcher-kva.example"
+    file: "/var/lib/knot/bücher.example.zone"
+    master: [dnscontrol_192_0_2_1]
+    acl: [dnscontrol_notify]
+`,
+		},
+	}
+
+	for _, tst := range tests {
+		c := serverConfigProvider(t, map[string]string{}, tst.meta)
+		got, err := c.generateServerConfig(serverConfigDomains("example.com", "bücher.example"))
+		if err != nil {
+			t.Fatalf("%s: %v", tst.meta, err)
+		}
+		if string(got) != tst.expected {
+			t.Errorf("%s:\ngot:\n%s\nexpected:\n%s", tst.meta, got, tst.expected)
+		}
+	}
+}
+
+func TestServerConfigCorrections(t *testing.T) {
+	dir := t.TempDir()
+	c := serverConfigProvider(t,
+		map[string]string{"directory": dir, "keydirectory": filepath.Join(dir, "keys")},
+		`{"server_config": {"format": "nsd"}}`)
+
+	dcs := serverConfigDomains("example.com", "example.net")
+	dcs[1].AutoDNSSEC = "on"
+	corrections, err := c.GetServerConfigCorrections(dcs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(dir, "nsd.conf.dnscontrol")
+	if len(corrections) != 1 || corrections[0].Msg != "Write NSD configuration for 2 zones to "+filename {
+		t.Fatalf("corrections: %v", corrections)
+	}
+	if err := corrections[0].F(); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	abs, _ := filepath.Abs(dir)
+	expected := `# Generated by dnscontrol. Do not edit.
+
+zone:
+	name: "example.com"
+	zonefile: "` + abs + `/example.com.zone"
+
+zone:
+	name: "example.net"
+	zonefile: "` + abs + `/example.net.zone.signed"
+`
+	if string(content) != expected {
+		t.Errorf("got:\n%s\nexpected:\n%s", content, expected)
+	}
+
+	// Nothing to do if the configuration is up to date.
+	corrections, err = c.GetServerConfigCorrections(dcs)
+	if err != nil || len(corrections) != 0 {
+		t.Errorf("second run: %v %v", corrections, err)
+	}
+
+	// The server configuration is not a zone.
+	if err := os.WriteFile(filepath.Join(dir, "example.com.zone"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	zones, err := c.ListZones()
+	if err != nil || len(zones) != 1 || zones[0] != "example.com" {
+		t.Errorf("ListZones() = %v %v", zones, err)
+	}
+
+	// The same zone twice needs views, which aren't supported.
+	if _, err := c.GetServerConfigCorrections(serverConfigDomains("example.com!inside", "example.com!outside")); err == nil {
+		t.Error("no error for a zone with two tags")
+	}
+}
+
+func TestParseServerConfig(t *testing.T) {
+	for _, meta := range []string{
+		`{"server_config": {"format": "powerdns"}}`,
+		`{"server_config": {"primaries": ["192.0.2.1"]}}`,
+		`{"server_config": {"format": "bind", "primaries": ["ns1.example.com"]}}`,
+		`{"server_config": {"format": "bind", "secondaries": ["192.0.2.0/24"]}}`,
+		`{"server_config": {"format": "bind", "allow_transfer": ["any"]}}`,
+		`{"server_config": {"format": "bind", "tsig_key": "my key"}}`,
+	} {
+		if _, err := initBind(map[string]string{}, json.RawMessage(meta)); err == nil {
+			t.Errorf("%s: no error", meta)
+		}
+	}
+}
@@ -0,0 +1,357 @@
+package bind
+
+import (
+	"bytes"
+	"crypto"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+	"github.com/StackExchange/dnscontrol/v4/pkg/prettyzone"
+	"github.com/StackExchange/dnscontrol/v4/pkg/printer"
+	"github.com/miekg/dns"
+)
+
+// signedSuffix is appended to the name of a zone file to get the name
+// of its signed version.
+const signedSuffix = ".signed"
+
+// SigningDefaults contains the "signing" provider metadata.
+type SigningDefaults struct {
+	Validity   string `json:"validity"`   // How long signatures are valid.
+	Refresh    string `json:"refresh"`    // Re-sign when signatures expire within this.
+	NSEC3Param string `json:"nsec3param"` // Use NSEC3 with these parameters, e.g. "1 0 0 -".
+}
+
+const (
+	defaultSignatureValidity = 14 * 24 * time.Hour
+	defaultSignatureRefresh  = 7 * 24 * time.Hour
+)
+
+// parseSigning validates the signing settings of the provider.
+func (c *bindProvider) parseSigning() error {
+	var err error
+	c.validity = defaultSignatureValidity
+	if c.Signing.Validity != "" {
+		if c.validity, err = time.ParseDuration(c.Signing.Validity); err != nil {
+			return fmt.Errorf("signing validity: %w", err)
+		}
+	}
+	c.refresh = defaultSignatureRefresh
+	if c.Signing.Refresh != "" {
+		if c.refresh, err = time.ParseDuration(c.Signing.Refresh); err != nil {
+			return fmt.Errorf("signing refresh: %w", err)
+		}
+	}
+	if c.refresh <= 0 || c.refresh >= c.validity {
+		return fmt.Errorf("signing refresh (%v) must be positive and less than the validity (%v)", c.refresh, c.validity)
+	}
+	if c.Signing.NSEC3Param != "" {
+		rr, err := dns.NewRR(". 0 IN NSEC3PARAM " + c.Signing.NSEC3Param)
+		if err != nil || rr == nil {
+			return fmt.Errorf("signing nsec3param %q must look like \"1 0 0 -\"", c.Signing.NSEC3Param)
+		}
+		c.nsec3 = rr.(*dns.NSEC3PARAM)
+		if c.nsec3.Hash != dns.SHA1 || c.nsec3.Flags != 0 {
+			return fmt.Errorf("signing nsec3param %q: only hash algorithm 1 and flags 0 are supported", c.Signing.NSEC3Param)
+		}
+	}
+	return nil
+}
+
+// signs reports whether the provider signs dc.
+func (c *bindProvider) signs(dc *models.DomainConfig) bool {
+	return c.keydirectory != "" && dc.AutoDNSSEC == "on"
+}
+
+// signingCorrection returns a correction that writes the signed version
+// of the zone next to c.zonefile, or nil if it is up to date. changed
+// tells if the zone file is being rewritten.
+func (c *bindProvider) signingCorrection(dc *models.DomainConfig, changed bool) (*models.Correction, error) {
+	signedfile := c.zonefile + signedSuffix
+
+	if !c.signs(dc) {
+		if c.keydirectory == "" || dc.AutoDNSSEC != "off" {
+			return nil, nil
+		}
+		if _, err := os.Stat(signedfile); err != nil {
+			return nil, nil
+		}
+		return &models.Correction{
+			Msg: fmt.Sprintf("Remove signed zone %s", signedfile),
+			F:   func() error { return os.Remove(signedfile) },
+		}, nil
+	}
+
+	// Sign what is written to the zone file.
+	var buf bytes.Buffer
+	if err := prettyzone.WriteZoneFileRC(&buf, dc.Records, dc.Name, 0, nil); err != nil {
+		return nil, err
+	}
+	rrs, err := parseZone(&buf, dc.Name, c.zonefile)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := readSignedZone(signedfile, dc.Name)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := loadKeys(c.keydirectory, dc.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	var reason string
+	switch {
+	case len(keys) == 0:
+		reason = fmt.Sprintf("generate a key in %s", c.keydirectory)
+	case changed:
+		reason = "the records changed"
+	default:
+		reason = resignReason(previous, rrs, keys, c.nsec3, nowFunc(), c.refresh)
+		if reason == "" {
+			return nil, nil
+		}
+	}
+
+	return &models.Correction{
+		Msg: fmt.Sprintf("Sign zone %s: %s", signedfile, reason),
+		F: func() error {
+			soa := findSOA(rrs)
+			if len(keys) == 0 {
+				key, err := generateKey(c.keydirectory, dc.Name, soa.Hdr.Ttl)
+				if err != nil {
+					return fmt.Errorf("could not generate key: %w", err)
+				}
+				keys = []signingKey{key}
+			}
+			if prev := findSOA(previous); prev != nil && soa.Serial <= prev.Serial {
+				// The signed zone was re-signed since the zone file was
+				// last written. Its serial must not go backwards.
+				soa.Serial = generateSerial(prev.Serial)
+			}
+			now := nowFunc()
+			signed, err := signZone(dc.Name, rrs, keys, signOptions{
+				inception:  now.Add(-time.Hour),
+				expiration: now.Add(c.validity),
+				nsec3:      c.nsec3,
+			})
+			if err != nil {
+				return err
+			}
+			printer.Printf("WRITING SIGNED ZONEFILE: %v\n", signedfile)
+			return writeSignedZone(signedfile, signed, now)
+		},
+	}, nil
+}
+
+// resignReason returns why the signed zone previous must be rewritten
+// to match the zone rrs signed with keys, or "" if it is up to date.
+func resignReason(previous, rrs []dns.RR, keys []signingKey, nsec3 *dns.NSEC3PARAM, now time.Time, refresh time.Duration) string {
+	if previous == nil {
+		return "the signed zone does not exist"
+	}
+
+	// Compare the data that is signed, ignoring TTLs, which signZone
+	// may have changed to make RRsets consistent.
+	want := map[string]bool{}
+	for _, rr := range rrs {
+		want[rrKey(rr)] = true
+	}
+	for _, k := range keys {
+		want[rrKey(k.dnskey)] = true
+	}
+	have := map[string]bool{}
+	var param *dns.NSEC3PARAM
+	var expiration uint32
+	for _, rr := range previous {
+		switch rr := rr.(type) {
+		case *dns.RRSIG:
+			if expiration == 0 || rr.Expiration < expiration {
+				expiration = rr.Expiration
+			}
+		case *dns.NSEC3PARAM:
+			param = rr
+		case *dns.NSEC, *dns.NSEC3:
+		default:
+			have[rrKey(rr)] = true
+		}
+	}
+	if len(have) != len(want) {
+		return "the records or keys changed"
+	}
+	for k := range have {
+		if !want[k] {
+			return "the records or keys changed"
+		}
+	}
+
+	switch {
+	case nsec3 == nil && param != nil:
+		return "use NSEC instead of NSEC3"
+	case nsec3 != nil && (param == nil || nsec3paramString(param) != nsec3paramString(nsec3)):
+		return fmt.Sprintf("use NSEC3 with parameters %q", nsec3paramString(nsec3))
+	}
+
+	if exp := time.Unix(int64(expiration), 0); !exp.After(now.Add(refresh)) {
+		return fmt.Sprintf("signatures expire at %s", exp.UTC().Format(time.RFC3339))
+	}
+	return ""
+}
+
+// rrKey identifies a record regardless of its TTL. The SOA only counts
+// with its serial set to 0, as the serial of the signed zone is its own.
+func rrKey(rr dns.RR) string {
+	rr = dns.Copy(rr)
+	rr.Header().Ttl = 0
+	rr.Header().Name = dns.CanonicalName(rr.Header().Name)
+	if soa, ok := rr.(*dns.SOA); ok {
+		soa.Serial = 0
+	}
+	return rr.String()
+}
+
+func nsec3paramString(p *dns.NSEC3PARAM) string {
+	salt := p.Salt
+	if salt == "" {
+		salt = "-"
+	}
+	return fmt.Sprintf("%d %d %d %s", p.Hash, p.Flags, p.Iterations, strings.ToLower(salt))
+}
+
+func findSOA(rrs []dns.RR) *dns.SOA {
+	for _, rr := range rrs {
+		if soa, ok := rr.(*dns.SOA); ok {
+			return soa
+		}
+	}
+	return nil
+}
+
+// parseZone returns the records of a zone file.
+func parseZone(r io.Reader, origin, filename string) ([]dns.RR, error) {
+	zp := dns.NewZoneParser(r, dns.Fqdn(origin), filename)
+	var rrs []dns.RR
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		rrs = append(rrs, rr)
+	}
+	if err := zp.Err(); err != nil {
+		return nil, fmt.Errorf("error while parsing '%v': %w", filename, err)
+	}
+	return rrs, nil
+}
+
+// readSignedZone returns the records of a signed zone file, or nil if
+// it doesn't exist.
+func readSignedZone(filename, origin string) ([]dns.RR, error) {
+	f, err := os.Open(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseZone(f, origin, filename)
+}
+
+func writeSignedZone(filename string, rrs []dns.RR, now time.Time) error {
+	fname, err := preprocessFilename(filename)
+	if err != nil {
+		return fmt.Errorf("could not create signed zonefile: %w", err)
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "; signed with dnscontrol %s\n", now.Format(time.RFC3339))
+	for _, rr := range rrs {
+		fmt.Fprintln(&buf, rr.String())
+	}
+	// Write and rename, so that a name server never reads half a zone.
+	tmp := fname + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("could not create signed zonefile: %w", err)
+	}
+	return os.Rename(tmp, fname)
+}
+
+// loadKeys returns the keys of zone in dir. The keys are in BIND's
+// format, as written by dnssec-keygen: a DNSKEY in
+// K<zone>.+<algorithm>+<keytag>.key and its private key in the
+// matching .private file.
+func loadKeys(dir, zone string) ([]signingKey, error) {
+	origin := dns.CanonicalName(zone)
+	names, err := filepath.Glob(filepath.Join(dir, "K"+origin+"+*.key"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	var keys []signingKey
+	for _, name := range names {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		rrs, err := parseZone(f, origin, name)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		if len(rrs) != 1 || rrs[0].Header().Rrtype != dns.TypeDNSKEY || dns.CanonicalName(rrs[0].Header().Name) != origin {
+			return nil, fmt.Errorf("%s must contain a single DNSKEY for %s", name, origin)
+		}
+		dnskey := rrs[0].(*dns.DNSKEY)
+
+		privname := strings.TrimSuffix(name, ".key") + ".private"
+		f, err = os.Open(privname)
+		if err != nil {
+			return nil, err
+		}
+		priv, err := dnskey.ReadPrivateKey(f, privname)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", privname, err)
+		}
+		signer, ok := priv.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("%s: unsupported private key", privname)
+		}
+		keys = append(keys, signingKey{dnskey: dnskey, priv: signer})
+	}
+	return keys, nil
+}
+
+// generateKey creates an ECDSAP256SHA256 combined signing key (CSK) for
+// zone in dir.
+func generateKey(dir, zone string, ttl uint32) (signingKey, error) {
+	origin := dns.CanonicalName(zone)
+	dnskey := &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: origin, Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: ttl},
+		Flags:     dns.ZONE | dns.SEP,
+		Protocol:  3,
+		Algorithm: dns.ECDSAP256SHA256,
+	}
+	priv, err := dnskey.Generate(256)
+	if err != nil {
+		return signingKey{}, err
+	}
+	base := filepath.Join(dir, fmt.Sprintf("K%s+%03d+%05d", origin, dnskey.Algorithm, dnskey.KeyTag()))
+	fname, err := preprocessFilename(base + ".private")
+	if err != nil {
+		return signingKey{}, err
+	}
+	if err := os.WriteFile(fname, []byte(dnskey.PrivateKeyString(priv)), 0600); err != nil {
+		return signingKey{}, err
+	}
+	pub := fmt.Sprintf("; This is a key-signing key, keyid %d, for %s\n%s\n", dnskey.KeyTag(), origin, dnskey)
+	if err := os.WriteFile(filepath.FromSlash(base+".key"), []byte(pub), 0644); err != nil {
+		return signingKey{}, err
+	}
+	printer.Printf("GENERATED KEY: %s.key\n", base)
+	return signingKey{dnskey: dnskey, priv: priv.(crypto.Signer)}, nil
+}
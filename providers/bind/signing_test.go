@@ -0,0 +1,163 @@
+package bind
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+	"github.com/miekg/dns"
+)
+
+func testDomain(ips ...string) *models.DomainConfig {
+	dc := &models.DomainConfig{Name: "example.com", AutoDNSSEC: "on", Metadata: map[string]string{}}
+	for _, ip := range ips {
+		rc := &models.RecordConfig{Type: "A", TTL: 300}
+		rc.SetLabel("www", "example.com")
+		rc.SetTarget(ip)
+		dc.Records = append(dc.Records, rc)
+	}
+	dc.UpdateSplitHorizonNames()
+	return dc
+}
+
+// run computes the corrections of dc like a push does, runs them and
+// returns their messages.
+func run(t *testing.T, c *bindProvider, dc *models.DomainConfig) []string {
+	t.Helper()
+	found, err := c.GetZoneRecords(dc.Name, dc.Metadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+	corrections, err := c.GetZoneRecordsCorrections(dc, found)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msgs []string
+	for _, corr := range corrections {
+		msgs = append(msgs, corr.Msg)
+		if err := corr.F(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return msgs
+}
+
+func signedSerial(t *testing.T, c *bindProvider) uint32 {
+	t.Helper()
+	rrs, err := readSignedZone(c.zonefile+signedSuffix, "example.com")
+	if err != nil || rrs == nil {
+		t.Fatalf("reading the signed zone: %v", err)
+	}
+	return findSOA(rrs).Serial
+}
+
+func TestSigningCorrections(t *testing.T) {
+	dir := t.TempDir()
+	keydir := filepath.Join(dir, "keys")
+	if err := os.Mkdir(keydir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	p, err := initBind(map[string]string{"directory": dir, "keydirectory": keydir}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := p.(*bindProvider)
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	nowFunc = func() time.Time { return now }
+	defer func() { nowFunc = time.Now }()
+
+	// The first push writes the zone, generates a key and signs.
+	msgs := run(t, c, testDomain("192.0.2.1"))
+	if len(msgs) != 2 || !strings.HasPrefix(msgs[1], "Sign zone "+c.zonefile+".signed: generate a key in") {
+		t.Fatalf("first push: %q", msgs)
+	}
+	keys, err := loadKeys(keydir, "example.com")
+	if err != nil || len(keys) != 1 || !keys[0].isKSK() {
+		t.Fatalf("keys: %v %v", keys, err)
+	}
+	if got := signedSerial(t, c); got != 2026101800 {
+		t.Errorf("signed serial = %d, want the serial of the zone file", got)
+	}
+
+	// Nothing to do until the signatures approach their expiry.
+	if msgs := run(t, c, testDomain("192.0.2.1")); len(msgs) != 0 {
+		t.Errorf("second push: %q", msgs)
+	}
+	now = now.Add(defaultSignatureValidity - defaultSignatureRefresh - 2*time.Hour)
+	if msgs := run(t, c, testDomain("192.0.2.1")); len(msgs) != 0 {
+		t.Errorf("push before the refresh: %q", msgs)
+	}
+	now = now.Add(2 * time.Hour)
+	msgs = run(t, c, testDomain("192.0.2.1"))
+	if len(msgs) != 1 || !strings.Contains(msgs[0], ": signatures expire at 2026-11-01T12:00:00Z") {
+		t.Errorf("push at the refresh: %q", msgs)
+	}
+	if got := signedSerial(t, c); got != 2026102500 {
+		t.Errorf("re-signed serial = %d, want a new serial", got)
+	}
+
+	// A change of the records is signed with a serial above both.
+	msgs = run(t, c, testDomain("192.0.2.1", "192.0.2.2"))
+	if len(msgs) != 2 || !strings.HasSuffix(msgs[1], ": the records changed") {
+		t.Errorf("push of a change: %q", msgs)
+	}
+	if got := signedSerial(t, c); got != 2026102501 {
+		t.Errorf("signed serial after a change = %d, want 2026102501", got)
+	}
+
+	// A new key, from dnssec-keygen for example, is picked up.
+	if _, err := generateKey(keydir, "example.com", 3600); err != nil {
+		t.Fatal(err)
+	}
+	msgs = run(t, c, testDomain("192.0.2.1", "192.0.2.2"))
+	if len(msgs) != 1 || !strings.HasSuffix(msgs[0], ": the records or keys changed") {
+		t.Errorf("push with a new key: %q", msgs)
+	}
+
+	// Switching to NSEC3 re-signs.
+	c.nsec3 = &dns.NSEC3PARAM{Hash: dns.SHA1}
+	msgs = run(t, c, testDomain("192.0.2.1", "192.0.2.2"))
+	if len(msgs) != 1 || !strings.HasSuffix(msgs[0], `: use NSEC3 with parameters "1 0 0 -"`) {
+		t.Errorf("push with NSEC3: %q", msgs)
+	}
+	if msgs := run(t, c, testDomain("192.0.2.1", "192.0.2.2")); len(msgs) != 0 {
+		t.Errorf("push after NSEC3: %q", msgs)
+	}
+
+	// DNSSEC off removes the signed zone.
+	dc := testDomain("192.0.2.1", "192.0.2.2")
+	dc.AutoDNSSEC = "off"
+	msgs = run(t, c, dc)
+	if len(msgs) != 1 || msgs[0] != "Remove signed zone "+c.zonefile+".signed" {
+		t.Errorf("push with DNSSEC off: %q", msgs)
+	}
+	if _, err := os.Stat(c.zonefile + signedSuffix); !os.IsNotExist(err) {
+		t.Errorf("signed zone still exists: %v", err)
+	}
+}
+
+func TestParseSigning(t *testing.T) {
+	for _, meta := range []string{
+		`{"signing": {"validity": "2 weeks"}}`,
+		`{"signing": {"refresh": "400h"}}`,
+		`{"signing": {"nsec3param": "1 0"}}`,
+		`{"signing": {"nsec3param": "2 0 0 -"}}`,
+	} {
+		if _, err := initBind(map[string]string{}, json.RawMessage(meta)); err == nil {
+			t.Errorf("%s: no error", meta)
+		}
+	}
+
+	p, err := initBind(map[string]string{}, json.RawMessage(`{"signing": {"validity": "720h", "refresh": "240h", "nsec3param": "1 0 0 AABB"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := p.(*bindProvider)
+	if c.validity != 720*time.Hour || c.refresh != 240*time.Hour || nsec3paramString(c.nsec3) != "1 0 0 aabb" {
+		t.Errorf("got %v %v %v", c.validity, c.refresh, c.nsec3)
+	}
+}
@@ -0,0 +1,338 @@
+package bind
+
+import (
+	"crypto"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// signingKey is a DNSKEY and its private key.
+type signingKey struct {
+	dnskey *dns.DNSKEY
+	priv   crypto.Signer
+}
+
+func (k signingKey) isKSK() bool {
+	return k.dnskey.Flags&dns.SEP != 0
+}
+
+// signOptions controls how signZone signs a zone.
+type signOptions struct {
+	inception  time.Time
+	expiration time.Time
+	nsec3      *dns.NSEC3PARAM // nil for NSEC.
+}
+
+type rrsetKey struct {
+	name   string
+	rrtype uint16
+}
+
+// signZone returns the records of a zone with the DNSKEY, NSEC or NSEC3
+// and RRSIG records that sign it with keys. rrs must contain the SOA.
+// Names below a delegation (glue) are neither signed nor part of the
+// NSEC(3) chain. Any existing DNSSEC records other than DNSKEY and DS
+// are dropped.
+func signZone(origin string, rrs []dns.RR, keys []signingKey, opts signOptions) ([]dns.RR, error) {
+	origin = dns.CanonicalName(origin)
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no keys to sign %s with", origin)
+	}
+
+	// Group the records in RRsets.
+	sets := map[rrsetKey][]dns.RR{}
+	var soa *dns.SOA
+	add := func(rr dns.RR) {
+		rr = dns.Copy(rr)
+		rr.Header().Name = dns.CanonicalName(rr.Header().Name)
+		k := rrsetKey{rr.Header().Name, rr.Header().Rrtype}
+		for _, other := range sets[k] {
+			if dns.IsDuplicate(other, rr) {
+				return
+			}
+		}
+		sets[k] = append(sets[k], rr)
+	}
+	for _, rr := range rrs {
+		switch rr.Header().Rrtype {
+		case dns.TypeRRSIG, dns.TypeNSEC, dns.TypeNSEC3, dns.TypeNSEC3PARAM:
+			continue
+		case dns.TypeSOA:
+			soa = rr.(*dns.SOA)
+		}
+		if !dns.IsSubDomain(origin, dns.CanonicalName(rr.Header().Name)) {
+			return nil, fmt.Errorf("%s is not in zone %s", rr.Header().Name, origin)
+		}
+		add(rr)
+	}
+	if soa == nil {
+		return nil, fmt.Errorf("zone %s has no SOA", origin)
+	}
+	for _, k := range keys {
+		add(k.dnskey)
+	}
+	if opts.nsec3 != nil {
+		p := *opts.nsec3
+		p.Hdr = dns.RR_Header{Name: origin, Rrtype: dns.TypeNSEC3PARAM, Class: dns.ClassINET, Ttl: 0}
+		add(&p)
+	}
+
+	// Every RR in an RRset must have the same TTL for the signature
+	// to cover them all.
+	for _, set := range sets {
+		for _, rr := range set[1:] {
+			rr.Header().Ttl = set[0].Header().Ttl
+		}
+	}
+
+	// Find the delegations. Names below them are glue.
+	cuts := map[string]bool{}
+	for k := range sets {
+		if k.rrtype == dns.TypeNS && k.name != origin {
+			cuts[k.name] = true
+		}
+	}
+	isGlue := func(name string) bool {
+		for n := name; n != origin; {
+			i, end := dns.NextLabel(n, 0)
+			if end {
+				break
+			}
+			n = n[i:]
+			if cuts[n] {
+				return true
+			}
+		}
+		return false
+	}
+
+	types := map[string][]uint16{}
+	for k := range sets {
+		if !isGlue(k.name) {
+			types[k.name] = append(types[k.name], k.rrtype)
+		}
+	}
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return canonicalLess(names[i], names[j]) })
+
+	negTTL := soa.Minttl
+	if soa.Hdr.Ttl < negTTL {
+		negTTL = soa.Hdr.Ttl
+	}
+
+	// Build the NSEC or NSEC3 chain.
+	if opts.nsec3 == nil {
+		for i, name := range names {
+			bitmap := append(types[name], dns.TypeNSEC, dns.TypeRRSIG)
+			nsec := &dns.NSEC{
+				Hdr:        dns.RR_Header{Name: name, Rrtype: dns.TypeNSEC, Class: dns.ClassINET, Ttl: negTTL},
+				NextDomain: names[(i+1)%len(names)],
+				TypeBitMap: sortedTypes(bitmap),
+			}
+			sets[rrsetKey{name, dns.TypeNSEC}] = []dns.RR{nsec}
+		}
+	} else {
+		for _, nsec3 := range nsec3Chain(origin, names, types, cuts, negTTL, opts.nsec3) {
+			sets[rrsetKey{nsec3.Hdr.Name, dns.TypeNSEC3}] = []dns.RR{nsec3}
+		}
+	}
+
+	// Sign the authoritative RRsets. The DNSKEY set is signed with the
+	// KSKs, everything else with the ZSKs. Keys of either kind do both
+	// if the other kind is missing.
+	var ksks, zsks []signingKey
+	for _, k := range keys {
+		if k.isKSK() {
+			ksks = append(ksks, k)
+		} else {
+			zsks = append(zsks, k)
+		}
+	}
+	if len(ksks) == 0 {
+		ksks = zsks
+	}
+	if len(zsks) == 0 {
+		zsks = ksks
+	}
+
+	var out []dns.RR
+	var sigs []dns.RR
+	for k, set := range sets {
+		out = append(out, set...)
+		if isGlue(k.name) {
+			continue
+		}
+		if cuts[k.name] && k.rrtype != dns.TypeDS && k.rrtype != dns.TypeNSEC {
+			continue
+		}
+		signers := zsks
+		if k.rrtype == dns.TypeDNSKEY && k.name == origin {
+			signers = ksks
+		}
+		for _, key := range signers {
+			sig := &dns.RRSIG{
+				Hdr:        dns.RR_Header{Ttl: set[0].Header().Ttl},
+				Algorithm:  key.dnskey.Algorithm,
+				KeyTag:     key.dnskey.KeyTag(),
+				SignerName: origin,
+				Inception:  uint32(opts.inception.Unix()),
+				Expiration: uint32(opts.expiration.Unix()),
+			}
+			if err := sig.Sign(key.priv, set); err != nil {
+				return nil, fmt.Errorf("signing %s %s: %w", k.name, dns.TypeToString[k.rrtype], err)
+			}
+			sigs = append(sigs, sig)
+		}
+	}
+	out = append(out, sigs...)
+
+	sortRRs(out)
+	return out, nil
+}
+
+// nsec3Chain returns the NSEC3 records of a zone with the authoritative
+// names (and delegations) names and their types.
+func nsec3Chain(origin string, names []string, types map[string][]uint16, cuts map[string]bool, ttl uint32, param *dns.NSEC3PARAM) []*dns.NSEC3 {
+	// Empty non-terminals get an NSEC3 record with no types.
+	bitmaps := map[string][]uint16{}
+	for _, name := range names {
+		t := types[name]
+		if !cuts[name] || containsType(t, dns.TypeDS) {
+			t = append(t, dns.TypeRRSIG)
+		}
+		bitmaps[name] = sortedTypes(t)
+		for n := name; n != origin; {
+			i, end := dns.NextLabel(n, 0)
+			if end {
+				break
+			}
+			n = n[i:]
+			if _, ok := bitmaps[n]; !ok && n != origin {
+				bitmaps[n] = nil
+			}
+		}
+	}
+
+	type hashed struct {
+		hash   string
+		bitmap []uint16
+	}
+	var chain []hashed
+	for name, bitmap := range bitmaps {
+		chain = append(chain, hashed{
+			hash:   dns.HashName(name, param.Hash, param.Iterations, param.Salt),
+			bitmap: bitmap,
+		})
+	}
+	sort.Slice(chain, func(i, j int) bool { return chain[i].hash < chain[j].hash })
+
+	var out []*dns.NSEC3
+	for i, h := range chain {
+		next := chain[(i+1)%len(chain)].hash
+		out = append(out, &dns.NSEC3{
+			Hdr:        dns.RR_Header{Name: strings.ToLower(h.hash) + "." + origin, Rrtype: dns.TypeNSEC3, Class: dns.ClassINET, Ttl: ttl},
+			Hash:       param.Hash,
+			Flags:      0,
+			Iterations: param.Iterations,
+			SaltLength: param.SaltLength,
+			Salt:       param.Salt,
+			HashLength: 20,
+			NextDomain: next,
+			TypeBitMap: h.bitmap,
+		})
+	}
+	return out
+}
+
+func containsType(types []uint16, t uint16) bool {
+	for _, x := range types {
+		if x == t {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedTypes(types []uint16) []uint16 {
+	out := append([]uint16(nil), types...)
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+// canonicalLess reports whether a sorts before b in the canonical order
+// of RFC 4034 section 6.1: label by label from the right, comparing
+// the lowercased octets of the labels.
+func canonicalLess(a, b string) bool {
+	la := dns.SplitDomainName(a)
+	lb := dns.SplitDomainName(b)
+	for i, j := len(la)-1, len(lb)-1; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		if x, y := labelOctets(la[i]), labelOctets(lb[j]); x != y {
+			return x < y
+		}
+	}
+	return len(la) < len(lb)
+}
+
+// labelOctets returns the lowercased octets of a label in presentation
+// format, decoding \X and \DDD.
+func labelOctets(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c != '\\' || i+1 == len(s):
+		case i+3 < len(s) && isDigit(s[i+1]) && isDigit(s[i+2]) && isDigit(s[i+3]):
+			c = (s[i+1]-'0')*100 + (s[i+2]-'0')*10 + (s[i+3] - '0')
+			i += 3
+		default:
+			c = s[i+1]
+			i++
+		}
+		if c >= 'A' && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// sortRRs sorts records by name in canonical order, with the SOA first
+// and each RRSIG after the RRset it covers.
+func sortRRs(rrs []dns.RR) {
+	typeOf := func(rr dns.RR) (uint16, bool) {
+		if sig, ok := rr.(*dns.RRSIG); ok {
+			return sig.TypeCovered, true
+		}
+		return rr.Header().Rrtype, false
+	}
+	sort.SliceStable(rrs, func(i, j int) bool {
+		a, b := rrs[i], rrs[j]
+		an, bn := a.Header().Name, b.Header().Name
+		if !strings.EqualFold(an, bn) {
+			return canonicalLess(an, bn)
+		}
+		at, asig := typeOf(a)
+		bt, bsig := typeOf(b)
+		if at != bt {
+			if at == dns.TypeSOA || bt == dns.TypeSOA {
+				return at == dns.TypeSOA
+			}
+			return at < bt
+		}
+		if asig != bsig {
+			return bsig
+		}
+		return a.String() < b.String()
+	})
+}
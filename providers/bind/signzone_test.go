@@ -0,0 +1,238 @@
+package bind
+
+import (
+	"crypto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const testZone = `
+example.com.          3600 IN SOA   ns1.example.com. hostmaster.example.com. 1 3600 600 604800 1440
+example.com.          3600 IN NS    ns1.example.com.
+example.com.           300 IN MX    10 mail.example.com.
+ns1.example.com.       300 IN A     192.0.2.1
+www.example.com.       300 IN A     192.0.2.2
+www.example.com.       600 IN A     192.0.2.3
+*.wild.example.com.    300 IN TXT   "wildcard"
+a.b.c.example.com.     300 IN A     192.0.2.4
+sub.example.com.      3600 IN NS    ns.sub.example.com.
+sub.example.com.      3600 IN DS    12345 13 2 0123456789ABCDEF0123456789ABCDEF0123456789ABCDEF0123456789ABCDEF
+ns.sub.example.com.   3600 IN A     192.0.2.5
+insecure.example.com. 3600 IN NS    ns.example.net.
+`
+
+func testRRs(t *testing.T) []dns.RR {
+	t.Helper()
+	rrs, err := parseZone(strings.NewReader(testZone), "example.com", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rrs
+}
+
+func testKey(t *testing.T, flags uint16) signingKey {
+	t.Helper()
+	dnskey := &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: "example.com.", Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: 3600},
+		Flags:     flags,
+		Protocol:  3,
+		Algorithm: dns.ECDSAP256SHA256,
+	}
+	priv, err := dnskey.Generate(256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signingKey{dnskey: dnskey, priv: priv.(crypto.Signer)}
+}
+
+// verify checks that every RRset of signed that must be signed is, by
+// the expected keys, and that nothing else is. It returns the RRsets.
+func verify(t *testing.T, signed []dns.RR, ksks, zsks []signingKey, now time.Time) map[rrsetKey][]dns.RR {
+	t.Helper()
+	sets := map[rrsetKey][]dns.RR{}
+	sigs := map[rrsetKey][]*dns.RRSIG{}
+	for _, rr := range signed {
+		if sig, ok := rr.(*dns.RRSIG); ok {
+			k := rrsetKey{sig.Hdr.Name, sig.TypeCovered}
+			sigs[k] = append(sigs[k], sig)
+			continue
+		}
+		k := rrsetKey{rr.Header().Name, rr.Header().Rrtype}
+		sets[k] = append(sets[k], rr)
+	}
+
+	unsigned := map[rrsetKey]bool{
+		{"sub.example.com.", dns.TypeNS}:      true,
+		{"ns.sub.example.com.", dns.TypeA}:    true,
+		{"insecure.example.com.", dns.TypeNS}: true,
+	}
+	for k, set := range sets {
+		if unsigned[k] {
+			if len(sigs[k]) != 0 {
+				t.Errorf("%s %s must not be signed", k.name, dns.TypeToString[k.rrtype])
+			}
+			continue
+		}
+		signers := zsks
+		if k.rrtype == dns.TypeDNSKEY {
+			signers = ksks
+		}
+		if len(sigs[k]) != len(signers) {
+			t.Errorf("%s %s has %d signatures, want %d", k.name, dns.TypeToString[k.rrtype], len(sigs[k]), len(signers))
+			continue
+		}
+		for i, sig := range sigs[k] {
+			if err := sig.Verify(signers[i].dnskey, set); err != nil {
+				t.Errorf("%s %s: %v", k.name, dns.TypeToString[k.rrtype], err)
+			}
+			if !sig.ValidityPeriod(now) {
+				t.Errorf("%s %s: signature not valid now", k.name, dns.TypeToString[k.rrtype])
+			}
+		}
+	}
+	return sets
+}
+
+func TestSignZoneNSEC(t *testing.T) {
+	csk := testKey(t, dns.ZONE|dns.SEP)
+	now := time.Now()
+	signed, err := signZone("example.com", testRRs(t), []signingKey{csk}, signOptions{
+		inception:  now.Add(-time.Hour),
+		expiration: now.Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signed[0].Header().Rrtype != dns.TypeSOA {
+		t.Errorf("first record is %v, want the SOA", signed[0])
+	}
+	sets := verify(t, signed, []signingKey{csk}, []signingKey{csk}, now)
+
+	// The RRset with different TTLs gets the TTL of its first record.
+	for _, rr := range sets[rrsetKey{"www.example.com.", dns.TypeA}] {
+		if rr.Header().Ttl != 300 {
+			t.Errorf("%v: want TTL 300", rr)
+		}
+	}
+
+	// The NSEC chain covers the authoritative names in canonical order.
+	want := []string{
+		"example.com.", "a.b.c.example.com.", "insecure.example.com.",
+		"ns1.example.com.", "sub.example.com.", "*.wild.example.com.", "www.example.com.",
+	}
+	var got []string
+	next := map[string]string{}
+	bitmaps := map[string]string{}
+	for _, rr := range signed {
+		if nsec, ok := rr.(*dns.NSEC); ok {
+			got = append(got, nsec.Hdr.Name)
+			next[nsec.Hdr.Name] = nsec.NextDomain
+			bitmaps[nsec.Hdr.Name] = strings.Join(strings.Fields(nsec.String())[5:], " ")
+			if nsec.Hdr.Ttl != 1440 {
+				t.Errorf("%v: want the TTL of the SOA minimum", nsec)
+			}
+		}
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("NSEC owners = %v, want %v", got, want)
+	}
+	for i, name := range want {
+		if n := next[name]; n != want[(i+1)%len(want)] {
+			t.Errorf("NSEC of %s points to %s, want %s", name, n, want[(i+1)%len(want)])
+		}
+	}
+	for name, bitmap := range map[string]string{
+		"example.com.":          "NS SOA MX RRSIG NSEC DNSKEY",
+		"sub.example.com.":      "NS DS RRSIG NSEC",
+		"insecure.example.com.": "NS RRSIG NSEC",
+	} {
+		if bitmaps[name] != bitmap {
+			t.Errorf("NSEC bitmap of %s = %q, want %q", name, bitmaps[name], bitmap)
+		}
+	}
+}
+
+func TestSignZoneNSEC3(t *testing.T) {
+	ksk := testKey(t, dns.ZONE|dns.SEP)
+	zsk := testKey(t, dns.ZONE)
+	param := &dns.NSEC3PARAM{Hash: dns.SHA1, Iterations: 0}
+	now := time.Now()
+	signed, err := signZone("example.com", testRRs(t), []signingKey{ksk, zsk}, signOptions{
+		inception:  now.Add(-time.Hour),
+		expiration: now.Add(time.Hour),
+		nsec3:      param,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sets := verify(t, signed, []signingKey{ksk}, []signingKey{zsk}, now)
+
+	if len(sets[rrsetKey{"example.com.", dns.TypeNSEC3PARAM}]) != 1 {
+		t.Error("missing NSEC3PARAM")
+	}
+	if len(sets[rrsetKey{"example.com.", dns.TypeNSEC}]) != 0 {
+		t.Error("NSEC in an NSEC3 zone")
+	}
+
+	// The 7 names of the NSEC test plus the empty non-terminals
+	// b.c.example.com, c.example.com and wild.example.com.
+	names := []string{
+		"example.com.", "a.b.c.example.com.", "b.c.example.com.", "c.example.com.", "insecure.example.com.",
+		"ns1.example.com.", "sub.example.com.", "wild.example.com.", "*.wild.example.com.", "www.example.com.",
+	}
+	hashes := map[string]string{}
+	for _, name := range names {
+		hashes[strings.ToLower(dns.HashName(name, dns.SHA1, 0, ""))+".example.com."] = name
+	}
+	var n int
+	for k, set := range sets {
+		if k.rrtype != dns.TypeNSEC3 {
+			continue
+		}
+		n++
+		nsec3 := set[0].(*dns.NSEC3)
+		name, ok := hashes[k.name]
+		if !ok {
+			t.Errorf("unexpected NSEC3 %v", nsec3)
+			continue
+		}
+		if !nsec3.Match(name) {
+			t.Errorf("NSEC3 %v does not match %s", nsec3, name)
+		}
+		if name == "c.example.com." && len(nsec3.TypeBitMap) != 0 {
+			t.Errorf("empty non-terminal %s has types %v", name, nsec3.TypeBitMap)
+		}
+		if name == "insecure.example.com." && fmtTypes(nsec3.TypeBitMap) != "NS" {
+			t.Errorf("unsigned delegation %s has types %v", name, nsec3.TypeBitMap)
+		}
+	}
+	if n != len(names) {
+		t.Errorf("got %d NSEC3 records, want %d", n, len(names))
+	}
+}
+
+func fmtTypes(types []uint16) string {
+	var s []string
+	for _, t := range types {
+		s = append(s, dns.TypeToString[t])
+	}
+	return strings.Join(s, " ")
+}
+
+func TestCanonicalLess(t *testing.T) {
+	// The example of RFC 4034 section 6.1, and an escaped uppercase label.
+	order := []string{
+		"example.", "a.example.", "yljkjljk.a.example.", "Z.a.example.",
+		"zABC.a.EXAMPLE.", "z.example.", "\\001.z.example.", "*.z.example.", "\\200.z.example.", "\\201.\\090.example.",
+	}
+	for i := range order {
+		for j := range order {
+			if got := canonicalLess(order[i], order[j]); got != (i < j) {
+				t.Errorf("canonicalLess(%q, %q) = %v", order[i], order[j], got)
+			}
+		}
+	}
+}
@@ -4,6 +4,8 @@ package providers
 
 import (
 	"log"
+
+	"github.com/StackExchange/dnscontrol/v4/pkg/ratelimit"
 )
 
 // Capability is a bitmasked set of "features" that a provider supports. Only use constants from this package.
@@ -69,6 +71,9 @@ const (
 	// CanUseRoute53Alias indicates the provider support the specific R53_ALIAS records that only the Route53 provider supports
 	CanUseRoute53Alias
 
+	// CanUseRoute53Routing indicates the provider supports the R53_WEIGHT, R53_LATENCY_REGION, R53_FAILOVER, R53_GEO and R53_MULTIVALUE routing policies that only the Route53 provider supports
+	CanUseRoute53Routing
+
 	// CanUseSOA indicates the provider supports full management of a zone's SOA record
 	CanUseSOA
 
@@ -140,6 +145,8 @@ func unwrapProviderCapabilities(pName string, meta []ProviderMetadata) {
 				Notes[pName][k] = v
 				providerCapabilities[pName][k] = v.HasFeature
 			}
+		case RateLimit:
+			rateLimits[pName] = ratelimit.Config(x)
 		default:
 			log.Fatalf("Unrecognized ProviderMetadata type: %T", pm)
 		}
@@ -24,20 +24,21 @@ func _() {
 	_ = x[CanUseNAPTR-13]
 	_ = x[CanUsePTR-14]
 	_ = x[CanUseRoute53Alias-15]
-	_ = x[CanUseSOA-16]
-	_ = x[CanUseSRV-17]
-	_ = x[CanUseSSHFP-18]
-	_ = x[CanUseSVCB-19]
-	_ = x[CanUseTLSA-20]
-	_ = x[CanUseDNSKEY-21]
-	_ = x[DocCreateDomains-22]
-	_ = x[DocDualHost-23]
-	_ = x[DocOfficiallySupported-24]
+	_ = x[CanUseRoute53Routing-16]
+	_ = x[CanUseSOA-17]
+	_ = x[CanUseSRV-18]
+	_ = x[CanUseSSHFP-19]
+	_ = x[CanUseSVCB-20]
+	_ = x[CanUseTLSA-21]
+	_ = x[CanUseDNSKEY-22]
+	_ = x[DocCreateDomains-23]
+	_ = x[DocDualHost-24]
+	_ = x[DocOfficiallySupported-25]
 }
 
-const _Capability_name = "CanAutoDNSSECCanConcurCanGetZonesCanUseAKAMAICDNCanUseAliasCanUseAzureAliasCanUseCAACanUseDHCIDCanUseDNAMECanUseDSCanUseDSForChildrenCanUseHTTPSCanUseLOCCanUseNAPTRCanUsePTRCanUseRoute53AliasCanUseSOACanUseSRVCanUseSSHFPCanUseSVCBCanUseTLSACanUseDNSKEYDocCreateDomainsDocDualHostDocOfficiallySupported"
+const _Capability_name = "CanAutoDNSSECCanConcurCanGetZonesCanUseAKAMAICDNCanUseAliasCanUseAzureAliasCanUseCAACanUseDHCIDCanUseDNAMECanUseDSCanUseDSForChildrenCanUseHTTPSCanUseLOCCanUseNAPTRCanUsePTRCanUseRoute53AliasCanUseRoute53RoutingCanUseSOACanUseSRVCanUseSSHFPCanUseSVCBCanUseTLSACanUseDNSKEYDocCreateDomainsDocDualHostDocOfficiallySupported"
 
-var _Capability_index = [...]uint16{0, 13, 22, 33, 48, 59, 75, 84, 95, 106, 114, 133, 144, 153, 164, 173, 191, 200, 209, 220, 230, 240, 252, 268, 279, 301}
+var _Capability_index = [...]uint16{0, 13, 22, 33, 48, 59, 75, 84, 95, 106, 114, 133, 144, 153, 164, 173, 191, 211, 220, 229, 240, 250, 260, 272, 288, 299, 321}
 
 func (i Capability) String() string {
 	if i >= Capability(len(_Capability_index)-1) {
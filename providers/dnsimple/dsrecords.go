@@ -0,0 +1,140 @@
+package dnsimple
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+	dnsimpleapi "github.com/dnsimple/dnsimple-go/dnsimple"
+)
+
+// GetRegistrarDSCorrections returns corrections that update the DS
+// records of a domain registered with DNSimple to ds.
+func (c *dnsimpleProvider) GetRegistrarDSCorrections(dc *models.DomainConfig, ds models.Records) ([]*models.Correction, error) {
+	existing, err := c.getDSRecords(dc.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	create, remove := diffDSRecords(existing, ds)
+	var corrections []*models.Correction
+	for _, d := range create {
+		d := d
+		corrections = append(corrections, &models.Correction{
+			Msg: fmt.Sprintf("Add DS %s", dsString(d)),
+			F: c.dsRecordFunc(dc.Name, func(client *dnsimpleapi.Client, accountID string) error {
+				_, err := client.Domains.CreateDelegationSignerRecord(context.Background(), accountID, dc.Name, d)
+				return err
+			}),
+		})
+	}
+	for _, d := range remove {
+		d := d
+		corrections = append(corrections, &models.Correction{
+			Msg: fmt.Sprintf("Remove DS %s", dsString(d)),
+			F: c.dsRecordFunc(dc.Name, func(client *dnsimpleapi.Client, accountID string) error {
+				_, err := client.Domains.DeleteDelegationSignerRecord(context.Background(), accountID, dc.Name, d.ID)
+				return err
+			}),
+		})
+	}
+	return corrections, nil
+}
+
+// diffDSRecords returns the DS records to create and remove so that
+// existing matches ds.
+func diffDSRecords(existing []dnsimpleapi.DelegationSignerRecord, ds models.Records) (create, remove []dnsimpleapi.DelegationSignerRecord) {
+	want := map[string]bool{}
+	for _, rc := range ds {
+		d := dnsimpleapi.DelegationSignerRecord{
+			Keytag:     strconv.Itoa(int(rc.DsKeyTag)),
+			Algorithm:  strconv.Itoa(int(rc.DsAlgorithm)),
+			DigestType: strconv.Itoa(int(rc.DsDigestType)),
+			Digest:     rc.DsDigest,
+		}
+		if !want[dsString(d)] {
+			want[dsString(d)] = true
+			create = append(create, d)
+		}
+	}
+
+	have := map[string]bool{}
+	for _, d := range existing {
+		have[dsString(d)] = true
+		if !want[dsString(d)] {
+			remove = append(remove, d)
+		}
+	}
+
+	n := 0
+	for _, d := range create {
+		if !have[dsString(d)] {
+			create[n] = d
+			n++
+		}
+	}
+	return create[:n], remove
+}
+
+func dsString(d dnsimpleapi.DelegationSignerRecord) string {
+	return strings.Join([]string{d.Keytag, d.Algorithm, d.DigestType, strings.ToUpper(d.Digest)}, " ")
+}
+
+func (c *dnsimpleProvider) getDSRecords(domainName string) ([]dnsimpleapi.DelegationSignerRecord, error) {
+	client := c.getClient()
+
+	accountID, err := c.getAccountID()
+	if err != nil {
+		var errorResponse *dnsimpleapi.ErrorResponse
+		if errors.As(err, &errorResponse) {
+			return nil, compileAttributeErrors(errorResponse)
+		}
+		return nil, err
+	}
+
+	opts := &dnsimpleapi.ListOptions{}
+	var recs []dnsimpleapi.DelegationSignerRecord
+	page := 1
+	for {
+		opts.Page = &page
+		dsResponse, err := client.Domains.ListDelegationSignerRecords(context.Background(), accountID, domainName, opts)
+		if err != nil {
+			var errorResponse *dnsimpleapi.ErrorResponse
+			if errors.As(err, &errorResponse) {
+				return nil, compileAttributeErrors(errorResponse)
+			}
+			return nil, err
+		}
+		recs = append(recs, dsResponse.Data...)
+		pg := dsResponse.Pagination
+		if pg == nil || pg.CurrentPage >= pg.TotalPages {
+			break
+		}
+		page++
+	}
+
+	return recs, nil
+}
+
+// Returns a function that runs f with the client and account ID.
+func (c *dnsimpleProvider) dsRecordFunc(domainName string, f func(client *dnsimpleapi.Client, accountID string) error) func() error {
+	return func() error {
+		client := c.getClient()
+
+		accountID, err := c.getAccountID()
+		if err == nil {
+			err = f(client, accountID)
+		}
+		if err != nil {
+			var errorResponse *dnsimpleapi.ErrorResponse
+			if errors.As(err, &errorResponse) {
+				return compileAttributeErrors(errorResponse)
+			}
+			return fmt.Errorf("DS records of %s: %w", domainName, err)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,39 @@
+package dnsimple
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+	dnsimpleapi "github.com/dnsimple/dnsimple-go/dnsimple"
+)
+
+func TestDiffDSRecords(t *testing.T) {
+	ds := func(s string) *models.RecordConfig {
+		rc := &models.RecordConfig{Type: "DS"}
+		rc.SetLabel("@", "example.com")
+		if err := rc.SetTargetDSString(s); err != nil {
+			t.Fatal(err)
+		}
+		return rc
+	}
+	existing := []dnsimpleapi.DelegationSignerRecord{
+		{ID: 1, Keytag: "1001", Algorithm: "13", DigestType: "2", Digest: "bb01"},
+		{ID: 2, Keytag: "900", Algorithm: "8", DigestType: "2", Digest: "CC"},
+	}
+
+	create, remove := diffDSRecords(existing, models.Records{ds("1001 13 2 BB01"), ds("1002 13 2 BB02"), ds("1002 13 2 BB02")})
+
+	wantCreate := []dnsimpleapi.DelegationSignerRecord{{Keytag: "1002", Algorithm: "13", DigestType: "2", Digest: "BB02"}}
+	if !reflect.DeepEqual(create, wantCreate) {
+		t.Errorf("create = %v, want %v", create, wantCreate)
+	}
+	if len(remove) != 1 || remove[0].ID != 2 {
+		t.Errorf("remove = %v, want the record with ID 2", remove)
+	}
+
+	create, remove = diffDSRecords(existing, models.Records{})
+	if len(create) != 0 || len(remove) != 2 {
+		t.Errorf("an empty DS set must remove all records, got create=%v remove=%v", create, remove)
+	}
+}
@@ -15,6 +15,7 @@ Settings from `creds.json`:
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"sort"
@@ -24,25 +25,34 @@ import (
 	"github.com/StackExchange/dnscontrol/v4/models"
 	"github.com/StackExchange/dnscontrol/v4/pkg/diff2"
 	"github.com/StackExchange/dnscontrol/v4/pkg/printer"
+	"github.com/StackExchange/dnscontrol/v4/pkg/ratelimit"
 	"github.com/StackExchange/dnscontrol/v4/providers"
 	"github.com/go-gandi/go-gandi"
 	"github.com/go-gandi/go-gandi/config"
 	"github.com/go-gandi/go-gandi/livedns"
+	"github.com/go-gandi/go-gandi/types"
 	"github.com/miekg/dns/dnsutil"
 )
 
 // Section 1: Register this provider in the system.
 
+const providerName = "GANDI_V5"
+
+// rateLimit retries requests that Gandi rejects because of its rate
+// limit (1000 requests per minute).
+var rateLimit = providers.RateLimit{
+	MaxRetries: 5,
+}
+
 // init registers the provider to dnscontrol.
 func init() {
-	const providerName = "GANDI_V5"
 	const providerMaintainer = "@TomOnTime"
 	fns := providers.DspFuncs{
 		Initializer:   newDsp,
 		RecordAuditor: AuditRecords,
 	}
-	providers.RegisterDomainServiceProviderType(providerName, fns, features)
-	providers.RegisterRegistrarType(providerName, newReg)
+	providers.RegisterDomainServiceProviderType(providerName, fns, features, rateLimit)
+	providers.RegisterRegistrarType(providerName, newReg, rateLimit)
 	providers.RegisterMaintainer(providerName, providerMaintainer)
 }
 
@@ -77,6 +87,7 @@ type gandiv5Provider struct {
 	sharingid string
 	debug     bool
 	apiurl    string
+	limiter   *ratelimit.Limiter
 }
 
 // newDsp generates a DNS Service Provider client handle.
@@ -103,6 +114,10 @@ func newHelper(m map[string]string, _ json.RawMessage) (*gandiv5Provider, error)
 	if err == nil {
 		api.debug = debug
 	}
+	api.limiter, err = providers.NewRateLimiter(providerName, m)
+	if err != nil {
+		return nil, err
+	}
 
 	return api, nil
 }
@@ -150,7 +165,11 @@ func (client *gandiv5Provider) GetZoneRecords(domain string, meta map[string]str
 	g := newLiveDNSClient(client)
 
 	// Get all the existing records:
-	records, err := g.GetDomainRecords(domain)
+	var records []livedns.DomainRecord
+	err := client.limiter.Do(func() (err error) {
+		records, err = g.GetDomainRecords(domain)
+		return retryable(err)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -242,7 +261,11 @@ func (client *gandiv5Provider) GetZoneRecordsCorrections(dc *models.DomainConfig
 					&models.Correction{
 						Msg: msg,
 						F: func() error {
-							res, err := g.CreateDomainRecord(domain, shortname, rtype, ttl, values)
+							var res types.StandardResponse
+							err := client.limiter.Do(func() (err error) {
+								res, err = g.CreateDomainRecord(domain, shortname, rtype, ttl, values)
+								return retryable(err)
+							})
 							if err != nil {
 								return fmt.Errorf("%+v: %w", res, err)
 							}
@@ -261,7 +284,11 @@ func (client *gandiv5Provider) GetZoneRecordsCorrections(dc *models.DomainConfig
 				&models.Correction{
 					Msg: msgs,
 					F: func() error {
-						res, err := g.UpdateDomainRecordsByName(domain, shortname, ns)
+						var res types.StandardResponse
+						err := client.limiter.Do(func() (err error) {
+							res, err = g.UpdateDomainRecordsByName(domain, shortname, ns)
+							return retryable(err)
+						})
 						if err != nil {
 							return fmt.Errorf("%+v: %w", res, err)
 						}
@@ -278,7 +305,9 @@ func (client *gandiv5Provider) GetZoneRecordsCorrections(dc *models.DomainConfig
 				&models.Correction{
 					Msg: msgs,
 					F: func() error {
-						err := g.DeleteDomainRecordsByName(domain, shortname)
+						err := client.limiter.Do(func() error {
+							return retryable(g.DeleteDomainRecordsByName(domain, shortname))
+						})
 						if err != nil {
 							return err
 						}
@@ -295,6 +324,16 @@ func (client *gandiv5Provider) GetZoneRecordsCorrections(dc *models.DomainConfig
 	return corrections, nil
 }
 
+// retryable marks the errors that should be retried (rate limited,
+// temporarily unavailable) for the limiter.
+func retryable(err error) error {
+	var rerr *types.RequestError
+	if errors.As(err, &rerr) && ratelimit.RetryableStatus(rerr.StatusCode) {
+		return ratelimit.Retry(err, 0)
+	}
+	return err
+}
+
 // debugRecords prints a list of RecordConfig.
 func debugRecords(note string, recs []*models.RecordConfig) {
 	printer.Debugf(note)
@@ -308,7 +347,11 @@ func debugRecords(note string, recs []*models.RecordConfig) {
 // GetNameservers returns a list of nameservers for domain.
 func (client *gandiv5Provider) GetNameservers(domain string) ([]*models.Nameserver, error) {
 	g := newLiveDNSClient(client)
-	nameservers, err := g.GetDomainNS(domain)
+	var nameservers []string
+	err := client.limiter.Do(func() (err error) {
+		nameservers, err = g.GetDomainNS(domain)
+		return retryable(err)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -325,7 +368,11 @@ func (client *gandiv5Provider) GetRegistrarCorrections(dc *models.DomainConfig)
 		APIURL:              client.apiurl,
 	})
 
-	existingNs, err := gd.GetNameServers(dc.Name)
+	var existingNs []string
+	err := client.limiter.Do(func() (err error) {
+		existingNs, err = gd.GetNameServers(dc.Name)
+		return retryable(err)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -341,8 +388,9 @@ func (client *gandiv5Provider) GetRegistrarCorrections(dc *models.DomainConfig)
 			{
 				Msg: fmt.Sprintf("Change Nameservers from '%s' to '%s'", existing, desired),
 				F: func() (err error) {
-					err = gd.UpdateNameServers(dc.Name, desiredNs)
-					return
+					return client.limiter.Do(func() error {
+						return retryable(gd.UpdateNameServers(dc.Name, desiredNs))
+					})
 				}},
 		}, nil
 	}
@@ -10,6 +10,7 @@ import (
 	"github.com/StackExchange/dnscontrol/v4/models"
 	"github.com/StackExchange/dnscontrol/v4/pkg/diff"
 	"github.com/StackExchange/dnscontrol/v4/pkg/printer"
+	"github.com/StackExchange/dnscontrol/v4/pkg/ratelimit"
 	"github.com/StackExchange/dnscontrol/v4/providers"
 	nc "github.com/billputer/go-namecheap"
 	"golang.org/x/net/publicsuffix"
@@ -23,6 +24,7 @@ type namecheapProvider struct {
 	APIKEY  string
 	APIUser string
 	client  *nc.Client
+	limiter *ratelimit.Limiter
 }
 
 var features = providers.DocumentationNotes{
@@ -41,15 +43,25 @@ var features = providers.DocumentationNotes{
 	providers.DocOfficiallySupported: providers.Cannot(),
 }
 
+const providerName = "NAMECHEAP"
+
+// rateLimit stays within the published limit of 20 requests per minute.
+// If a request is rate limited anyway, retry for about a minute.
+var rateLimit = providers.RateLimit{
+	MaxRPS:     20.0 / 60,
+	MaxRetries: 12,
+	MinBackoff: 5 * time.Second,
+	MaxBackoff: 15 * time.Second,
+}
+
 func init() {
-	const providerName = "NAMECHEAP"
 	const providerMaintainer = "@willpower232"
-	providers.RegisterRegistrarType(providerName, newReg)
+	providers.RegisterRegistrarType(providerName, newReg, rateLimit)
 	fns := providers.DspFuncs{
 		Initializer:   newDsp,
 		RecordAuditor: AuditRecords,
 	}
-	providers.RegisterDomainServiceProviderType(providerName, fns, features)
+	providers.RegisterDomainServiceProviderType(providerName, fns, features, rateLimit)
 	providers.RegisterCustomRecordType("URL", providerName, "")
 	providers.RegisterCustomRecordType("URL301", providerName, "")
 	providers.RegisterCustomRecordType("FRAME", providerName, "")
@@ -71,6 +83,11 @@ func newProvider(m map[string]string, metadata json.RawMessage) (*namecheapProvi
 		return nil, fmt.Errorf("missing Namecheap apikey and apiuser")
 	}
 	api.client = nc.NewClient(api.APIUser, api.APIKEY, api.APIUser)
+	limiter, err := providers.NewRateLimiter(providerName, m)
+	if err != nil {
+		return nil, err
+	}
+	api.limiter = limiter
 	// if BaseURL is specified in creds, use that url
 	BaseURL, ok := m["BaseURL"]
 	if ok {
@@ -92,29 +109,17 @@ func splitDomain(domain string) (sld string, tld string) {
 //	"The limits for the API calls will be 20/Min, 700/Hour and 8000/Day for one user.
 //	 If you can limit the requests within these it should be fine."
 //
-// this helper performs some api action, checks for rate limited response, and if so, enters a retry loop until it resolves
+// this helper performs some api action, checks for rate limited response, and if so, retries it.
 // if you are consistently hitting this, you may have success asking their support to increase your account's limits.
-func doWithRetry(f func() error) {
-	// sleep 5 seconds at a time, up to 23 times (1 minute, 15 seconds)
-	const maxRetries = 23
-	const sleepTime = 5 * time.Second
-	var currentRetry int
-	for {
+func (n *namecheapProvider) doWithRetry(f func() error) {
+	_ = n.limiter.Do(func() error {
 		err := f()
-		if err == nil {
-			return
+		if err != nil && strings.Contains(err.Error(), "unexpected status code from api: 405") {
+			printer.Printf("Namecheap rate limit exceeded. Retrying.\n")
+			return ratelimit.Retry(err, 0)
 		}
-		if strings.Contains(err.Error(), "unexpected status code from api: 405") {
-			currentRetry++
-			if currentRetry >= maxRetries {
-				return
-			}
-			printer.Printf("Namecheap rate limit exceeded. Waiting %s to retry.\n", sleepTime)
-			time.Sleep(sleepTime)
-		} else {
-			return
-		}
-	}
+		return err
+	})
 }
 
 // GetZoneRecords gets the records of a zone and returns them in RecordConfig format.
@@ -122,7 +127,7 @@ func (n *namecheapProvider) GetZoneRecords(domain string, meta map[string]string
 	sld, tld := splitDomain(domain)
 	var records *nc.DomainDNSGetHostsResult
 	var err error
-	doWithRetry(func() error {
+	n.doWithRetry(func() error {
 		records, err = n.client.DomainsDNSGetHosts(sld, tld)
 		return err
 	})
@@ -337,7 +342,7 @@ func (n *namecheapProvider) generateRecords(dc *models.DomainConfig) error {
 	}
 	sld, tld := splitDomain(dc.Name)
 	var err error
-	doWithRetry(func() error {
+	n.doWithRetry(func() error {
 		_, err = n.client.DomainDNSSetHosts(sld, tld, recs)
 		return err
 	})
@@ -354,7 +359,7 @@ func (n *namecheapProvider) GetNameservers(domainName string) ([]*models.Nameser
 func (n *namecheapProvider) GetRegistrarCorrections(dc *models.DomainConfig) ([]*models.Correction, error) {
 	var info *nc.DomainInfo
 	var err error
-	doWithRetry(func() error {
+	n.doWithRetry(func() error {
 		info, err = n.client.DomainGetInfo(dc.Name)
 		return err
 	})
@@ -376,7 +381,7 @@ func (n *namecheapProvider) GetRegistrarCorrections(dc *models.DomainConfig) ([]
 			{
 				Msg: fmt.Sprintf("Change Nameservers from '%s' to '%s'", found, desired),
 				F: func() (err error) {
-					doWithRetry(func() error {
+					n.doWithRetry(func() error {
 						_, err = n.client.DomainDNSSetCustom(sld, tld, desired)
 						return err
 					})
@@ -2,11 +2,139 @@ package powerdns
 
 import (
 	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/StackExchange/dnscontrol/v4/models"
+	"github.com/StackExchange/dnscontrol/v4/pkg/printer"
 	"github.com/mittwald/go-powerdns/apis/cryptokeys"
+	"github.com/mittwald/go-powerdns/apis/zones"
 )
 
+// Domain metadata that declares the DNSSEC policy of a zone with
+// AUTODNSSEC_ON.
+const (
+	metaDNSSECAlgorithm  = "powerdns_dnssec_algorithm"
+	metaDNSSECKeys       = "powerdns_dnssec_keys"
+	metaDNSSECNSEC       = "powerdns_dnssec_nsec"
+	metaDNSSECNSEC3Param = "powerdns_dnssec_nsec3param"
+	metaKSKRollover      = "powerdns_ksk_rollover"
+	metaKSKRolloverHold  = "powerdns_ksk_rollover_hold"
+)
+
+// PowerDNS zone metadata that records the state of a KSK rollover.
+const (
+	kindRollover = "X-DNSCONTROL-KSK-ROLLOVER" // The label of the last rollover.
+	kindRetire   = "X-DNSCONTROL-KSK-RETIRE"   // The IDs of the keys being replaced.
+	kindDSSeen   = "X-DNSCONTROL-KSK-DS-SEEN"  // When the parent published the DS of the new keys.
+)
+
+const (
+	defaultNSEC3Param   = "1 0 0 -"
+	defaultRolloverHold = 48 * time.Hour
+)
+
+// policyMeta are the metadata keys of the DNSSEC policy.
+var policyMeta = []string{metaDNSSECAlgorithm, metaDNSSECKeys, metaDNSSECNSEC, metaDNSSECNSEC3Param, metaKSKRollover, metaKSKRolloverHold}
+
+var algorithms = []string{"rsasha256", "rsasha512", "ecdsap256sha256", "ecdsap384sha384", "ed25519", "ed448"}
+
+// timeNow is replaced by the tests.
+var timeNow = time.Now
+
+type dnssecPolicy struct {
+	algorithm  string // "" lets PowerDNS choose.
+	split      bool   // A KSK and a ZSK instead of a CSK.
+	nsec       string // "", "nsec" or "nsec3". "" leaves it alone.
+	nsec3param string
+	rollover   string
+	hold       time.Duration
+}
+
+func parseDNSSECPolicy(meta map[string]string) (dnssecPolicy, error) {
+	p := dnssecPolicy{
+		algorithm:  strings.ToLower(meta[metaDNSSECAlgorithm]),
+		nsec:       strings.ToLower(meta[metaDNSSECNSEC]),
+		nsec3param: strings.Join(strings.Fields(meta[metaDNSSECNSEC3Param]), " "),
+		rollover:   meta[metaKSKRollover],
+		hold:       defaultRolloverHold,
+	}
+
+	if p.algorithm != "" && !slices.Contains(algorithms, p.algorithm) {
+		return p, fmt.Errorf("%s %q is not one of %s", metaDNSSECAlgorithm, meta[metaDNSSECAlgorithm], strings.Join(algorithms, ", "))
+	}
+
+	switch keys := meta[metaDNSSECKeys]; keys {
+	case "", "csk":
+	case "split":
+		p.split = true
+	default:
+		return p, fmt.Errorf("%s %q must be \"csk\" or \"split\"", metaDNSSECKeys, keys)
+	}
+
+	switch p.nsec {
+	case "", "nsec":
+		if p.nsec3param != "" {
+			return p, fmt.Errorf("%s requires %s \"nsec3\"", metaDNSSECNSEC3Param, metaDNSSECNSEC)
+		}
+	case "nsec3":
+		if p.nsec3param == "" {
+			p.nsec3param = defaultNSEC3Param
+		}
+		if f := strings.Fields(p.nsec3param); len(f) != 4 {
+			return p, fmt.Errorf("%s %q must be \"algorithm flags iterations salt\"", metaDNSSECNSEC3Param, p.nsec3param)
+		}
+	default:
+		return p, fmt.Errorf("%s %q must be \"nsec\" or \"nsec3\"", metaDNSSECNSEC, meta[metaDNSSECNSEC])
+	}
+
+	if h := meta[metaKSKRolloverHold]; h != "" {
+		d, err := time.ParseDuration(h)
+		if err != nil || d < 0 {
+			return p, fmt.Errorf("%s %q is not a duration", metaKSKRolloverHold, h)
+		}
+		p.hold = d
+	}
+
+	return p, nil
+}
+
+// signingKeyType is the type of the key that signs the DNSKEY set.
+func (p dnssecPolicy) signingKeyType() string {
+	if p.split {
+		return "ksk"
+	}
+	return "csk"
+}
+
+func (p dnssecPolicy) String() string {
+	s := "a CSK"
+	if p.split {
+		s = "a KSK and a ZSK"
+	}
+	if p.algorithm != "" {
+		s += " (" + strings.ToUpper(p.algorithm) + ")"
+	}
+	return s
+}
+
+// hasDNSSECPolicy reports whether any of the DNSSEC policy metadata is set.
+func hasDNSSECPolicy(meta map[string]string) bool {
+	for _, k := range policyMeta {
+		if _, ok := meta[k]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func isSigningKey(k cryptokeys.Cryptokey) bool {
+	return k.KeyType == "ksk" || k.KeyType == "csk"
+}
+
 // getDNSSECCorrections returns corrections that update a domain's DNSSEC state.
 func (dsp *powerdnsProvider) getDNSSECCorrections(dc *models.DomainConfig) ([]*models.Correction, error) {
 	zoneCryptokeys, getErr := dsp.client.Cryptokeys().ListCryptokeys(context.Background(), dsp.ServerName, dc.Name)
@@ -16,15 +144,10 @@ func (dsp *powerdnsProvider) getDNSSECCorrections(dc *models.DomainConfig) ([]*m
 
 	// check if any of the avail. key is active and published
 	hasEnabledKey := false
-	var keyID int
-
-	if len(zoneCryptokeys) > 0 {
-		for _, cryptoKey := range zoneCryptokeys {
-			if cryptoKey.Active && cryptoKey.Published {
-				hasEnabledKey = true
-				keyID = cryptoKey.ID
-				break
-			}
+	for _, cryptoKey := range zoneCryptokeys {
+		if cryptoKey.Active && cryptoKey.Published {
+			hasEnabledKey = true
+			break
 		}
 	}
 
@@ -34,28 +157,309 @@ func (dsp *powerdnsProvider) getDNSSECCorrections(dc *models.DomainConfig) ([]*m
 			{
 				Msg: "Disable DNSSEC",
 				F: func() error {
-					return dsp.client.Cryptokeys().DeleteCryptokey(context.Background(), dsp.ServerName, dc.Name, keyID)
+					for _, cryptoKey := range zoneCryptokeys {
+						if err := dsp.client.Cryptokeys().DeleteCryptokey(context.Background(), dsp.ServerName, dc.Name, cryptoKey.ID); err != nil {
+							return err
+						}
+					}
+					return nil
 				},
 			},
 		}, nil
 	}
 
+	if dc.AutoDNSSEC != "on" {
+		return nil, nil
+	}
+
+	policy, err := parseDNSSECPolicy(dc.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	var corrections []*models.Correction
+
 	// dnssec is disabled, we want it to be enabled
-	if !hasEnabledKey && dc.AutoDNSSEC == "on" {
-		return []*models.Correction{
-			{
-				Msg: "Enable DNSSEC",
-				F: func() (err error) {
-					_, err = dsp.client.Cryptokeys().CreateCryptokey(context.Background(), dsp.ServerName, dc.Name, cryptokeys.Cryptokey{
-						KeyType:   "csk",
-						Active:    true,
-						Published: true,
-					})
-					return
-				},
+	if !hasEnabledKey {
+		corrections = append(corrections, &models.Correction{
+			Msg: "Enable DNSSEC with " + policy.String(),
+			F: func() error {
+				if err := dsp.createKeys(dc.Name, policy); err != nil {
+					return err
+				}
+				if policy.rollover == "" {
+					return nil
+				}
+				return dsp.setMetadata(dc.Name, kindRollover, policy.rollover)
 			},
-		}, nil
+		})
+	} else {
+		if msg := policyMismatch(policy, zoneCryptokeys); msg != "" {
+			printer.Warnf("%s: %s\n", dc.Name, msg)
+		}
+		rollover, err := dsp.getRolloverCorrections(dc, policy, zoneCryptokeys)
+		if err != nil {
+			return nil, err
+		}
+		corrections = append(corrections, rollover...)
+	}
+
+	nsec, err := dsp.getNSECCorrections(dc, policy)
+	if err != nil {
+		return nil, err
+	}
+	return append(corrections, nsec...), nil
+}
+
+// createKeys creates the keys of a zone that isn't signed yet.
+func (dsp *powerdnsProvider) createKeys(zone string, policy dnssecPolicy) error {
+	types := []string{policy.signingKeyType()}
+	if policy.split {
+		types = append(types, "zsk")
+	}
+	for _, t := range types {
+		if err := dsp.createKey(zone, t, policy.algorithm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (dsp *powerdnsProvider) createKey(zone, keyType, algorithm string) error {
+	_, err := dsp.client.Cryptokeys().CreateCryptokey(context.Background(), dsp.ServerName, zone, cryptokeys.Cryptokey{
+		KeyType:   keyType,
+		Active:    true,
+		Published: true,
+		Algorithm: algorithm,
+	})
+	return err
+}
+
+// policyMismatch describes how the active keys differ from policy, or
+// returns "" if they don't. Existing keys are never changed to match
+// the policy; only new keys follow it.
+func policyMismatch(policy dnssecPolicy, keys []cryptokeys.Cryptokey) string {
+	var have []string
+	split := false
+	for _, k := range keys {
+		if !k.Active {
+			continue
+		}
+		if policy.algorithm != "" && !strings.EqualFold(k.Algorithm, policy.algorithm) {
+			return fmt.Sprintf("DNSSEC key %d uses %s instead of %s. Existing keys are not changed to match the policy", k.ID, k.Algorithm, strings.ToUpper(policy.algorithm))
+		}
+		have = append(have, k.KeyType)
+		split = split || k.KeyType == "zsk"
+	}
+	if split != policy.split {
+		return fmt.Sprintf("DNSSEC keys are %s instead of %s. Existing keys are not changed to match the policy", strings.Join(have, ", "), policy)
+	}
+	return ""
+}
+
+// getRolloverCorrections returns the corrections of the next step of the
+// KSK rollover of a signed zone. A rollover is started by changing the
+// powerdns_ksk_rollover label and takes several pushes:
+//
+//  1. A new KSK (or CSK) is published and signs the DNSKEY set along with
+//     the old one. The DS set reported to the registrar now has both.
+//  2. Once the parent publishes the DS of the new key, that time is
+//     recorded.
+//  3. Once the hold time has passed since then, the old key is removed
+//     and the DS set only has the new key.
+func (dsp *powerdnsProvider) getRolloverCorrections(dc *models.DomainConfig, policy dnssecPolicy, keys []cryptokeys.Cryptokey) ([]*models.Correction, error) {
+	if policy.rollover == "" {
+		return nil, nil
+	}
+
+	labels, err := dsp.getMetadata(dc.Name, kindRollover)
+	if err != nil {
+		return nil, err
+	}
+	retire, err := dsp.getMetadata(dc.Name, kindRetire)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(retire) == 0 {
+		switch {
+		case len(labels) > 0 && labels[0] == policy.rollover:
+			return nil, nil
+		case len(labels) == 0:
+			// The keys predate the label. Adopt them.
+			return []*models.Correction{{
+				Msg: fmt.Sprintf("Record the current KSK as KSK rollover %q", policy.rollover),
+				F:   func() error { return dsp.setMetadata(dc.Name, kindRollover, policy.rollover) },
+			}}, nil
+		}
+
+		var old []string
+		for _, k := range keys {
+			if isSigningKey(k) {
+				old = append(old, strconv.Itoa(k.ID))
+			}
+		}
+		return []*models.Correction{{
+			Msg: fmt.Sprintf("Start KSK rollover %q: publish a new %s to replace key %s", policy.rollover, strings.ToUpper(policy.signingKeyType()), strings.Join(old, ", ")),
+			F: func() error {
+				// Record the old keys first, so that a failure leaves
+				// the rollover in progress rather than forgotten.
+				if err := dsp.setMetadata(dc.Name, kindRetire, old...); err != nil {
+					return err
+				}
+				if err := dsp.deleteMetadata(dc.Name, kindDSSeen); err != nil {
+					return err
+				}
+				if err := dsp.createKey(dc.Name, policy.signingKeyType(), policy.algorithm); err != nil {
+					return err
+				}
+				return dsp.setMetadata(dc.Name, kindRollover, policy.rollover)
+			},
+		}}, nil
+	}
+
+	// A rollover is in progress.
+	label := policy.rollover
+	if len(labels) > 0 {
+		label = labels[0]
+	}
+	var newDS []string
+	for _, k := range keys {
+		if isSigningKey(k) && !slices.Contains(retire, strconv.Itoa(k.ID)) {
+			newDS = append(newDS, parentDigests(k.DS)...)
+		}
+	}
+
+	if len(newDS) == 0 {
+		return nil, fmt.Errorf("KSK rollover %q: none of the keys of %s replaces key %s", label, dc.Name, strings.Join(retire, ", "))
+	}
+
+	seen, err := dsp.getMetadata(dc.Name, kindDSSeen)
+	if err != nil {
+		return nil, err
+	}
+	if len(seen) == 0 {
+		published, err := dsp.parentDS(dc.Name)
+		if err != nil {
+			return []*models.Correction{{Msg: fmt.Sprintf("KSK rollover %q: can't look up the DS records of the parent: %s", label, err)}}, nil
+		}
+		for _, ds := range newDS {
+			if !slices.Contains(published, ds) {
+				return []*models.Correction{{Msg: fmt.Sprintf("KSK rollover %q: waiting for the parent to publish DS %s", label, ds)}}, nil
+			}
+		}
+		return []*models.Correction{{
+			Msg: fmt.Sprintf("KSK rollover %q: the parent publishes the DS of the new key", label),
+			F:   func() error { return dsp.setMetadata(dc.Name, kindDSSeen, timeNow().UTC().Format(time.RFC3339)) },
+		}}, nil
+	}
+
+	since, err := time.Parse(time.RFC3339, seen[0])
+	if err != nil {
+		return nil, fmt.Errorf("zone metadata %s: %w", kindDSSeen, err)
+	}
+	if timeNow().Sub(since) < policy.hold {
+		return nil, nil
+	}
+	return []*models.Correction{{
+		Msg: fmt.Sprintf("Finish KSK rollover %q: remove key %s", label, strings.Join(retire, ", ")),
+		F: func() error {
+			for _, id := range retire {
+				n, err := strconv.Atoi(id)
+				if err != nil {
+					return fmt.Errorf("zone metadata %s: %w", kindRetire, err)
+				}
+				if err := dsp.client.Cryptokeys().DeleteCryptokey(context.Background(), dsp.ServerName, dc.Name, n); err != nil {
+					return err
+				}
+			}
+			if err := dsp.deleteMetadata(dc.Name, kindDSSeen); err != nil {
+				return err
+			}
+			return dsp.deleteMetadata(dc.Name, kindRetire)
+		},
+	}}, nil
+}
+
+// getNSECCorrections returns the corrections that switch the zone between
+// NSEC and NSEC3.
+func (dsp *powerdnsProvider) getNSECCorrections(dc *models.DomainConfig, policy dnssecPolicy) ([]*models.Correction, error) {
+	if policy.nsec == "" {
+		return nil, nil
+	}
+	zone, err := dsp.client.Zones().GetZone(context.Background(), dsp.ServerName, dc.Name, zones.WithoutResourceRecordSets())
+	if err != nil {
+		return nil, err
+	}
+
+	want := ""
+	if policy.nsec == "nsec3" {
+		want = policy.nsec3param
+	}
+	if strings.Join(strings.Fields(zone.NSec3Param), " ") == want {
+		return nil, nil
+	}
+
+	msg := "Use NSEC instead of NSEC3"
+	if want != "" {
+		msg = fmt.Sprintf("Use NSEC3 with parameters %q", want)
+	}
+	return []*models.Correction{{
+		Msg: msg,
+		F:   func() error { return dsp.setNSEC3Param(dc.Name, want) },
+	}}, nil
+}
+
+// parentDigests returns the SHA-256 DS records of a key in the form the
+// parent publishes them.
+func parentDigests(ds []string) []string {
+	var out []string
+	for _, d := range ds {
+		f := strings.Fields(d)
+		if len(f) == 4 && f[2] == "2" {
+			out = append(out, strings.Join(f[:3], " ")+" "+strings.ToUpper(f[3]))
+		}
 	}
+	return out
+}
 
-	return nil, nil
+// GetDSRecords returns the DS records of the keys that sign the DNSKEY
+// set. During a KSK rollover this includes both the old and the new key.
+// The DS records are only managed when the DNSSEC policy metadata is set.
+// It returns nil (not managed) while no signing key is active and
+// published, since the DS records are read before the push creates the keys.
+func (dsp *powerdnsProvider) GetDSRecords(dc *models.DomainConfig) (models.Records, error) {
+	if !hasDNSSECPolicy(dc.Metadata) {
+		return nil, nil
+	}
+	switch dc.AutoDNSSEC {
+	case "on":
+	case "off":
+		return models.Records{}, nil
+	default:
+		return nil, nil
+	}
+
+	keys, err := dsp.client.Cryptokeys().ListCryptokeys(context.Background(), dsp.ServerName, dc.Name)
+	if err != nil {
+		return nil, err
+	}
+	recs := models.Records{}
+	for _, k := range keys {
+		if !isSigningKey(k) || !k.Active || !k.Published {
+			continue
+		}
+		for _, ds := range parentDigests(k.DS) {
+			rc := &models.RecordConfig{Type: "DS", Metadata: map[string]string{}}
+			rc.SetLabel("@", dc.Name)
+			if err := rc.SetTargetDSString(ds); err != nil {
+				return nil, err
+			}
+			recs = append(recs, rc)
+		}
+	}
+	if len(recs) == 0 {
+		return nil, nil
+	}
+	return recs, nil
 }
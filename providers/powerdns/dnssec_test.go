@@ -0,0 +1,265 @@
+package powerdns
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+	pdns "github.com/mittwald/go-powerdns"
+	"github.com/mittwald/go-powerdns/apis/cryptokeys"
+	"github.com/mittwald/go-powerdns/pdnshttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePowerDNS serves the parts of the PowerDNS API that the DNSSEC code
+// uses, for a single zone.
+type fakePowerDNS struct {
+	keys       []cryptokeys.Cryptokey
+	nextID     int
+	nsec3param string
+	metadata   map[string][]string
+}
+
+func (f *fakePowerDNS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/servers/localhost/zones/")
+	zone, rest, _ := strings.Cut(path, "/")
+	if strings.TrimSuffix(zone, ".") != "example.com" {
+		http.NotFound(w, r)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+
+	switch {
+	case rest == "" && r.Method == http.MethodGet:
+		json.NewEncoder(w).Encode(map[string]string{"name": "example.com.", "nsec3param": f.nsec3param})
+	case rest == "" && r.Method == http.MethodPut:
+		var settings map[string]string
+		json.Unmarshal(body, &settings)
+		f.nsec3param = settings["nsec3param"]
+		w.WriteHeader(http.StatusNoContent)
+	case rest == "cryptokeys" && r.Method == http.MethodGet:
+		json.NewEncoder(w).Encode(f.keys)
+	case rest == "cryptokeys" && r.Method == http.MethodPost:
+		var k cryptokeys.Cryptokey
+		json.Unmarshal(body, &k)
+		f.nextID++
+		k.ID = f.nextID
+		if k.Algorithm == "" {
+			k.Algorithm = "ECDSAP256SHA256"
+		}
+		if k.KeyType != "zsk" {
+			tag := 1000 + k.ID
+			k.DS = []string{fmt.Sprintf("%d 13 1 AA%02d", tag, k.ID), fmt.Sprintf("%d 13 2 BB%02d", tag, k.ID)}
+		}
+		f.keys = append(f.keys, k)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(k)
+	case strings.HasPrefix(rest, "cryptokeys/") && r.Method == http.MethodDelete:
+		id, _ := strconv.Atoi(strings.TrimPrefix(rest, "cryptokeys/"))
+		for i, k := range f.keys {
+			if k.ID == id {
+				f.keys = append(f.keys[:i], f.keys[i+1:]...)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	case strings.HasPrefix(rest, "metadata/"):
+		kind := strings.TrimPrefix(rest, "metadata/")
+		switch r.Method {
+		case http.MethodGet:
+			json.NewEncoder(w).Encode(zoneMetadata{Kind: kind, Metadata: f.metadata[kind]})
+		case http.MethodPut:
+			var m zoneMetadata
+			json.Unmarshal(body, &m)
+			f.metadata[kind] = m.Metadata
+			json.NewEncoder(w).Encode(m)
+		case http.MethodDelete:
+			delete(f.metadata, kind)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	default:
+		http.Error(w, "unexpected request "+r.Method+" "+r.URL.Path, http.StatusBadRequest)
+	}
+}
+
+func newFakeProvider(t *testing.T) (*powerdnsProvider, *fakePowerDNS) {
+	fake := &fakePowerDNS{metadata: map[string][]string{}}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	client, err := pdns.New(pdns.WithBaseURL(srv.URL), pdns.WithAPIKeyAuthentication("key"))
+	require.NoError(t, err)
+	dsp := &powerdnsProvider{
+		client:     client,
+		ServerName: "localhost",
+		http:       pdnshttp.NewClient(srv.URL, http.DefaultClient, &pdnshttp.APIKeyAuthenticator{APIKey: "key"}, io.Discard),
+		parentDS:   func(string) ([]string, error) { return nil, nil },
+	}
+	return dsp, fake
+}
+
+// push runs the DNSSEC corrections of dc and returns their messages.
+func push(t *testing.T, dsp *powerdnsProvider, dc *models.DomainConfig) []string {
+	t.Helper()
+	corrections, err := dsp.getDNSSECCorrections(dc)
+	require.NoError(t, err)
+	var msgs []string
+	for _, c := range corrections {
+		msgs = append(msgs, c.Msg)
+		if c.F != nil {
+			require.NoError(t, c.F())
+		}
+	}
+	return msgs
+}
+
+func dsStrings(t *testing.T, dsp *powerdnsProvider, dc *models.DomainConfig) []string {
+	t.Helper()
+	recs, err := dsp.GetDSRecords(dc)
+	require.NoError(t, err)
+	var ds []string
+	for _, rc := range recs {
+		ds = append(ds, rc.GetTargetCombined())
+	}
+	return ds
+}
+
+func TestParseDNSSECPolicy(t *testing.T) {
+	p, err := parseDNSSECPolicy(map[string]string{})
+	require.NoError(t, err)
+	assert.Equal(t, dnssecPolicy{hold: defaultRolloverHold}, p)
+
+	p, err = parseDNSSECPolicy(map[string]string{
+		metaDNSSECAlgorithm: "ED25519",
+		metaDNSSECKeys:      "split",
+		metaDNSSECNSEC:      "nsec3",
+		metaKSKRollover:     "2026",
+		metaKSKRolloverHold: "72h",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, dnssecPolicy{algorithm: "ed25519", split: true, nsec: "nsec3", nsec3param: "1 0 0 -", rollover: "2026", hold: 72 * time.Hour}, p)
+
+	for _, meta := range []map[string]string{
+		{metaDNSSECAlgorithm: "rsamd5"},
+		{metaDNSSECKeys: "zsk"},
+		{metaDNSSECNSEC: "nsec5"},
+		{metaDNSSECNSEC3Param: "1 0 0 -"},
+		{metaDNSSECNSEC: "nsec3", metaDNSSECNSEC3Param: "1 0"},
+		{metaKSKRolloverHold: "2 days"},
+	} {
+		_, err := parseDNSSECPolicy(meta)
+		assert.Error(t, err, "%v", meta)
+	}
+}
+
+func TestDNSSECPolicy(t *testing.T) {
+	dsp, fake := newFakeProvider(t)
+	dc := &models.DomainConfig{Name: "example.com", AutoDNSSEC: "on", Metadata: map[string]string{
+		metaDNSSECAlgorithm: "ed25519",
+		metaDNSSECKeys:      "split",
+		metaDNSSECNSEC:      "nsec3",
+	}}
+
+	ds, err := dsp.GetDSRecords(dc)
+	require.NoError(t, err)
+	assert.Nil(t, ds, "DS records must not be managed before a key is active")
+
+	assert.Equal(t, []string{"Enable DNSSEC with a KSK and a ZSK (ED25519)", `Use NSEC3 with parameters "1 0 0 -"`}, push(t, dsp, dc))
+	require.Len(t, fake.keys, 2)
+	assert.Equal(t, "ksk", fake.keys[0].KeyType)
+	assert.Equal(t, "zsk", fake.keys[1].KeyType)
+	assert.Equal(t, "ed25519", fake.keys[0].Algorithm)
+	assert.Equal(t, []string{"1001 13 2 BB01"}, dsStrings(t, dsp, dc))
+	assert.Empty(t, push(t, dsp, dc))
+
+	dc.Metadata[metaDNSSECNSEC] = "nsec"
+	assert.Equal(t, []string{"Use NSEC instead of NSEC3"}, push(t, dsp, dc))
+	assert.Equal(t, "", fake.nsec3param)
+
+	dc.AutoDNSSEC = "off"
+	assert.Equal(t, []string{"Disable DNSSEC"}, push(t, dsp, dc))
+	assert.Empty(t, fake.keys)
+	ds, err = dsp.GetDSRecords(dc)
+	require.NoError(t, err)
+	assert.NotNil(t, ds, "DNSSEC off must remove the DS records at the parent")
+	assert.Empty(t, ds)
+
+	dc.AutoDNSSEC = ""
+	ds, err = dsp.GetDSRecords(dc)
+	require.NoError(t, err)
+	assert.Nil(t, ds, "DNSSEC unmanaged must leave the DS records at the parent alone")
+}
+
+func TestKSKRollover(t *testing.T) {
+	dsp, fake := newFakeProvider(t)
+	dc := &models.DomainConfig{Name: "example.com", AutoDNSSEC: "on", Metadata: map[string]string{
+		metaKSKRollover: "2026",
+	}}
+	start := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return start }
+	defer func() { timeNow = time.Now }()
+	var parent []string
+	dsp.parentDS = func(string) ([]string, error) { return parent, nil }
+
+	assert.Equal(t, []string{"Enable DNSSEC with a CSK"}, push(t, dsp, dc))
+	assert.Equal(t, []string{"2026"}, fake.metadata[kindRollover])
+	assert.Empty(t, push(t, dsp, dc))
+	parent = dsStrings(t, dsp, dc)
+	assert.Equal(t, []string{"1001 13 2 BB01"}, parent)
+
+	dc.Metadata[metaKSKRollover] = "2027"
+	assert.Equal(t, []string{`Start KSK rollover "2027": publish a new CSK to replace key 1`}, push(t, dsp, dc))
+	assert.Len(t, fake.keys, 2)
+	assert.Equal(t, []string{"1001 13 2 BB01", "1002 13 2 BB02"}, dsStrings(t, dsp, dc))
+
+	assert.Equal(t, []string{`KSK rollover "2027": waiting for the parent to publish DS 1002 13 2 BB02`}, push(t, dsp, dc))
+
+	parent = dsStrings(t, dsp, dc)
+	assert.Equal(t, []string{`KSK rollover "2027": the parent publishes the DS of the new key`}, push(t, dsp, dc))
+
+	timeNow = func() time.Time { return start.Add(47 * time.Hour) }
+	assert.Empty(t, push(t, dsp, dc))
+
+	timeNow = func() time.Time { return start.Add(48 * time.Hour) }
+	assert.Equal(t, []string{`Finish KSK rollover "2027": remove key 1`}, push(t, dsp, dc))
+	require.Len(t, fake.keys, 1)
+	assert.Equal(t, 2, fake.keys[0].ID)
+	assert.Equal(t, []string{"1002 13 2 BB02"}, dsStrings(t, dsp, dc))
+	assert.Empty(t, fake.metadata[kindRetire])
+	assert.Empty(t, push(t, dsp, dc))
+}
+
+func TestKSKRolloverAdoptsExistingKeys(t *testing.T) {
+	dsp, fake := newFakeProvider(t)
+	dc := &models.DomainConfig{Name: "example.com", AutoDNSSEC: "on", Metadata: map[string]string{}}
+	push(t, dsp, dc)
+
+	dc.Metadata[metaKSKRollover] = "2026"
+	assert.Equal(t, []string{`Record the current KSK as KSK rollover "2026"`}, push(t, dsp, dc))
+	assert.Len(t, fake.keys, 1)
+	assert.Empty(t, push(t, dsp, dc))
+}
+
+func TestDSRecordsWithoutPolicy(t *testing.T) {
+	dsp, _ := newFakeProvider(t)
+	dc := &models.DomainConfig{Name: "example.com", AutoDNSSEC: "on", Metadata: map[string]string{}}
+
+	assert.Equal(t, []string{"Enable DNSSEC with a CSK"}, push(t, dsp, dc))
+	ds, err := dsp.GetDSRecords(dc)
+	require.NoError(t, err)
+	assert.Nil(t, ds, "DS records must not be managed without a DNSSEC policy")
+
+	dc.AutoDNSSEC = "off"
+	ds, err = dsp.GetDSRecords(dc)
+	require.NoError(t, err)
+	assert.Nil(t, ds)
+}
@@ -0,0 +1,90 @@
+package powerdns
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/miekg/dns"
+	"github.com/mittwald/go-powerdns/pdnshttp"
+)
+
+// The go-powerdns client doesn't cover zone metadata or changing the
+// settings of a zone, so these use the PowerDNS HTTP API directly.
+
+func (dsp *powerdnsProvider) zonePath(zone string) string {
+	return fmt.Sprintf("/servers/%s/zones/%s", url.PathEscape(dsp.ServerName), url.PathEscape(canonical(strings.TrimSuffix(zone, "."))))
+}
+
+type zoneMetadata struct {
+	Kind     string   `json:"kind"`
+	Metadata []string `json:"metadata"`
+}
+
+// getMetadata returns the values of a kind of zone metadata.
+func (dsp *powerdnsProvider) getMetadata(zone, kind string) ([]string, error) {
+	var m zoneMetadata
+	err := dsp.http.Get(context.Background(), dsp.zonePath(zone)+"/metadata/"+url.PathEscape(kind), &m)
+	if pdnshttp.IsNotFound(err) {
+		return nil, nil
+	}
+	return m.Metadata, err
+}
+
+// setMetadata replaces the values of a kind of zone metadata.
+func (dsp *powerdnsProvider) setMetadata(zone, kind string, values ...string) error {
+	return dsp.http.Put(context.Background(), dsp.zonePath(zone)+"/metadata/"+url.PathEscape(kind), nil,
+		pdnshttp.WithJSONRequestBody(zoneMetadata{Kind: kind, Metadata: values}))
+}
+
+// deleteMetadata removes a kind of zone metadata.
+func (dsp *powerdnsProvider) deleteMetadata(zone, kind string) error {
+	err := dsp.http.Delete(context.Background(), dsp.zonePath(zone)+"/metadata/"+url.PathEscape(kind), nil)
+	if pdnshttp.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// setNSEC3Param switches a signed zone to NSEC3 with param, or to NSEC if
+// param is "". PowerDNS doesn't allow changing the NSEC3PARAM metadata
+// directly.
+func (dsp *powerdnsProvider) setNSEC3Param(zone, param string) error {
+	return dsp.http.Put(context.Background(), dsp.zonePath(zone), nil,
+		pdnshttp.WithJSONRequestBody(map[string]string{"nsec3param": param}))
+}
+
+// lookupParentDS returns the DS records of domain, as seen by the
+// system's resolver, in the form "keytag algorithm digesttype digest".
+func lookupParentDS(domain string) ([]string, error) {
+	conf, err := dns.ClientConfigFromFile("/etc/resolv.conf")
+	if err != nil {
+		return nil, err
+	}
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(domain), dns.TypeDS)
+
+	c := new(dns.Client)
+	err = fmt.Errorf("no nameservers in /etc/resolv.conf")
+	for _, server := range conf.Servers {
+		var r *dns.Msg
+		r, _, err = c.Exchange(m, net.JoinHostPort(server, conf.Port))
+		if err != nil {
+			continue
+		}
+		if r.Rcode != dns.RcodeSuccess {
+			err = fmt.Errorf("DS lookup of %s: %s", domain, dns.RcodeToString[r.Rcode])
+			continue
+		}
+		var ds []string
+		for _, rr := range r.Answer {
+			if d, ok := rr.(*dns.DS); ok {
+				ds = append(ds, fmt.Sprintf("%d %d %d %s", d.KeyTag, d.Algorithm, d.DigestType, strings.ToUpper(d.Digest)))
+			}
+		}
+		return ds, nil
+	}
+	return nil, err
+}
@@ -3,8 +3,11 @@ package powerdns
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
 
 	"github.com/mittwald/go-powerdns/apis/zones"
+	"github.com/mittwald/go-powerdns/pdnshttp"
 
 	"github.com/StackExchange/dnscontrol/v4/models"
 	"github.com/StackExchange/dnscontrol/v4/providers"
@@ -55,6 +58,8 @@ type powerdnsProvider struct {
 	SOAEditAPI     string         `json:"soa_edit_api,omitempty"`
 
 	nameservers []*models.Nameserver
+	http        *pdnshttp.Client                      // For the API calls pdns.Client doesn't cover.
+	parentDS    func(domain string) ([]string, error) // Looks up the DS records at the parent.
 }
 
 // newDSP initializes a PowerDNS DNSServiceProvider.
@@ -93,6 +98,9 @@ func newDSP(m map[string]string, metadata json.RawMessage) (providers.DNSService
 		return dsp, err
 	}
 
+	dsp.http = pdnshttp.NewClient(dsp.APIUrl, http.DefaultClient, &pdnshttp.APIKeyAuthenticator{APIKey: dsp.APIKey}, io.Discard)
+	dsp.parentDS = lookupParentDS
+
 	var clientErr error
 	dsp.client, clientErr = pdns.New(
 		pdns.WithBaseURL(dsp.APIUrl),
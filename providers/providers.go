@@ -24,6 +24,36 @@ type ZoneCreator interface {
 	EnsureZoneExists(domain string) error
 }
 
+// SplitHorizonZoneCreator should be implemented by providers that keep
+// a separate zone for each split horizon tag (D("example.com!tag")).
+// It is used instead of ZoneCreator and ZoneLister for tagged domains.
+type SplitHorizonZoneCreator interface {
+	TaggedZoneExists(domain, tag string) (bool, error)
+	EnsureTaggedZoneExists(domain, tag string) error
+}
+
+// DSLister should be implemented by DNS providers that sign zones and
+// manage their keys. GetDSRecords returns the DS records the parent zone
+// should publish, or nil if the provider doesn't manage DNSSEC for dc.
+type DSLister interface {
+	GetDSRecords(dc *models.DomainConfig) (models.Records, error)
+}
+
+// DSRegistrar should be implemented by registrars that can update the
+// DS records of a domain at its parent zone. ds is the DS set reported
+// by the domain's DSListers; an empty set removes all DS records.
+type DSRegistrar interface {
+	GetRegistrarDSCorrections(dc *models.DomainConfig, ds models.Records) ([]*models.Correction, error)
+}
+
+// ServerConfigWriter should be implemented by DNS providers that write
+// the configuration that makes a name server load their zones.
+// GetServerConfigCorrections gets all the zones that use the provider,
+// even those not selected with --domains.
+type ServerConfigWriter interface {
+	GetServerConfigCorrections(dcs []*models.DomainConfig) ([]*models.Correction, error)
+}
+
 // ZoneLister should be implemented by providers that have the
 // ability to list the zones they manage. This facilitates using the
 // "get-zones" command for "all" zones.
@@ -0,0 +1,43 @@
+package providers
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/StackExchange/dnscontrol/v4/pkg/ratelimit"
+)
+
+// RateLimit opts a provider into the shared rate limiter and retry
+// policy (see pkg/ratelimit) and sets its defaults. Pass it to
+// RegisterDomainServiceProviderType() or RegisterRegistrarType() along
+// with the provider's other ProviderMetadata. The provider then calls
+// NewRateLimiter() from its initializer.
+//
+// Users can override the defaults with the creds.json keys "max_rps"
+// and "max_retries".
+type RateLimit ratelimit.Config
+
+// rateLimits stores the RateLimit defaults, keyed by provider type.
+var rateLimits = map[string]ratelimit.Config{}
+
+// NewRateLimiter returns a Limiter for an instance of the provider
+// type pName, configured by the provider's RateLimit defaults and the
+// creds.json settings in config.
+func NewRateLimiter(pName string, config map[string]string) (*ratelimit.Limiter, error) {
+	cfg := rateLimits[pName]
+	if s := config["max_rps"]; s != "" {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("%s: max_rps must be a number >= 0, got %q", pName, s)
+		}
+		cfg.MaxRPS = v
+	}
+	if s := config["max_retries"]; s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("%s: max_retries must be an integer >= 0, got %q", pName, s)
+		}
+		cfg.MaxRetries = v
+	}
+	return ratelimit.New(cfg), nil
+}
@@ -15,7 +15,9 @@ func AuditRecords(records []*models.RecordConfig) []error {
 
 	a.Add("R53_ALIAS", rejectifTargetEqualsLabel) // Last verified 2023-03-01
 
-	return a.Audit(records)
+	a.Add("R53_ALIAS", rejectifMultiValueAlias)
+
+	return append(a.Audit(records), auditRoutingSets(records)...)
 }
 
 // Normally this kind of function would be put in `pkg/rejectif` but
@@ -29,3 +31,48 @@ func rejectifTargetEqualsLabel(rc *models.RecordConfig) error {
 	}
 	return nil
 }
+
+// rejectifMultiValueAlias rejects an ALIAS with a multivalue answer
+// routing policy, which Route53 doesn't permit.
+func rejectifMultiValueAlias(rc *models.RecordConfig) error {
+	if rc.R53Routing[models.R53RoutingMultiValue] == "true" {
+		return fmt.Errorf("R53_ALIAS can't use R53_MULTIVALUE")
+	}
+	return nil
+}
+
+// auditRoutingSets rejects record sets that Route53 can't store: a set
+// whose records have different routing policies, and a label and type
+// with both a simple record set and sets with a routing policy.
+func auditRoutingSets(records []*models.RecordConfig) (errs []error) {
+	sets := map[models.RecordKey]*models.RecordConfig{}
+	routed := map[models.RecordKey]bool{}
+	for _, rc := range records {
+		key := rc.Key()
+		if first, ok := sets[key]; !ok {
+			sets[key] = rc
+		} else if routingComparable(first) != routingComparable(rc) {
+			errs = append(errs, rc.SourceError(fmt.Errorf("records of %s %s in set %q have different routing policies", rc.GetLabelFQDN(), rc.Type, rc.R53Routing[models.R53RoutingSetIdentifier])))
+		}
+		if rc.R53Routing[models.R53RoutingSetIdentifier] != "" {
+			routed[simpleKey(rc)] = true
+		}
+	}
+
+	reported := map[models.RecordKey]bool{}
+	for _, rc := range records {
+		key := simpleKey(rc)
+		if rc.R53Routing[models.R53RoutingSetIdentifier] == "" && routed[key] && !reported[key] {
+			errs = append(errs, rc.SourceError(fmt.Errorf("%s %s has records with and without a routing policy", key.NameFQDN, key.Type)))
+			reported[key] = true
+		}
+	}
+	return errs
+}
+
+// simpleKey returns the key of rc without its set identifier.
+func simpleKey(rc *models.RecordConfig) models.RecordKey {
+	c := *rc
+	c.R53Routing = nil
+	return c.Key()
+}
@@ -0,0 +1,268 @@
+package route53
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+	"github.com/StackExchange/dnscontrol/v4/pkg/printer"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	r53 "github.com/aws/aws-sdk-go-v2/service/route53"
+	r53Types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+)
+
+// vpc is a VPC that a private zone is associated with.
+type vpc struct {
+	ID     string `json:"vpc_id"`
+	Region string `json:"region"`
+}
+
+func (v vpc) String() string {
+	return v.ID + " (" + v.Region + ")"
+}
+
+// parsePrivateZones returns the private_zones of the provider metadata:
+// the VPCs of the private zone of each split horizon tag.
+//
+//	{"private_zones": {"internal": [{"vpc_id": "vpc-1a2b3c4d", "region": "us-east-1"}]}}
+func parsePrivateZones(metadata json.RawMessage) (map[string][]vpc, error) {
+	if len(metadata) == 0 {
+		return nil, nil
+	}
+	var m struct {
+		PrivateZones map[string][]vpc `json:"private_zones"`
+	}
+	if err := json.Unmarshal(metadata, &m); err != nil {
+		return nil, fmt.Errorf("ROUTE53 metadata: %w", err)
+	}
+	for tag, vpcs := range m.PrivateZones {
+		if tag == "" {
+			return nil, fmt.Errorf("ROUTE53 private_zones: the tag can't be empty")
+		}
+		if len(vpcs) == 0 {
+			return nil, fmt.Errorf("ROUTE53 private_zones %q: at least one VPC is required", tag)
+		}
+		for _, v := range vpcs {
+			if v.ID == "" || v.Region == "" {
+				return nil, fmt.Errorf("ROUTE53 private_zones %q: each VPC requires a vpc_id and a region", tag)
+			}
+		}
+	}
+	return m.PrivateZones, nil
+}
+
+func isPrivate(zone r53Types.HostedZone) bool {
+	return zone.Config != nil && zone.Config.PrivateZone
+}
+
+// withoutApexNS returns recs without the NS records at the apex.
+func withoutApexNS(recs models.Records) models.Records {
+	var result models.Records
+	for _, rec := range recs {
+		if rec.Type == "NS" && rec.GetLabel() == "@" {
+			continue
+		}
+		result = append(result, rec)
+	}
+	return result
+}
+
+// resetZones clears the zone cache so that the next lookup lists the
+// zones again.
+func (r *route53Provider) resetZones() {
+	r.zonesByDomain = nil
+	r.zonesByID = nil
+	r.zonesByVPC = nil
+}
+
+// privateZone returns the private zone of domain for the split horizon
+// tag: the zone of that name associated with any of the VPCs of the tag.
+func (r *route53Provider) privateZone(domain, tag string) (r53Types.HostedZone, bool, error) {
+	if err := r.getZones(); err != nil {
+		return r53Types.HostedZone{}, false, err
+	}
+	// A zone that lost its association with the first VPC is still the
+	// zone of the tag, so look at all of them.
+	for _, v := range r.privateZones[tag] {
+		summaries, err := r.listZonesByVPC(v)
+		if err != nil {
+			return r53Types.HostedZone{}, false, err
+		}
+		for _, s := range summaries {
+			if unescape(s.Name) != domain {
+				continue
+			}
+			// Zones of other accounts may be associated with the VPC.
+			if zone, ok := r.zonesByID[parseZoneID(aws.ToString(s.HostedZoneId))]; ok {
+				return zone, true, nil
+			}
+		}
+	}
+	return r53Types.HostedZone{}, false, nil
+}
+
+func (r *route53Provider) listZonesByVPC(v vpc) ([]r53Types.HostedZoneSummary, error) {
+	if summaries, ok := r.zonesByVPC[v]; ok {
+		return summaries, nil
+	}
+
+	var summaries []r53Types.HostedZoneSummary
+	var next *string
+	for {
+		var out *r53.ListHostedZonesByVPCOutput
+		var err error
+		withRetry(func() error {
+			out, err = r.client.ListHostedZonesByVPC(context.Background(), &r53.ListHostedZonesByVPCInput{
+				VPCId:     aws.String(v.ID),
+				VPCRegion: r53Types.VPCRegion(v.Region),
+				NextToken: next,
+			})
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, out.HostedZoneSummaries...)
+		if out.NextToken == nil {
+			break
+		}
+		next = out.NextToken
+	}
+
+	if r.zonesByVPC == nil {
+		r.zonesByVPC = map[vpc][]r53Types.HostedZoneSummary{}
+	}
+	r.zonesByVPC[v] = summaries
+	return summaries, nil
+}
+
+// missingVPCs returns the VPCs in vpcs that zone isn't associated with.
+func (r *route53Provider) missingVPCs(zone r53Types.HostedZone, vpcs []vpc) ([]vpc, error) {
+	var out *r53.GetHostedZoneOutput
+	var err error
+	withRetry(func() error {
+		out, err = r.client.GetHostedZone(context.Background(), &r53.GetHostedZoneInput{Id: zone.Id})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	associated := map[vpc]bool{}
+	for _, v := range out.VPCs {
+		associated[vpc{ID: aws.ToString(v.VPCId), Region: string(v.VPCRegion)}] = true
+	}
+	var missing []vpc
+	for _, v := range vpcs {
+		if !associated[v] {
+			missing = append(missing, v)
+		}
+	}
+	return missing, nil
+}
+
+func (r *route53Provider) associateVPC(zone r53Types.HostedZone, v vpc) error {
+	var err error
+	withRetry(func() error {
+		_, err = r.client.AssociateVPCWithHostedZone(context.Background(), &r53.AssociateVPCWithHostedZoneInput{
+			HostedZoneId: zone.Id,
+			VPC:          &r53Types.VPC{VPCId: aws.String(v.ID), VPCRegion: r53Types.VPCRegion(v.Region)},
+		})
+		return err
+	})
+	return err
+}
+
+// associateVPCCorrections returns the corrections that associate zone
+// with the VPCs in vpcs it isn't associated with. Associations that
+// aren't listed are left alone.
+func (r *route53Provider) associateVPCCorrections(zone r53Types.HostedZone, vpcs []vpc) ([]*models.Correction, error) {
+	missing, err := r.missingVPCs(zone, vpcs)
+	if err != nil {
+		return nil, err
+	}
+	var corrections []*models.Correction
+	for _, v := range missing {
+		v := v
+		corrections = append(corrections, &models.Correction{
+			Msg: fmt.Sprintf("Associate VPC %s with private zone %s", v, unescape(zone.Name)),
+			F:   func() error { return r.associateVPC(zone, v) },
+		})
+	}
+	return corrections, nil
+}
+
+// TaggedZoneExists returns true if the zone of D("domain!tag") exists.
+func (r *route53Provider) TaggedZoneExists(domain, tag string) (bool, error) {
+	if r.privateZones[tag] == nil {
+		if err := r.getZones(); err != nil {
+			return false, err
+		}
+		_, ok := r.zonesByDomain[domain]
+		return ok, nil
+	}
+	_, ok, err := r.privateZone(domain, tag)
+	return ok, err
+}
+
+// EnsureTaggedZoneExists creates the zone of D("domain!tag") if it
+// doesn't exist. If private_zones lists the tag, it is a private zone
+// associated with the tag's VPCs.
+func (r *route53Provider) EnsureTaggedZoneExists(domain, tag string) error {
+	vpcs := r.privateZones[tag]
+	if vpcs == nil {
+		return r.EnsureZoneExists(domain)
+	}
+
+	zone, ok, err := r.privateZone(domain, tag)
+	if err != nil {
+		return err
+	}
+	if ok {
+		missing, err := r.missingVPCs(zone, vpcs)
+		if err != nil {
+			return err
+		}
+		for _, v := range missing {
+			if err := r.associateVPC(zone, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	printer.Printf("Adding private zone for %s to route 53 account with VPCs %s\n", domain, vpcList(vpcs))
+	// A private zone is created with one VPC. The others are associated
+	// afterwards.
+	in := &r53.CreateHostedZoneInput{
+		Name:             &domain,
+		CallerReference:  aws.String(fmt.Sprint(time.Now().UnixNano())),
+		HostedZoneConfig: &r53Types.HostedZoneConfig{PrivateZone: true},
+		VPC:              &r53Types.VPC{VPCId: aws.String(vpcs[0].ID), VPCRegion: r53Types.VPCRegion(vpcs[0].Region)},
+	}
+	r.resetZones()
+	var out *r53.CreateHostedZoneOutput
+	withRetry(func() error {
+		out, err = r.client.CreateHostedZone(context.Background(), in)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	for _, v := range vpcs[1:] {
+		if err := r.associateVPC(*out.HostedZone, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func vpcList(vpcs []vpc) string {
+	l := make([]string, len(vpcs))
+	for i, v := range vpcs {
+		l[i] = v.String()
+	}
+	return strings.Join(l, ", ")
+}
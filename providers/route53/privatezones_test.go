@@ -0,0 +1,258 @@
+package route53
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/StackExchange/dnscontrol/v4/models"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	r53 "github.com/aws/aws-sdk-go-v2/service/route53"
+	r53Types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+)
+
+// fakeRoute53 is an in-memory stand-in for the parts of the Route53 API
+// that the private zone code uses.
+type fakeRoute53 struct {
+	zones []r53Types.HostedZone
+	vpcs  map[string][]r53Types.VPC // zone ID -> associated VPCs
+}
+
+func (f *fakeRoute53) addZone(id, name string, private bool, vpcs ...r53Types.VPC) {
+	f.zones = append(f.zones, r53Types.HostedZone{
+		Id:     aws.String("/hostedzone/" + id),
+		Name:   aws.String(name + "."),
+		Config: &r53Types.HostedZoneConfig{PrivateZone: private},
+	})
+	if f.vpcs == nil {
+		f.vpcs = map[string][]r53Types.VPC{}
+	}
+	f.vpcs[id] = vpcs
+}
+
+func r53vpc(id, region string) r53Types.VPC {
+	return r53Types.VPC{VPCId: aws.String(id), VPCRegion: r53Types.VPCRegion(region)}
+}
+
+func (f *fakeRoute53) AssociateVPCWithHostedZone(_ context.Context, in *r53.AssociateVPCWithHostedZoneInput, _ ...func(*r53.Options)) (*r53.AssociateVPCWithHostedZoneOutput, error) {
+	id := parseZoneID(aws.ToString(in.HostedZoneId))
+	f.vpcs[id] = append(f.vpcs[id], *in.VPC)
+	return &r53.AssociateVPCWithHostedZoneOutput{}, nil
+}
+
+func (f *fakeRoute53) ChangeResourceRecordSets(context.Context, *r53.ChangeResourceRecordSetsInput, ...func(*r53.Options)) (*r53.ChangeResourceRecordSetsOutput, error) {
+	return &r53.ChangeResourceRecordSetsOutput{}, nil
+}
+
+func (f *fakeRoute53) CreateHostedZone(_ context.Context, in *r53.CreateHostedZoneInput, _ ...func(*r53.Options)) (*r53.CreateHostedZoneOutput, error) {
+	id := fmt.Sprintf("Z%d", len(f.zones)+1)
+	var vpcs []r53Types.VPC
+	if in.VPC != nil {
+		vpcs = append(vpcs, *in.VPC)
+	}
+	f.addZone(id, aws.ToString(in.Name), in.HostedZoneConfig != nil && in.HostedZoneConfig.PrivateZone, vpcs...)
+	return &r53.CreateHostedZoneOutput{HostedZone: &f.zones[len(f.zones)-1]}, nil
+}
+
+func (f *fakeRoute53) GetHostedZone(_ context.Context, in *r53.GetHostedZoneInput, _ ...func(*r53.Options)) (*r53.GetHostedZoneOutput, error) {
+	id := parseZoneID(aws.ToString(in.Id))
+	for i, z := range f.zones {
+		if parseZoneID(aws.ToString(z.Id)) == id {
+			return &r53.GetHostedZoneOutput{HostedZone: &f.zones[i], VPCs: f.vpcs[id]}, nil
+		}
+	}
+	return nil, fmt.Errorf("no such zone %s", id)
+}
+
+func (f *fakeRoute53) ListHostedZones(context.Context, *r53.ListHostedZonesInput, ...func(*r53.Options)) (*r53.ListHostedZonesOutput, error) {
+	return &r53.ListHostedZonesOutput{HostedZones: f.zones}, nil
+}
+
+func (f *fakeRoute53) ListHostedZonesByVPC(_ context.Context, in *r53.ListHostedZonesByVPCInput, _ ...func(*r53.Options)) (*r53.ListHostedZonesByVPCOutput, error) {
+	var out r53.ListHostedZonesByVPCOutput
+	for _, z := range f.zones {
+		for _, v := range f.vpcs[parseZoneID(aws.ToString(z.Id))] {
+			if aws.ToString(v.VPCId) == aws.ToString(in.VPCId) && v.VPCRegion == in.VPCRegion {
+				out.HostedZoneSummaries = append(out.HostedZoneSummaries, r53Types.HostedZoneSummary{
+					HostedZoneId: aws.String(parseZoneID(aws.ToString(z.Id))),
+					Name:         z.Name,
+				})
+			}
+		}
+	}
+	return &out, nil
+}
+
+func (f *fakeRoute53) ListResourceRecordSets(context.Context, *r53.ListResourceRecordSetsInput, ...func(*r53.Options)) (*r53.ListResourceRecordSetsOutput, error) {
+	return &r53.ListResourceRecordSetsOutput{}, nil
+}
+
+var testPrivateZones = map[string][]vpc{
+	"internal": {{ID: "vpc-1", Region: "us-east-1"}, {ID: "vpc-2", Region: "eu-west-1"}},
+}
+
+func TestFindZoneSplitHorizon(t *testing.T) {
+	fake := &fakeRoute53{}
+	fake.addZone("ZPRIVATE", "example.com", true, r53vpc("vpc-1", "us-east-1"))
+	fake.addZone("ZPUBLIC", "example.com", false)
+	fake.addZone("ZOTHER", "example.com", true, r53vpc("vpc-9", "us-east-1"))
+	r := &route53Provider{client: fake, privateZones: testPrivateZones}
+
+	tests := []struct {
+		meta map[string]string
+		want string
+	}{
+		{map[string]string{}, "/hostedzone/ZPUBLIC"},
+		{map[string]string{models.DomainTag: "internal"}, "/hostedzone/ZPRIVATE"},
+		{map[string]string{models.DomainTag: "external"}, "/hostedzone/ZPUBLIC"},
+		{map[string]string{models.DomainTag: "internal", "zone_id": "ZOTHER"}, "/hostedzone/ZOTHER"},
+	}
+	for _, tst := range tests {
+		zone, err := r.findZone("example.com", tst.meta)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := aws.ToString(zone.Id); got != tst.want {
+			t.Errorf("findZone(%v) = %s, want %s", tst.meta, got, tst.want)
+		}
+	}
+
+	if _, err := r.findZone("example.org", map[string]string{models.DomainTag: "internal"}); err == nil || !strings.Contains(err.Error(), "example.org!internal") {
+		t.Errorf("expected a missing domain error for example.org!internal, got %v", err)
+	}
+}
+
+func TestEnsureTaggedZoneExists(t *testing.T) {
+	fake := &fakeRoute53{}
+	fake.addZone("ZPUBLIC", "example.com", false)
+	r := &route53Provider{client: fake, privateZones: testPrivateZones}
+
+	exists, err := r.TaggedZoneExists("example.com", "internal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exists {
+		t.Fatal("the public zone must not count as the private zone")
+	}
+
+	if err := r.EnsureTaggedZoneExists("example.com", "internal"); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.zones) != 2 {
+		t.Fatalf("expected a new zone, got %d zones", len(fake.zones))
+	}
+	created := fake.zones[1]
+	if !isPrivate(created) {
+		t.Error("the new zone isn't private")
+	}
+	want := []r53Types.VPC{r53vpc("vpc-1", "us-east-1"), r53vpc("vpc-2", "eu-west-1")}
+	if got := fake.vpcs[parseZoneID(aws.ToString(created.Id))]; !reflect.DeepEqual(got, want) {
+		t.Errorf("VPCs = %v, want %v", got, want)
+	}
+
+	exists, err = r.TaggedZoneExists("example.com", "internal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !exists {
+		t.Error("the private zone wasn't found after creating it")
+	}
+
+	// Nothing more to do.
+	if err := r.EnsureTaggedZoneExists("example.com", "internal"); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.zones) != 2 {
+		t.Errorf("expected no new zone, got %d zones", len(fake.zones))
+	}
+}
+
+func TestPrivateZoneOtherVPC(t *testing.T) {
+	// The zone is only associated with the second VPC of the tag.
+	fake := &fakeRoute53{}
+	fake.addZone("ZPRIVATE", "example.com", true, r53vpc("vpc-2", "eu-west-1"))
+	r := &route53Provider{client: fake, privateZones: testPrivateZones}
+
+	exists, err := r.TaggedZoneExists("example.com", "internal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !exists {
+		t.Fatal("the private zone wasn't found by its second VPC")
+	}
+
+	if err := r.EnsureTaggedZoneExists("example.com", "internal"); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.zones) != 1 {
+		t.Fatalf("expected no new zone, got %d zones", len(fake.zones))
+	}
+	want := []r53Types.VPC{r53vpc("vpc-2", "eu-west-1"), r53vpc("vpc-1", "us-east-1")}
+	if got := fake.vpcs["ZPRIVATE"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("VPCs = %v, want %v", got, want)
+	}
+}
+
+func TestAssociateVPCCorrections(t *testing.T) {
+	fake := &fakeRoute53{}
+	fake.addZone("ZPRIVATE", "example.com", true, r53vpc("vpc-1", "us-east-1"), r53vpc("vpc-old", "us-east-1"))
+	r := &route53Provider{client: fake, privateZones: testPrivateZones}
+
+	zone, err := r.findZone("example.com", map[string]string{models.DomainTag: "internal"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	corrections, err := r.associateVPCCorrections(zone, testPrivateZones["internal"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(corrections) != 1 {
+		t.Fatalf("expected 1 correction, got %d", len(corrections))
+	}
+	if want := "Associate VPC vpc-2 (eu-west-1) with private zone example.com"; corrections[0].Msg != want {
+		t.Errorf("Msg = %q, want %q", corrections[0].Msg, want)
+	}
+	if err := corrections[0].F(); err != nil {
+		t.Fatal(err)
+	}
+	// vpc-old isn't listed but stays associated.
+	want := []r53Types.VPC{r53vpc("vpc-1", "us-east-1"), r53vpc("vpc-old", "us-east-1"), r53vpc("vpc-2", "eu-west-1")}
+	if got := fake.vpcs["ZPRIVATE"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("VPCs = %v, want %v", got, want)
+	}
+}
+
+func TestParsePrivateZones(t *testing.T) {
+	tests := []struct {
+		metadata string
+		want     map[string][]vpc
+		err      string
+	}{
+		{"", nil, ""},
+		{`{}`, nil, ""},
+		{`{"private_zones": {"internal": [{"vpc_id": "vpc-1", "region": "us-east-1"}]}}`, map[string][]vpc{"internal": {{ID: "vpc-1", Region: "us-east-1"}}}, ""},
+		{`{"private_zones": {"internal": []}}`, nil, "at least one VPC"},
+		{`{"private_zones": {"internal": [{"vpc_id": "vpc-1"}]}}`, nil, "vpc_id and a region"},
+		{`{"private_zones": {"": [{"vpc_id": "vpc-1", "region": "us-east-1"}]}}`, nil, "tag can't be empty"},
+		{`{"private_zones": ["vpc-1"]}`, nil, "ROUTE53 metadata"},
+	}
+	for _, tst := range tests {
+		got, err := parsePrivateZones(json.RawMessage(tst.metadata))
+		if tst.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tst.err) {
+				t.Errorf("%s: expected an error containing %q, got %v", tst.metadata, tst.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", tst.metadata, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tst.want) {
+			t.Errorf("%s: got %v, want %v", tst.metadata, got, tst.want)
+		}
+	}
+}
@@ -26,12 +26,26 @@ import (
 	r53dTypes "github.com/aws/aws-sdk-go-v2/service/route53domains/types"
 )
 
+// route53API is the part of the Route53 API that the provider uses.
+// The tests replace it with a stand-in.
+type route53API interface {
+	AssociateVPCWithHostedZone(context.Context, *r53.AssociateVPCWithHostedZoneInput, ...func(*r53.Options)) (*r53.AssociateVPCWithHostedZoneOutput, error)
+	ChangeResourceRecordSets(context.Context, *r53.ChangeResourceRecordSetsInput, ...func(*r53.Options)) (*r53.ChangeResourceRecordSetsOutput, error)
+	CreateHostedZone(context.Context, *r53.CreateHostedZoneInput, ...func(*r53.Options)) (*r53.CreateHostedZoneOutput, error)
+	GetHostedZone(context.Context, *r53.GetHostedZoneInput, ...func(*r53.Options)) (*r53.GetHostedZoneOutput, error)
+	ListHostedZones(context.Context, *r53.ListHostedZonesInput, ...func(*r53.Options)) (*r53.ListHostedZonesOutput, error)
+	ListHostedZonesByVPC(context.Context, *r53.ListHostedZonesByVPCInput, ...func(*r53.Options)) (*r53.ListHostedZonesByVPCOutput, error)
+	ListResourceRecordSets(context.Context, *r53.ListResourceRecordSetsInput, ...func(*r53.Options)) (*r53.ListResourceRecordSetsOutput, error)
+}
+
 type route53Provider struct {
-	client        *r53.Client
+	client        route53API
 	registrar     *r53d.Client
 	delegationSet *string
+	privateZones  map[string][]vpc // The VPCs of the private zone of each split horizon tag.
 	zonesByID     map[string]r53Types.HostedZone
-	zonesByDomain map[string]r53Types.HostedZone
+	zonesByDomain map[string]r53Types.HostedZone // Public zones, or a private zone if there is no public one.
+	zonesByVPC    map[vpc][]r53Types.HostedZoneSummary
 }
 
 func newRoute53Reg(conf map[string]string) (providers.Registrar, error) {
@@ -42,7 +56,12 @@ func newRoute53Dsp(conf map[string]string, metadata json.RawMessage) (providers.
 	return newRoute53(conf, metadata)
 }
 
-func newRoute53(m map[string]string, _ json.RawMessage) (*route53Provider, error) {
+func newRoute53(m map[string]string, metadata json.RawMessage) (*route53Provider, error) {
+	privateZones, err := parsePrivateZones(metadata)
+	if err != nil {
+		return nil, err
+	}
+
 	optFns := []func(*config.LoadOptions) error{
 		// Route53 uses a global endpoint and route53domains
 		// currently only has a single regional endpoint in us-east-1
@@ -66,7 +85,7 @@ func newRoute53(m map[string]string, _ json.RawMessage) (*route53Provider, error
 		printer.Printf("ROUTE53 DelegationSet %s configured\n", val)
 		dls = aws.String(val)
 	}
-	api := &route53Provider{client: r53.NewFromConfig(config), registrar: r53d.NewFromConfig(config), delegationSet: dls}
+	api := &route53Provider{client: r53.NewFromConfig(config), registrar: r53d.NewFromConfig(config), delegationSet: dls, privateZones: privateZones}
 	err = api.getZones()
 	if err != nil {
 		return nil, err
@@ -84,6 +103,7 @@ var features = providers.DocumentationNotes{
 	providers.CanUseLOC:              providers.Cannot(),
 	providers.CanUsePTR:              providers.Can(),
 	providers.CanUseRoute53Alias:     providers.Can(),
+	providers.CanUseRoute53Routing:   providers.Can(),
 	providers.CanUseSRV:              providers.Can(),
 	providers.DocCreateDomains:       providers.Can(),
 	providers.DocDualHost:            providers.Can(),
@@ -162,7 +182,11 @@ func (r *route53Provider) getZones() error {
 		}
 		for _, z := range out.HostedZones {
 			domain := strings.TrimSuffix(aws.ToString(z.Name), ".")
-			r.zonesByDomain[domain] = z
+			// A public zone and private zones may have the same name.
+			// Private zones are found by their VPC (see privateZone).
+			if _, ok := r.zonesByDomain[domain]; !ok || !isPrivate(z) {
+				r.zonesByDomain[domain] = z
+			}
 			r.zonesByID[parseZoneID(aws.ToString(z.Id))] = z
 		}
 		if out.NextMarker != nil {
@@ -217,39 +241,26 @@ func (r *route53Provider) GetNameservers(domain string) ([]*models.Nameserver, e
 }
 
 func (r *route53Provider) GetZoneRecords(domain string, meta map[string]string) (models.Records, error) {
-	if err := r.getZones(); err != nil {
+	zone, err := r.findZone(domain, meta)
+	if err != nil {
 		return nil, err
 	}
-
-	var zone r53Types.HostedZone
-
-	// If the zone_id is specified in meta, use it.
-	if zoneID, ok := meta["zone_id"]; ok {
-		zone = r.zonesByID[zoneID]
-		return r.getZoneRecords(zone)
-	}
-
-	//	fmt.Printf("DEBUG: ROUTE53 zones:\n")
-	//	for i, j := range r.zonesByDomain {
-	//		fmt.Printf("       %s: %v\n", i, aws.ToString(j.Id))
-	//	}
-
-	// Otherwise, use the domain name to look up the zone.
-	if zone, ok := r.zonesByDomain[domain]; ok {
-		return r.getZoneRecords(zone)
-	}
-
-	// Not found there?  Error.
-	return nil, errDomainNoExist{domain}
+	return r.getZoneRecords(zone)
 }
 
 func (r *route53Provider) getZone(dc *models.DomainConfig) (r53Types.HostedZone, error) {
+	return r.findZone(dc.Name, dc.Metadata)
+}
 
+// findZone returns the zone of the domain with metadata meta. The zone
+// is chosen by, in order: the zone_id metadata (R53_ZONE()), the split
+// horizon tag if private_zones lists it, and the name.
+func (r *route53Provider) findZone(domain string, meta map[string]string) (r53Types.HostedZone, error) {
 	if err := r.getZones(); err != nil {
 		return r53Types.HostedZone{}, err
 	}
 
-	if zoneID, ok := dc.Metadata["zone_id"]; ok {
+	if zoneID, ok := meta["zone_id"]; ok {
 		zone, ok := r.zonesByID[zoneID]
 		if !ok {
 			return r53Types.HostedZone{}, errZoneNoExist{zoneID}
@@ -257,11 +268,22 @@ func (r *route53Provider) getZone(dc *models.DomainConfig) (r53Types.HostedZone,
 		return zone, nil
 	}
 
-	if zone, ok := r.zonesByDomain[dc.Name]; ok {
+	if tag := meta[models.DomainTag]; r.privateZones[tag] != nil {
+		zone, ok, err := r.privateZone(domain, tag)
+		if err != nil {
+			return r53Types.HostedZone{}, err
+		}
+		if !ok {
+			return r53Types.HostedZone{}, errDomainNoExist{domain + "!" + tag}
+		}
 		return zone, nil
 	}
 
-	return r53Types.HostedZone{}, errDomainNoExist{dc.Name}
+	if zone, ok := r.zonesByDomain[domain]; ok {
+		return zone, nil
+	}
+
+	return r53Types.HostedZone{}, errDomainNoExist{domain}
 }
 
 func (r *route53Provider) getZoneRecords(zone r53Types.HostedZone) (models.Records, error) {
@@ -272,6 +294,10 @@ func (r *route53Provider) getZoneRecords(zone r53Types.HostedZone) (models.Recor
 
 	var existingRecords = []*models.RecordConfig{}
 	for _, set := range records {
+		if isPrivate(zone) && set.Type == r53Types.RRTypeNs && unescape(set.Name) == unescape(zone.Name) {
+			// Private zones aren't delegated. Route53 manages their NS records.
+			continue
+		}
 		rts, err := nativeToRecords(set, unescape(zone.Name))
 		if err != nil {
 			return nil, err
@@ -288,6 +314,19 @@ func (r *route53Provider) GetZoneRecordsCorrections(dc *models.DomainConfig, exi
 		return nil, err
 	}
 
+	var corrections []*models.Correction
+	if isPrivate(zone) {
+		// GetNameservers() returns the nameservers of the public zone.
+		dc.Records = withoutApexNS(dc.Records)
+
+		if tag := dc.Metadata[models.DomainTag]; r.privateZones[tag] != nil {
+			corrections, err = r.associateVPCCorrections(zone, r.privateZones[tag])
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	// update zone_id to current zone.id if not specified by the user
 	for _, want := range dc.Records {
 		if want.Type == "R53_ALIAS" && want.R53Alias["zone_id"] == "" {
@@ -295,13 +334,12 @@ func (r *route53Provider) GetZoneRecordsCorrections(dc *models.DomainConfig, exi
 		}
 	}
 
-	var corrections []*models.Correction
 	changes := []r53Types.Change{}
 	changeDesc := []string{} // TODO(tlim): This should be a [][]string so that we aren't joining strings until the last moment.
 
 	// Amazon Route53 is a "ByRecordSet" API.
 	// At each label:rtype pair, we either delete all records or UPSERT the desired records.
-	instructions, err := diff2.ByRecordSet(existingRecords, dc, nil)
+	instructions, err := diff2.ByRecordSet(existingRecords, dc, routingComparable)
 	if err != nil {
 		return nil, err
 	}
@@ -342,7 +380,8 @@ func (r *route53Provider) GetZoneRecordsCorrections(dc *models.DomainConfig, exi
 				// Make a list of all the records to be installed at label:rtype
 				rrset = &r53Types.ResourceRecordSet{
 					Name: aws.String(instNameFQDN),
-					Type: r53Types.RRType(instType),
+					// Not instType, which includes the set identifier.
+					Type: r53Types.RRType(inst.New[0].Type),
 				}
 
 				for _, r := range inst.New {
@@ -354,6 +393,7 @@ func (r *route53Provider) GetZoneRecordsCorrections(dc *models.DomainConfig, exi
 					rrset.TTL = &i
 				}
 			}
+			routingToRRSet(rrset, inst.New[0].R53Routing)
 			chg = r53Types.Change{
 				Action:            r53Types.ChangeActionUpsert,
 				ResourceRecordSet: rrset,
@@ -445,6 +485,7 @@ func nativeToRecords(set r53Types.ResourceRecordSet, origin string) ([]*models.R
 		}
 		rc.SetLabelFromFQDN(unescape(set.Name), origin)
 		rc.SetTarget(aws.ToString(set.AliasTarget.DNSName))
+		rc.R53Routing = nativeToRouting(set)
 		// rc.Original stores a pointer to the original set for use by
 		// r53Types.ChangeActionDelete and anything else that needs the
 		// native record verbatim.
@@ -492,6 +533,7 @@ func nativeToRecords(set r53Types.ResourceRecordSet, origin string) ([]*models.R
 
 				rc := &models.RecordConfig{TTL: uint32(aws.ToInt64(set.TTL))}
 				rc.SetLabelFromFQDN(unescape(set.Name), origin)
+				rc.R53Routing = nativeToRouting(set)
 				rc.Original = set
 				if err := rc.PopulateFromStringFunc(rtypeString, val, origin, txtutil.ParseQuoted); err != nil {
 					return nil, fmt.Errorf("unparsable record type=%q received from ROUTE53: %w", rtypeString, err)
@@ -522,6 +564,102 @@ func aliasToRRSet(zone r53Types.HostedZone, r *models.RecordConfig) *r53Types.Re
 	return rrset
 }
 
+// nativeToRouting returns the routing policy and health check of set
+// in the form of RecordConfig.R53Routing, or nil if it has neither.
+func nativeToRouting(set r53Types.ResourceRecordSet) map[string]string {
+	m := map[string]string{}
+	if set.SetIdentifier != nil {
+		m[models.R53RoutingSetIdentifier] = aws.ToString(set.SetIdentifier)
+	}
+	if set.Weight != nil {
+		m[models.R53RoutingWeight] = strconv.FormatInt(aws.ToInt64(set.Weight), 10)
+	}
+	if set.Region != "" {
+		m[models.R53RoutingRegion] = string(set.Region)
+	}
+	if set.Failover != "" {
+		m[models.R53RoutingFailover] = string(set.Failover)
+	}
+	if g := set.GeoLocation; g != nil {
+		if g.ContinentCode != nil {
+			m[models.R53RoutingGeoContinent] = aws.ToString(g.ContinentCode)
+		}
+		if g.CountryCode != nil {
+			m[models.R53RoutingGeoCountry] = aws.ToString(g.CountryCode)
+		}
+		if g.SubdivisionCode != nil {
+			m[models.R53RoutingGeoSubdivision] = aws.ToString(g.SubdivisionCode)
+		}
+	}
+	if aws.ToBool(set.MultiValueAnswer) {
+		m[models.R53RoutingMultiValue] = "true"
+	}
+	if set.HealthCheckId != nil {
+		m[models.R53RoutingHealthCheckID] = aws.ToString(set.HealthCheckId)
+	}
+	if len(m) == 0 {
+		return nil
+	}
+	return m
+}
+
+// routingToRRSet sets the routing policy and health check of rrset from
+// routing, a RecordConfig.R53Routing.
+func routingToRRSet(rrset *r53Types.ResourceRecordSet, routing map[string]string) {
+	if v, ok := routing[models.R53RoutingSetIdentifier]; ok {
+		rrset.SetIdentifier = aws.String(v)
+	}
+	if v, ok := routing[models.R53RoutingWeight]; ok {
+		// Validated by R53_WEIGHT().
+		w, _ := strconv.ParseInt(v, 10, 64)
+		rrset.Weight = aws.Int64(w)
+	}
+	if v, ok := routing[models.R53RoutingRegion]; ok {
+		rrset.Region = r53Types.ResourceRecordSetRegion(v)
+	}
+	if v, ok := routing[models.R53RoutingFailover]; ok {
+		rrset.Failover = r53Types.ResourceRecordSetFailover(v)
+	}
+	continent, hasContinent := routing[models.R53RoutingGeoContinent]
+	country, hasCountry := routing[models.R53RoutingGeoCountry]
+	if hasContinent || hasCountry {
+		rrset.GeoLocation = &r53Types.GeoLocation{}
+		if hasContinent {
+			rrset.GeoLocation.ContinentCode = aws.String(continent)
+		}
+		if hasCountry {
+			rrset.GeoLocation.CountryCode = aws.String(country)
+		}
+		if v, ok := routing[models.R53RoutingGeoSubdivision]; ok {
+			rrset.GeoLocation.SubdivisionCode = aws.String(v)
+		}
+	}
+	if routing[models.R53RoutingMultiValue] == "true" {
+		rrset.MultiValueAnswer = aws.Bool(true)
+	}
+	if v, ok := routing[models.R53RoutingHealthCheckID]; ok {
+		rrset.HealthCheckId = aws.String(v)
+	}
+}
+
+// routingComparable returns the routing policy and health check of rc
+// so that diff2 notices when they change.
+func routingComparable(rc *models.RecordConfig) string {
+	if len(rc.R53Routing) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(rc.R53Routing))
+	for k := range rc.R53Routing {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	fields := make([]string, len(keys))
+	for i, k := range keys {
+		fields[i] = k + "=" + rc.R53Routing[k]
+	}
+	return strings.Join(fields, " ")
+}
+
 func getZoneID(zone r53Types.HostedZone, r *models.RecordConfig) string {
 	zoneID := r.R53Alias["zone_id"]
 	if zoneID == "" {
@@ -671,8 +809,7 @@ func (r *route53Provider) EnsureZoneExists(domain string) error {
 	}
 
 	// reset zone cache
-	r.zonesByDomain = nil
-	r.zonesByID = nil
+	r.resetZones()
 
 	var err error
 	withRetry(func() error {
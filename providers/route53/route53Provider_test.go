@@ -5,6 +5,7 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/StackExchange/dnscontrol/v4/models"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	r53Types "github.com/aws/aws-sdk-go-v2/service/route53/types"
 )
@@ -141,3 +142,89 @@ func Test_changeBatcher(t *testing.T) {
 		})
 	}
 }
+
+func TestRoutingRoundTrip(t *testing.T) {
+	sets := []r53Types.ResourceRecordSet{
+		{SetIdentifier: aws.String("blue"), Weight: aws.Int64(90), HealthCheckId: aws.String("hc")},
+		{SetIdentifier: aws.String("us"), Region: r53Types.ResourceRecordSetRegionUsEast1},
+		{SetIdentifier: aws.String("primary"), Failover: r53Types.ResourceRecordSetFailoverPrimary},
+		{SetIdentifier: aws.String("eu"), GeoLocation: &r53Types.GeoLocation{ContinentCode: aws.String("EU")}},
+		{SetIdentifier: aws.String("ca"), GeoLocation: &r53Types.GeoLocation{CountryCode: aws.String("US"), SubdivisionCode: aws.String("CA")}},
+		{SetIdentifier: aws.String("one"), MultiValueAnswer: aws.Bool(true)},
+		{HealthCheckId: aws.String("hc")},
+		{},
+	}
+	for i, set := range sets {
+		var got r53Types.ResourceRecordSet
+		routingToRRSet(&got, nativeToRouting(set))
+		if !reflect.DeepEqual(got, set) {
+			t.Errorf("%d: got %+v, want %+v", i, got, set)
+		}
+	}
+}
+
+func TestNativeToRecordsRouting(t *testing.T) {
+	set := r53Types.ResourceRecordSet{
+		Name:            aws.String("www.example.com."),
+		Type:            r53Types.RRTypeA,
+		TTL:             aws.Int64(60),
+		SetIdentifier:   aws.String("blue"),
+		Weight:          aws.Int64(90),
+		ResourceRecords: []r53Types.ResourceRecord{{Value: aws.String("1.2.3.4")}, {Value: aws.String("1.2.3.5")}},
+	}
+	recs, err := nativeToRecords(set, "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 2 {
+		t.Fatalf("got %d records, want 2", len(recs))
+	}
+	for _, rc := range recs {
+		if got := rc.Key().Type; got != "A/blue" {
+			t.Errorf("got key type %q, want A/blue", got)
+		}
+		if got := routingComparable(rc); got != "set_identifier=blue weight=90" {
+			t.Errorf("got routing %q", got)
+		}
+	}
+}
+
+func TestAuditRoutingSets(t *testing.T) {
+	mk := func(name, target string, routing map[string]string) *models.RecordConfig {
+		rc := &models.RecordConfig{Type: "A", R53Routing: routing}
+		rc.SetLabel(name, "example.com")
+		rc.SetTarget(target)
+		return rc
+	}
+	weight := func(id, w string) map[string]string {
+		return map[string]string{models.R53RoutingSetIdentifier: id, models.R53RoutingWeight: w}
+	}
+	tests := []struct {
+		name    string
+		records models.Records
+		want    int
+	}{
+		{"ok", models.Records{
+			mk("www", "1.2.3.4", weight("blue", "90")),
+			mk("www", "1.2.3.5", weight("blue", "90")),
+			mk("www", "1.2.3.6", weight("green", "10")),
+			mk("@", "1.2.3.4", nil),
+		}, 0},
+		{"different policies", models.Records{
+			mk("www", "1.2.3.4", weight("blue", "90")),
+			mk("www", "1.2.3.5", weight("blue", "10")),
+		}, 1},
+		{"simple and routed", models.Records{
+			mk("www", "1.2.3.4", weight("blue", "90")),
+			mk("www", "1.2.3.5", nil),
+			mk("www", "1.2.3.6", nil),
+		}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if errs := AuditRecords(tt.records); len(errs) != tt.want {
+				t.Errorf("got %d errors, want %d: %v", len(errs), tt.want, errs)
+			}
+		})
+	}
+}